@@ -0,0 +1,50 @@
+package v1alpha1
+
+// Condition types reported in the Conditions of Operation, Server and ProvisioningProfile
+const (
+	// ConditionSSHReachable indicates the controller could reach the server over SSH
+	ConditionSSHReachable = "SSHReachable"
+
+	// ConditionBootstrapped indicates the bootstrap script completed on the server
+	ConditionBootstrapped = "Bootstrapped"
+
+	// ConditionNodeRegistered indicates the server's Node registered and reported Ready
+	ConditionNodeRegistered = "NodeRegistered"
+
+	// ConditionRoutesProgrammed indicates pod CIDR routes were configured for the server
+	ConditionRoutesProgrammed = "RoutesProgrammed"
+
+	// ConditionBMCReachable indicates the controller could query the server's BMC
+	ConditionBMCReachable = "BMCReachable"
+
+	// ConditionProfileValid indicates a ProvisioningProfile's version, Secrets and bootstrap script were validated
+	ConditionProfileValid = "ProfileValid"
+
+	// ConditionReady indicates a Server is ready for workloads, a ProvisioningProfile is ready for use,
+	// or a ServerClaim has all of its Servers bound and provisioned
+	ConditionReady = "Ready"
+
+	// ConditionSucceeded indicates an Operation finished: True on success, False on failure,
+	// Unknown while it is still running
+	ConditionSucceeded = "Succeeded"
+)
+
+// Condition reasons
+const (
+	ReasonInProgress = "InProgress"
+	ReasonSucceeded  = "Succeeded"
+	ReasonFailed     = "Failed"
+	ReasonValid      = "Valid"
+	ReasonInvalid    = "Invalid"
+
+	ReasonCancelled        = "Cancelled"
+	ReasonDeadlineExceeded = "DeadlineExceeded"
+	ReasonPaused           = "Paused"
+
+	ReasonReachable   = "Reachable"
+	ReasonUnreachable = "Unreachable"
+
+	ReasonBound               = "Bound"
+	ReasonInsufficientServers = "InsufficientServers"
+	ReasonReleasing           = "Releasing"
+)
@@ -0,0 +1,65 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DatacenterSpec defines the desired state of Datacenter
+type DatacenterSpec struct {
+	// Router is the datacenter's Tailscale subnet router
+	Router DatacenterRouter `json:"router"`
+
+	// WorkerSubnet is the CIDR of the datacenter's worker network (e.g., "10.50.0.0/16"). The
+	// router always advertises it alongside the workers' pod CIDRs.
+	WorkerSubnet string `json:"workerSubnet,omitempty"`
+
+	// PodCIDRPoolRef references the PodCIDRPool the datacenter's Servers get their pod CIDRs
+	// from. If unset, the pool is chosen by its selector.
+	PodCIDRPoolRef *LocalObjectReference `json:"podCIDRPoolRef,omitempty"`
+
+	// ResourceGroup is the Azure resource group of the datacenter's worker VMs. Defaults to the
+	// controller's -aks-vm-resource-group.
+	ResourceGroup string `json:"resourceGroup,omitempty"`
+
+	// SSHCredentialsSecretRef references a Secret containing SSH credentials for the router.
+	// Secret should have keys "privateKey" and optionally "username" (default: ubuntu).
+	// If unset, the controller's SSH key is used.
+	SSHCredentialsSecretRef string `json:"sshCredentialsSecretRef,omitempty"`
+}
+
+// DatacenterRouter identifies a datacenter's Tailscale subnet router
+type DatacenterRouter struct {
+	// TailscaleIP is the router's Tailscale IP, used for SSH and to find its Tailscale device
+	TailscaleIP string `json:"tailscaleIP"`
+
+	// Hostname is the router's Tailscale hostname, used to find its Tailscale device if its IP
+	// doesn't match
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Router",type="string",JSONPath=".spec.router.tailscaleIP"
+// +kubebuilder:printcolumn:name="Subnet",type="string",JSONPath=".spec.workerSubnet"
+// +kubebuilder:printcolumn:name="Pool",type="string",JSONPath=".spec.podCIDRPoolRef.name"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// Datacenter is a site whose Servers are reached and routed through one Tailscale subnet router
+type Datacenter struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DatacenterSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// DatacenterList contains a list of Datacenter
+type DatacenterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Datacenter `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Datacenter{}, &DatacenterList{})
+}
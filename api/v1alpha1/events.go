@@ -0,0 +1,35 @@
+package v1alpha1
+
+// Event reasons recorded by the controllers on Operations, Servers and Nodes
+const (
+	// Operation lifecycle
+	EventReasonQueued           = "Queued"
+	EventReasonStarted          = "Started"
+	EventReasonStepCompleted    = "StepCompleted"
+	EventReasonRetryScheduled   = "RetryScheduled"
+	EventReasonSucceeded        = "Succeeded"
+	EventReasonFailed           = "Failed"
+	EventReasonCancelled        = "Cancelled"
+	EventReasonDeadlineExceeded = "DeadlineExceeded"
+
+	// Bootstrap of a repaved Server
+	EventReasonBootstrapStarted   = "BootstrapStarted"
+	EventReasonBootstrapSucceeded = "BootstrapSucceeded"
+	EventReasonBootstrapFailed    = "BootstrapFailed"
+
+	// Server state changes, e.g. ready -> provisioning or ready -> unreachable
+	EventReasonStateChanged = "StateChanged"
+
+	// Node changes made by an Operation
+	EventReasonNodeCordoned   = "NodeCordoned"
+	EventReasonNodeUncordoned = "NodeUncordoned"
+	EventReasonNodeDrained    = "NodeDrained"
+	EventReasonNodeDeleted    = "NodeDeleted"
+
+	// Route sync
+	EventReasonRouteProgrammed        = "RouteProgrammed"
+	EventReasonRouteFailed            = "RouteFailed"
+	EventReasonRouteRemoved           = "RouteRemoved"
+	EventReasonTailscaleRoutesUpdated = "TailscaleRoutesUpdated"
+	EventReasonNodeUnclassified       = "NodeUnclassified"
+)
@@ -12,6 +12,7 @@ const (
 	OperationPhaseRunning   OperationPhase = "Running"
 	OperationPhaseSucceeded OperationPhase = "Succeeded"
 	OperationPhaseFailed    OperationPhase = "Failed"
+	OperationPhaseCancelled OperationPhase = "Cancelled"
 )
 
 // OperationType represents the type of operation to perform
@@ -20,6 +21,58 @@ type OperationType string
 const (
 	OperationTypeRepave OperationType = "repave"
 	OperationTypeReboot OperationType = "reboot"
+
+	// Power operations are performed out-of-band through the Server's BMC
+	OperationTypePowerOn    OperationType = "power-on"
+	OperationTypePowerOff   OperationType = "power-off"
+	OperationTypePowerCycle OperationType = "power-cycle"
+
+	// OperationTypeWipe removes the server's Node and erases its Kubernetes state and data disks
+	OperationTypeWipe OperationType = "wipe"
+
+	// OperationTypeDecommission removes the server's Node, pod CIDR routes and Tailscale
+	// advertisements, and marks the server available
+	OperationTypeDecommission OperationType = "decommission"
+
+	// OperationTypeUpgrade upgrades kubelet and kubectl in place to the ProvisioningProfile's
+	// Kubernetes version, keeping the server's Node
+	OperationTypeUpgrade OperationType = "upgrade"
+)
+
+// OperationStep identifies a persisted step within a multi-step Operation workflow
+type OperationStep string
+
+const (
+	// Reboot workflow steps (Cordon and Drain also run before a repave)
+	OperationStepCordon       OperationStep = "Cordon"
+	OperationStepDrain        OperationStep = "Drain"
+	OperationStepRecordBootID OperationStep = "RecordBootID"
+	OperationStepReboot       OperationStep = "Reboot"
+	OperationStepWaitForNode  OperationStep = "WaitForNode"
+	OperationStepUncordon     OperationStep = "Uncordon"
+
+	// Repave workflow steps
+	OperationStepPreflight       OperationStep = "Preflight"
+	OperationStepNodeCleanup     OperationStep = "NodeCleanup"
+	OperationStepAllocatePodCIDR OperationStep = "AllocatePodCIDR"
+	OperationStepBootstrap       OperationStep = "Bootstrap"
+	OperationStepRouting         OperationStep = "Routing"
+	OperationStepVerify          OperationStep = "Verify"
+
+	// Power workflow steps
+	OperationStepPowerAction       OperationStep = "PowerAction"
+	OperationStepWaitForPowerState OperationStep = "WaitForPowerState"
+
+	// Wipe workflow step (after Cordon, Drain and NodeCleanup)
+	OperationStepWipe OperationStep = "Wipe"
+
+	// Decommission workflow steps (after Cordon, Drain and NodeCleanup, optionally followed by Wipe)
+	OperationStepRouteCleanup     OperationStep = "RouteCleanup"
+	OperationStepTailscaleCleanup OperationStep = "TailscaleCleanup"
+	OperationStepReleasePodCIDR   OperationStep = "ReleasePodCIDR"
+
+	// Upgrade workflow step (after Preflight, Cordon and Drain, followed by WaitForNode and Uncordon)
+	OperationStepUpgrade OperationStep = "Upgrade"
 )
 
 // OperationSpec defines the desired state of Operation
@@ -27,11 +80,118 @@ type OperationSpec struct {
 	// ServerRef references the Server to operate on
 	ServerRef LocalObjectReference `json:"serverRef"`
 
-	// ProvisioningProfileRef references the ProvisioningProfile to use for provisioning
-	ProvisioningProfileRef LocalObjectReference `json:"provisioningProfileRef"`
+	// ProvisioningProfileRef references the ProvisioningProfile to use for provisioning.
+	// Required for repave and upgrade; optional for reboot (SSH credentials) and unused by power operations.
+	ProvisioningProfileRef LocalObjectReference `json:"provisioningProfileRef,omitempty"`
 
-	// Operation to perform (e.g., "repave", "reboot")
+	// Operation to perform (e.g., "repave", "reboot", "power-cycle", "wipe", "decommission", "upgrade")
 	Operation OperationType `json:"operation"`
+
+	// RetryPolicy controls whether a failed step is retried. If unset, failures are final.
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+
+	// ActiveDeadlineSeconds is how long the operation may run, measured from its start time,
+	// before any in-flight step is aborted and the operation fails
+	// +kubebuilder:validation:Minimum=1
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
+
+	// Cancel aborts the operation. Any in-flight step is stopped and the phase becomes Cancelled.
+	Cancel bool `json:"cancel,omitempty"`
+
+	// Drain controls how the Node is cordoned and drained before a repave or reboot
+	Drain *DrainOptions `json:"drain,omitempty"`
+
+	// Decommission controls the optional parts of a decommission operation
+	Decommission *DecommissionOptions `json:"decommission,omitempty"`
+
+	// Priority orders Operations queued on the same Server. Higher values start first;
+	// Operations of equal priority start in creation order (default: 0).
+	Priority int32 `json:"priority,omitempty"`
+
+	// TTLSecondsAfterFinished is how long a finished operation is kept before it is deleted and
+	// summarized in its Server's status.operationHistory. If unset, the controller's
+	// -operation-ttl default applies. Operations owned by an OperationSet or ServerClaim are
+	// deleted with their owner instead.
+	// +kubebuilder:validation:Minimum=0
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+}
+
+// DrainOptions controls how a Node is drained. Pods are evicted through the Eviction API,
+// so PodDisruptionBudgets are honored; DaemonSet and mirror pods are skipped.
+type DrainOptions struct {
+	// Disabled skips cordoning and draining the Node
+	Disabled bool `json:"disabled,omitempty"`
+
+	// TimeoutSeconds bounds how long to wait for pods to be evicted (default: 600)
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// GracePeriodSeconds overrides the termination grace period of evicted pods
+	// +kubebuilder:validation:Minimum=0
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds,omitempty"`
+
+	// Force continues the operation when the timeout expires with pods remaining,
+	// instead of failing it
+	Force bool `json:"force,omitempty"`
+}
+
+// DecommissionOptions controls the optional parts of a decommission. The Node, its pod CIDR
+// routes and its Tailscale advertisements are always removed.
+type DecommissionOptions struct {
+	// DeleteTailscaleDevice removes the server's device from the tailnet
+	DeleteTailscaleDevice bool `json:"deleteTailscaleDevice,omitempty"`
+
+	// Wipe erases the server's Kubernetes state and data disks, as a wipe operation does
+	Wipe bool `json:"wipe,omitempty"`
+}
+
+// FailureClass categorizes an Operation failure for retry decisions
+type FailureClass string
+
+const (
+	// FailureClassSSH is a failure to connect to the server over SSH
+	FailureClassSSH FailureClass = "SSH"
+	// FailureClassTailscale is a failure to reach the server's router over the tailnet
+	FailureClassTailscale FailureClass = "Tailscale"
+	// FailureClassBootstrap is a script that ran on the server but exited non-zero
+	FailureClassBootstrap FailureClass = "Bootstrap"
+	// FailureClassBMC is a failed request to the server's BMC
+	FailureClassBMC FailureClass = "BMC"
+	// FailureClassTimeout is a step that did not complete within its time limit
+	FailureClassTimeout FailureClass = "Timeout"
+	// FailureClassUnknown is any other failure
+	FailureClassUnknown FailureClass = "Unknown"
+)
+
+// RetryPolicy controls how failed Operation steps are retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first (default: 3)
+	// +kubebuilder:validation:Minimum=1
+	MaxAttempts int32 `json:"maxAttempts,omitempty"`
+
+	// BackoffBaseSeconds is the delay before the first retry. It doubles on each subsequent retry (default: 30)
+	BackoffBaseSeconds int32 `json:"backoffBaseSeconds,omitempty"`
+
+	// BackoffCapSeconds is the maximum delay between retries (default: 600)
+	BackoffCapSeconds int32 `json:"backoffCapSeconds,omitempty"`
+
+	// RetryOn lists the failure classes that are retried (default: SSH, Tailscale, BMC, Timeout)
+	RetryOn []FailureClass `json:"retryOn,omitempty"`
+}
+
+// OperationFailure records the most recent failed attempt of an Operation
+type OperationFailure struct {
+	// Class of the failure, used to decide whether it is retryable
+	Class FailureClass `json:"class"`
+
+	// Step that failed
+	Step OperationStep `json:"step,omitempty"`
+
+	// Message describing the failure
+	Message string `json:"message,omitempty"`
+
+	// Time of the failure
+	Time metav1.Time `json:"time"`
 }
 
 // LocalObjectReference contains enough information to locate the referenced resource
@@ -42,7 +202,7 @@ type LocalObjectReference struct {
 
 // OperationStatus defines the observed state of Operation
 type OperationStatus struct {
-	// Phase of the operation: Pending, Running, Succeeded, Failed
+	// Phase of the operation: Pending, Running, Succeeded, Failed, Cancelled
 	Phase OperationPhase `json:"phase,omitempty"`
 
 	// StartTime is when the operation started
@@ -56,6 +216,39 @@ type OperationStatus struct {
 
 	// DCJobID is the ID returned by the datacenter API
 	DCJobID string `json:"dcJobID,omitempty"`
+
+	// Step is the workflow step currently being executed
+	Step OperationStep `json:"step,omitempty"`
+
+	// StepStartTime is when the current step started
+	StepStartTime *metav1.Time `json:"stepStartTime,omitempty"`
+
+	// CompletedSteps lists the workflow steps that have finished, in order
+	CompletedSteps []OperationStep `json:"completedSteps,omitempty"`
+
+	// NodeBootID is the host boot ID observed before a reboot, used to detect that the host restarted
+	NodeBootID string `json:"nodeBootID,omitempty"`
+
+	// RouteSyncManaged records that route sync's cleanup finalizer was on the Node when a
+	// decommission deleted it, so route sync removes its Tailscale advertisement
+	RouteSyncManaged bool `json:"routeSyncManaged,omitempty"`
+
+	// Attempts is the number of times the operation has been attempted, including the current one
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// LastFailure describes the most recent failed attempt
+	LastFailure *OperationFailure `json:"lastFailure,omitempty"`
+
+	// NextRetryTime is when the failed step will be retried
+	NextRetryTime *metav1.Time `json:"nextRetryTime,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest observations of the operation's progress
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -63,6 +256,8 @@ type OperationStatus struct {
 // +kubebuilder:printcolumn:name="Server",type="string",JSONPath=".spec.serverRef.name"
 // +kubebuilder:printcolumn:name="Operation",type="string",JSONPath=".spec.operation"
 // +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Step",type="string",JSONPath=".status.step"
+// +kubebuilder:printcolumn:name="Attempts",type="integer",JSONPath=".status.attempts"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Operation represents an operation to be performed on Server
@@ -0,0 +1,158 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OperationSetPhase represents the current phase of an OperationSet
+type OperationSetPhase string
+
+const (
+	OperationSetPhaseRunning   OperationSetPhase = "Running"
+	OperationSetPhasePaused    OperationSetPhase = "Paused"
+	OperationSetPhaseSucceeded OperationSetPhase = "Succeeded"
+	OperationSetPhaseFailed    OperationSetPhase = "Failed"
+)
+
+// OperationSetLabel is set on every Operation created by an OperationSet, with the set's name as value
+const OperationSetLabel = "stargate.io/operation-set"
+
+// OperationSetSpec defines the desired state of OperationSet
+type OperationSetSpec struct {
+	// Selector selects Servers by label. If unset, all Servers in the namespace are selected.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Inventory further restricts the selected Servers by inventory fields
+	Inventory *InventorySelector `json:"inventory,omitempty"`
+
+	// Template is the Operation created for each selected Server
+	Template OperationTemplate `json:"template"`
+
+	// MaxParallel is the maximum number of Operations running at once (default: 1)
+	// +kubebuilder:validation:Minimum=1
+	MaxParallel int32 `json:"maxParallel,omitempty"`
+
+	// MaxUnavailable is the maximum number of Servers that may be unavailable at once, counting
+	// Servers with a running Operation and Servers with a failed one that aren't Ready again
+	// (default: MaxParallel)
+	// +kubebuilder:validation:Minimum=1
+	MaxUnavailable int32 `json:"maxUnavailable,omitempty"`
+
+	// RackByRack finishes every Server in a rack before starting the next one. The rack is the
+	// part of Inventory.Location before "-slot" (e.g. "rack-5" for "rack-5-slot-12").
+	RackByRack bool `json:"rackByRack,omitempty"`
+
+	// FailureThreshold is the number of failed Operations after which no new Operations are
+	// started and the set is Paused (default: 1)
+	// +kubebuilder:validation:Minimum=1
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+
+	// Paused stops new Operations from being started. Running Operations are not affected.
+	Paused bool `json:"paused,omitempty"`
+}
+
+// InventorySelector selects Servers by their inventory metadata
+type InventorySelector struct {
+	// SKU must match the Server's inventory SKU exactly
+	SKU string `json:"sku,omitempty"`
+
+	// LocationPrefix must be a prefix of the Server's inventory location (e.g. "rack-5")
+	LocationPrefix string `json:"locationPrefix,omitempty"`
+}
+
+// OperationTemplate describes the Operation created for each Server of an OperationSet
+type OperationTemplate struct {
+	// ProvisioningProfileRef references the ProvisioningProfile to use for provisioning
+	ProvisioningProfileRef LocalObjectReference `json:"provisioningProfileRef,omitempty"`
+
+	// Operation to perform (e.g., "repave", "reboot", "power-cycle", "wipe", "decommission")
+	Operation OperationType `json:"operation"`
+
+	// RetryPolicy controls whether a failed step is retried
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+
+	// ActiveDeadlineSeconds is how long each Operation may run before it fails
+	// +kubebuilder:validation:Minimum=1
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
+
+	// Drain controls how each Node is cordoned and drained
+	Drain *DrainOptions `json:"drain,omitempty"`
+
+	// Decommission controls the optional parts of each decommission operation
+	Decommission *DecommissionOptions `json:"decommission,omitempty"`
+
+	// Priority orders each Operation against others queued on the same Server
+	Priority int32 `json:"priority,omitempty"`
+}
+
+// OperationSetStatus defines the observed state of OperationSet
+type OperationSetStatus struct {
+	// Phase of the set: Running, Paused, Succeeded, Failed
+	Phase OperationSetPhase `json:"phase,omitempty"`
+
+	// StartTime is when the first Operation was created
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the last Operation finished
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Message provides additional status information
+	Message string `json:"message,omitempty"`
+
+	// Total is the number of selected Servers
+	Total int32 `json:"total"`
+
+	// Pending is the number of selected Servers whose Operation has not been created yet
+	Pending int32 `json:"pending"`
+
+	// Running is the number of Operations that are pending or running
+	Running int32 `json:"running"`
+
+	// Succeeded is the number of Operations that succeeded
+	Succeeded int32 `json:"succeeded"`
+
+	// Failed is the number of Operations that failed or were cancelled
+	Failed int32 `json:"failed"`
+
+	// CurrentRack is the rack being worked on when RackByRack is set
+	CurrentRack string `json:"currentRack,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest observations of the set's progress
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Operation",type="string",JSONPath=".spec.template.operation"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Total",type="integer",JSONPath=".status.total"
+// +kubebuilder:printcolumn:name="Succeeded",type="integer",JSONPath=".status.succeeded"
+// +kubebuilder:printcolumn:name="Failed",type="integer",JSONPath=".status.failed"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// OperationSet rolls an Operation out across a group of Servers
+type OperationSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OperationSetSpec   `json:"spec,omitempty"`
+	Status OperationSetStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// OperationSetList contains a list of OperationSet
+type OperationSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OperationSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OperationSet{}, &OperationSetList{})
+}
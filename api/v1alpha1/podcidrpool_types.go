@@ -0,0 +1,75 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PodCIDRPoolSpec defines the desired state of PodCIDRPool
+type PodCIDRPoolSpec struct {
+	// CIDR is the IPv4 range pod CIDRs are allocated from (e.g., "10.244.64.0/18")
+	CIDR string `json:"cidr"`
+
+	// BlockSize is the prefix length of each allocated pod CIDR (default: 24)
+	// +kubebuilder:validation:Minimum=16
+	// +kubebuilder:validation:Maximum=28
+	BlockSize int32 `json:"blockSize,omitempty"`
+
+	// Reserved lists CIDRs within the range that are never allocated, e.g. the pod CIDRs of
+	// AKS nodes
+	Reserved []string `json:"reserved,omitempty"`
+
+	// Selector selects the Servers the pool allocates for by label. If unset, the pool serves
+	// every Server in the namespace.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// PodCIDRAllocation records a pod CIDR allocated to a Server
+type PodCIDRAllocation struct {
+	// Server is the name of the Server the CIDR is allocated to
+	Server string `json:"server"`
+
+	// CIDR is the allocated pod CIDR
+	CIDR string `json:"cidr"`
+}
+
+// PodCIDRPoolStatus defines the observed state of PodCIDRPool
+type PodCIDRPoolStatus struct {
+	// Allocations lists the pod CIDRs allocated from the pool. It is the source of truth for
+	// which CIDRs are in use.
+	Allocations []PodCIDRAllocation `json:"allocations,omitempty"`
+
+	// Allocated is the number of allocated pod CIDRs
+	Allocated int32 `json:"allocated"`
+
+	// Available is the number of pod CIDRs that can still be allocated
+	Available int32 `json:"available"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="CIDR",type="string",JSONPath=".spec.cidr"
+// +kubebuilder:printcolumn:name="Block",type="integer",JSONPath=".spec.blockSize"
+// +kubebuilder:printcolumn:name="Allocated",type="integer",JSONPath=".status.allocated"
+// +kubebuilder:printcolumn:name="Available",type="integer",JSONPath=".status.available"
+
+// PodCIDRPool is a range of pod CIDRs allocated to Servers, one per Server
+type PodCIDRPool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PodCIDRPoolSpec   `json:"spec,omitempty"`
+	Status PodCIDRPoolStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PodCIDRPoolList contains a list of PodCIDRPool
+type PodCIDRPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PodCIDRPool `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PodCIDRPool{}, &PodCIDRPoolList{})
+}
@@ -0,0 +1,125 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RouteLayer is a layer route sync programs routes in
+type RouteLayer string
+
+const (
+	// RouteLayerAzureRouteTable is a route in an Azure route table
+	RouteLayerAzureRouteTable RouteLayer = "AzureRouteTable"
+	// RouteLayerKernelRoute is a kernel route on a router, changed over SSH
+	RouteLayerKernelRoute RouteLayer = "KernelRoute"
+	// RouteLayerTailscale is the set of routes a router advertises and has enabled in Tailscale
+	RouteLayerTailscale RouteLayer = "Tailscale"
+)
+
+// RouteAction is what a RouteMutation does
+type RouteAction string
+
+const (
+	// RouteActionUpsert creates a route or replaces it
+	RouteActionUpsert RouteAction = "Upsert"
+	// RouteActionDelete deletes a route
+	RouteActionDelete RouteAction = "Delete"
+	// RouteActionAdvertise replaces the routes a router advertises over Tailscale
+	RouteActionAdvertise RouteAction = "Advertise"
+)
+
+// RouteMutation is one change route sync makes to a route layer
+type RouteMutation struct {
+	// Layer is the route layer the mutation changes
+	Layer RouteLayer `json:"layer"`
+
+	// Action is what the mutation does
+	Action RouteAction `json:"action"`
+
+	// Router is the router the route goes through: "aks", "dc" for the router set by the
+	// controller's flags, or "dc/<namespace>/<name>" for a Datacenter's router
+	Router string `json:"router"`
+
+	// RouteTable is the Azure route table, for AzureRouteTable mutations
+	RouteTable string `json:"routeTable,omitempty"`
+
+	// Name is the Azure route name, for AzureRouteTable mutations
+	Name string `json:"name,omitempty"`
+
+	// Route is the destination prefix of an Azure or kernel route
+	Route string `json:"route,omitempty"`
+
+	// NextHop is the next hop IP of an Azure route, or of a kernel route to a worker
+	NextHop string `json:"nextHop,omitempty"`
+
+	// Device is the interface of a kernel route without a next hop, e.g. tailscale0
+	Device string `json:"device,omitempty"`
+
+	// Routes is the full set of routes to advertise, for Tailscale mutations
+	Routes []string `json:"routes,omitempty"`
+
+	// Node is the Node whose routes the mutation programs or removes, if any
+	Node string `json:"node,omitempty"`
+}
+
+// RouteSyncPlanSpec defines the desired state of RouteSyncPlan
+type RouteSyncPlanSpec struct {
+	// ApprovedHash approves the plan with this hash. In apply mode, the route sync controller
+	// executes the plan once its status.hash matches.
+	ApprovedHash string `json:"approvedHash,omitempty"`
+}
+
+// RouteSyncPlanStatus defines the observed state of RouteSyncPlan
+type RouteSyncPlanStatus struct {
+	// Mode is the route sync mode that produced the plan ("plan" or "apply")
+	Mode string `json:"mode,omitempty"`
+
+	// Mutations are the changes route sync intends to make, in the order they are applied
+	Mutations []RouteMutation `json:"mutations,omitempty"`
+
+	// Hash identifies the planned mutations. Set spec.approvedHash to it to approve them.
+	Hash string `json:"hash,omitempty"`
+
+	// LastUpdated is when the plan last changed
+	LastUpdated *metav1.Time `json:"lastUpdated,omitempty"`
+
+	// AppliedHash is the hash of the last plan that was applied
+	AppliedHash string `json:"appliedHash,omitempty"`
+
+	// AppliedTime is when the last plan was applied
+	AppliedTime *metav1.Time `json:"appliedTime,omitempty"`
+
+	// Message describes the outcome of the last apply
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Mode",type="string",JSONPath=".status.mode"
+// +kubebuilder:printcolumn:name="Hash",type="string",JSONPath=".status.hash"
+// +kubebuilder:printcolumn:name="Approved",type="string",JSONPath=".spec.approvedHash"
+// +kubebuilder:printcolumn:name="Applied",type="string",JSONPath=".status.appliedHash"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// RouteSyncPlan holds the route mutations the route sync controller intends to make when it
+// runs in plan or apply mode
+type RouteSyncPlan struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RouteSyncPlanSpec   `json:"spec,omitempty"`
+	Status RouteSyncPlanStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RouteSyncPlanList contains a list of RouteSyncPlan
+type RouteSyncPlanList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RouteSyncPlan `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RouteSyncPlan{}, &RouteSyncPlanList{})
+}
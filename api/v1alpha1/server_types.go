@@ -2,6 +2,7 @@ package v1alpha1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // ServerSpec defines the desired state of Server
@@ -18,6 +19,10 @@ type ServerSpec struct {
 	// RouterIP is the Tailscale IP or FQDN of the subnet router for SSH access (for workers behind a router)
 	RouterIP string `json:"routerIP,omitempty"`
 
+	// DatacenterRef references the Datacenter the server is in. Its router, worker subnet and
+	// pod CIDR pool are used for the server's routes. If unset, the controller's flags are used.
+	DatacenterRef *LocalObjectReference `json:"datacenterRef,omitempty"`
+
 	// BMC configuration for out-of-band management
 	BMC *BMCConfig `json:"bmc,omitempty"`
 
@@ -48,7 +53,7 @@ type ServerInventory struct {
 
 // ServerStatus defines the observed state of Server
 type ServerStatus struct {
-	// State of the hardware: ready, provisioning, error
+	// State of the hardware: ready, provisioning, error, unreachable, off
 	State string `json:"state,omitempty"`
 
 	// CurrentOS version running on the hardware
@@ -62,6 +67,84 @@ type ServerStatus struct {
 
 	// Message provides additional status information
 	Message string `json:"message,omitempty"`
+
+	// ClaimRef references the ServerClaim the server is bound to. A server with a ClaimRef
+	// is not available to other claims.
+	ClaimRef *ClaimReference `json:"claimRef,omitempty"`
+
+	// ActiveOperation references the Operation that holds the server's lock. Other Operations
+	// on the server stay Pending until it finishes.
+	ActiveOperation *OperationReference `json:"activeOperation,omitempty"`
+
+	// OperationHistory summarizes the most recent Operations on the server that were garbage
+	// collected, oldest first
+	OperationHistory []OperationHistoryEntry `json:"operationHistory,omitempty"`
+
+	// LastSeen is when a health probe last reached the server over SSH or saw its Node Ready
+	LastSeen *metav1.Time `json:"lastSeen,omitempty"`
+
+	// PowerState is the power state last reported by the server's BMC
+	PowerState string `json:"powerState,omitempty"`
+
+	// PodCIDR is the pod CIDR allocated to the server from a PodCIDRPool. It is kept across
+	// repaves and released when the server is decommissioned.
+	PodCIDR string `json:"podCIDR,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest observations of the server's state
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ClaimReference identifies the ServerClaim a Server is bound to
+type ClaimReference struct {
+	// Name of the ServerClaim
+	Name string `json:"name"`
+
+	// UID of the ServerClaim, so a re-created claim with the same name is not confused with it
+	UID types.UID `json:"uid"`
+}
+
+// OperationReference identifies the Operation holding a Server's lock
+type OperationReference struct {
+	// Name of the Operation
+	Name string `json:"name"`
+
+	// UID of the Operation, so a re-created Operation with the same name does not inherit the lock
+	UID types.UID `json:"uid"`
+}
+
+// MaxOperationHistory is the number of entries kept in a Server's status.operationHistory
+const MaxOperationHistory = 10
+
+// OperationHistoryEntry summarizes a finished Operation on a Server
+type OperationHistoryEntry struct {
+	// Name of the Operation
+	Name string `json:"name"`
+
+	// Operation type (e.g., "repave", "reboot")
+	Operation OperationType `json:"operation"`
+
+	// Result is the phase the Operation finished in: Succeeded, Failed or Cancelled
+	Result OperationPhase `json:"result"`
+
+	// ProvisioningProfile the Operation used, if any
+	ProvisioningProfile string `json:"provisioningProfile,omitempty"`
+
+	// StartTime is when the Operation started running
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the Operation finished
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Duration is how long the Operation ran
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// Message is the Operation's final status message
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -69,6 +152,8 @@ type ServerStatus struct {
 // +kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state"
 // +kubebuilder:printcolumn:name="OS",type="string",JSONPath=".status.currentOS"
 // +kubebuilder:printcolumn:name="IPv4",type="string",JSONPath=".spec.ipv4"
+// +kubebuilder:printcolumn:name="Claim",type="string",JSONPath=".status.claimRef.name"
+// +kubebuilder:printcolumn:name="Last Seen",type="date",JSONPath=".status.lastSeen"
 
 // Server represents a bare-metal server in a datacenter
 type Server struct {
@@ -0,0 +1,104 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ServerClaimPhase represents the current phase of a ServerClaim
+type ServerClaimPhase string
+
+const (
+	// ServerClaimPhasePending means not enough free Servers match the claim yet
+	ServerClaimPhasePending ServerClaimPhase = "Pending"
+	// ServerClaimPhaseProvisioning means the Servers are bound and being repaved
+	ServerClaimPhaseProvisioning ServerClaimPhase = "Provisioning"
+	// ServerClaimPhaseBound means the requested Servers are bound and ready
+	ServerClaimPhaseBound ServerClaimPhase = "Bound"
+	// ServerClaimPhaseReleasing means the claim was deleted and its Servers are being wiped
+	ServerClaimPhaseReleasing ServerClaimPhase = "Releasing"
+)
+
+// ServerClaimLabel is set on every Operation created for a ServerClaim, with the claim's name as value
+const ServerClaimLabel = "stargate.io/server-claim"
+
+// ReleasePolicy describes what happens to a claim's Servers when the claim is deleted
+type ReleasePolicy string
+
+const (
+	// ReleasePolicyWipe wipes the Servers before returning them to the pool
+	ReleasePolicyWipe ReleasePolicy = "Wipe"
+	// ReleasePolicyRetain returns the Servers to the pool as they are
+	ReleasePolicyRetain ReleasePolicy = "Retain"
+)
+
+// ServerClaimSpec defines the desired state of ServerClaim
+type ServerClaimSpec struct {
+	// Count is the number of Servers requested
+	// +kubebuilder:validation:Minimum=1
+	Count int32 `json:"count"`
+
+	// Selector selects candidate Servers by label. If unset, any Server in the namespace may be bound.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Inventory further restricts the candidate Servers by SKU and location
+	Inventory *InventorySelector `json:"inventory,omitempty"`
+
+	// ProvisioningProfileRef, if set, repaves each bound Server with this profile
+	ProvisioningProfileRef *LocalObjectReference `json:"provisioningProfileRef,omitempty"`
+
+	// ReleasePolicy is what happens to the Servers when the claim is deleted (default: Wipe)
+	// +kubebuilder:validation:Enum=Wipe;Retain
+	ReleasePolicy ReleasePolicy `json:"releasePolicy,omitempty"`
+}
+
+// ServerClaimStatus defines the observed state of ServerClaim
+type ServerClaimStatus struct {
+	// Phase of the claim: Pending, Provisioning, Bound, Releasing
+	Phase ServerClaimPhase `json:"phase,omitempty"`
+
+	// Servers lists the names of the Servers bound to the claim
+	Servers []string `json:"servers,omitempty"`
+
+	// Bound is the number of Servers bound to the claim
+	Bound int32 `json:"bound"`
+
+	// Message provides additional status information
+	Message string `json:"message,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest observations of the claim's state
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Count",type="integer",JSONPath=".spec.count"
+// +kubebuilder:printcolumn:name="Bound",type="integer",JSONPath=".status.bound"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ServerClaim requests a number of Servers from the free pool
+type ServerClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ServerClaimSpec   `json:"spec,omitempty"`
+	Status ServerClaimStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ServerClaimList contains a list of ServerClaim
+type ServerClaimList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ServerClaim `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ServerClaim{}, &ServerClaimList{})
+}
@@ -3,10 +3,13 @@ package main
 import (
 	"encoding/base64"
 	"flag"
+	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"time"
 
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/kubernetes"
@@ -15,9 +18,12 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	api "github.com/vpatelsj/stargate/api/v1alpha1"
 	"github.com/vpatelsj/stargate/controller"
+	"github.com/vpatelsj/stargate/pkg/tailscale"
+	"github.com/vpatelsj/stargate/webhook"
 )
 
 var (
@@ -33,6 +39,13 @@ func init() {
 func main() {
 	var metricsAddr string
 	var probeAddr string
+	var enableLeaderElection bool
+	var leaderElectionNamespace string
+	var serverProbeInterval time.Duration
+	var operationTTL time.Duration
+	var enableWebhooks bool
+	var webhookPort int
+	var webhookCertDir string
 
 	// Bootstrap configuration flags
 	var kindContainerName string
@@ -70,6 +83,13 @@ func main() {
 	var tailnetName string
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8081", "The address the metric endpoint binds to.")
+	flag.BoolVar(&enableLeaderElection, "leader-elect", false, "Enable leader election so only one controller replica reconciles at a time.")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "", "Namespace for the leader election lease (defaults to the in-cluster namespace).")
+	flag.DurationVar(&serverProbeInterval, "server-probe-interval", 2*time.Minute, "How often to probe each Server's health over SSH, its Node and its BMC (0 disables probing).")
+	flag.DurationVar(&operationTTL, "operation-ttl", 7*24*time.Hour, "How long finished Operations are kept before they are deleted, unless they set spec.ttlSecondsAfterFinished (0 keeps them forever).")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false, "Serve the validating and defaulting admission webhooks for Stargate CRDs.")
+	flag.IntVar(&webhookPort, "webhook-port", 9443, "The port the webhook server binds to.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "", "Directory containing tls.crt and tls.key for the webhook server (defaults to the controller-runtime temp dir).")
 
 	// Bootstrap configuration flags
 	flag.StringVar(&kindContainerName, "kind-container", "stargate-demo-control-plane", "Name of the Kind control plane Docker container.")
@@ -87,11 +107,11 @@ func main() {
 	flag.StringVar(&aksResourceGroup, "aks-resource-group", "", "AKS cluster resource group (used for node labels).")
 	flag.StringVar(&aksClusterDNS, "aks-cluster-dns", "10.0.0.10", "AKS cluster DNS service IP.")
 	flag.StringVar(&aksSubscriptionID, "aks-subscription-id", "", "Azure subscription ID for provider-id.")
-	flag.StringVar(&aksVMResourceGroup, "aks-vm-resource-group", "", "Resource group containing the worker VMs.")
+	flag.StringVar(&aksVMResourceGroup, "aks-vm-resource-group", "", "Resource group containing the worker VMs, for Servers whose Datacenter doesn't set one.")
 	flag.StringVar(&aksAPIServerPrivateIP, "aks-api-server-private-ip", "", "Private IP of AKS API server (via Tailscale mesh). When set, kubelet connects through this IP instead of public FQDN.")
 
 	// Routing configuration flags
-	flag.StringVar(&dcRouterTailscaleIP, "dc-router-tailscale-ip", "", "Tailscale IP of the DC router for route updates.")
+	flag.StringVar(&dcRouterTailscaleIP, "dc-router-tailscale-ip", "", "Tailscale IP of the DC router for route updates, for Servers without a Datacenter.")
 	flag.StringVar(&aksRouterTailscaleIP, "aks-router-tailscale-ip", "", "Tailscale IP of the AKS router for route updates.")
 	flag.StringVar(&azureRouteTableName, "azure-route-table-name", "", "Azure route table name for pod CIDR routes.")
 	flag.StringVar(&azureVNetName, "azure-vnet-name", "", "Azure VNet name containing the subnets.")
@@ -102,17 +122,31 @@ func main() {
 	var routerRouteTableName string
 	var tailscaleClientID string
 	var tailscaleClientSecret string
+	var workerNodeSelector string
+	var aksNodeSelector string
+	var nameHeuristics bool
+	var routeResyncInterval time.Duration
+	var aksNodeSubnetCIDR string
+	var routeSyncMode string
+	var routeSyncPlanNamespace string
 	flag.BoolVar(&enableRouteSync, "enable-route-sync", false, "Enable the route sync controller to automatically update Azure routes when nodes join.")
 	flag.StringVar(&aksNodeResourceGroup, "aks-node-resource-group", "", "Resource group containing AKS managed infrastructure (MC_*). Required for route sync.")
 	flag.StringVar(&routerSubnetName, "router-subnet-name", "", "Subnet name where the Tailscale router lives.")
 	flag.StringVar(&aksRouterPrivateIP, "aks-router-private-ip", "", "Private IP of the AKS router VM (e.g., 10.237.0.4). Used as next-hop for Azure route tables.")
 	flag.StringVar(&routerRouteTableName, "router-route-table-name", "stargate-router-rt", "Route table name for router subnet (return traffic). Created if doesn't exist.")
-	flag.StringVar(&dcSubnetCIDR, "dc-subnet-cidr", "10.50.0.0/16", "DC subnet CIDR to route through the router.")
+	flag.StringVar(&dcSubnetCIDR, "dc-subnet-cidr", "10.50.0.0/16", "DC subnet CIDR to route through the router, for Servers without a Datacenter.")
+	flag.StringVar(&aksNodeSubnetCIDR, "aks-node-subnet-cidr", controller.DefaultAKSNodeSubnetCIDR, "AKS node subnet CIDR the AKS router advertises over Tailscale.")
 	flag.StringVar(&dcPodCIDR, "dc-pod-cidr", "10.244.50.0/20", "DC pod CIDR range to route through the router.")
 	flag.StringVar(&tailscaleAPIKey, "tailscale-api-key", "", "Tailscale API key for route management (or set TAILSCALE_API_KEY env).")
 	flag.StringVar(&tailscaleClientID, "tailscale-client-id", "", "Tailscale OAuth client ID (or set TAILSCALE_CLIENT_ID env).")
 	flag.StringVar(&tailscaleClientSecret, "tailscale-client-secret", "", "Tailscale OAuth client secret (or set TAILSCALE_CLIENT_SECRET env).")
 	flag.StringVar(&tailnetName, "tailnet-name", "", "Tailscale tailnet name (defaults to API key's tailnet).")
+	flag.StringVar(&workerNodeSelector, "route-sync-worker-selector", controller.DefaultWorkerNodeSelector, "Label selector of the Nodes route sync treats as stargate DC workers.")
+	flag.StringVar(&aksNodeSelector, "route-sync-aks-selector", controller.DefaultAKSNodeSelector, "Label selector of the Nodes route sync treats as AKS nodes.")
+	flag.DurationVar(&routeResyncInterval, "route-resync-interval", controller.DefaultRouteResyncInterval, "How often route sync diffs the Azure route tables against the Nodes and repairs drift (0 disables).")
+	flag.BoolVar(&nameHeuristics, "route-sync-name-heuristics", false, "Classify Nodes neither route sync selector matches by their name (for Nodes bootstrapped without stargate.io labels).")
+	flag.StringVar(&routeSyncMode, "route-sync-mode", controller.RouteSyncModeAuto, "Route sync mode: 'auto' (apply route changes right away), 'plan' (only publish them to the RouteSyncPlan) or 'apply' (publish them and apply approved plans).")
+	flag.StringVar(&routeSyncPlanNamespace, "route-sync-plan-namespace", controller.DefaultRouteSyncPlanNamespace, "Namespace of the RouteSyncPlan and ConfigMap route sync publishes its plan to in plan and apply modes, and of the ConfigMap recording the applied Tailscale advertisements.")
 
 	opts := zap.Options{
 		Development: true,
@@ -125,9 +159,16 @@ func main() {
 	restConfig := ctrl.GetConfigOrDie()
 
 	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
-		Scheme:                 scheme,
-		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
-		HealthProbeBindAddress: probeAddr,
+		Scheme:                  scheme,
+		Metrics:                 metricsserver.Options{BindAddress: metricsAddr},
+		HealthProbeBindAddress:  probeAddr,
+		LeaderElection:          enableLeaderElection,
+		LeaderElectionID:        "stargate-azure-controller.stargate.io",
+		LeaderElectionNamespace: leaderElectionNamespace,
+		WebhookServer: ctrlwebhook.NewServer(ctrlwebhook.Options{
+			Port:    webhookPort,
+			CertDir: webhookCertDir,
+		}),
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to create manager")
@@ -152,10 +193,37 @@ func main() {
 		aksAPIServer = restConfig.Host
 	}
 
+	// Tailscale credentials: use env vars if flags not provided
+	tsAPIKey := tailscaleAPIKey
+	if tsAPIKey == "" {
+		tsAPIKey = os.Getenv("TAILSCALE_API_KEY")
+	}
+	tsClientID := tailscaleClientID
+	if tsClientID == "" {
+		tsClientID = os.Getenv("TAILSCALE_CLIENT_ID")
+	}
+	tsClientSecret := tailscaleClientSecret
+	if tsClientSecret == "" {
+		tsClientSecret = os.Getenv("TAILSCALE_CLIENT_SECRET")
+	}
+
+	// Tailscale API client for decommission operations (optional) - prefer OAuth, fall back to API key
+	var tsClient *tailscale.Client
+	if tsClientID != "" && tsClientSecret != "" {
+		tsClient, err = tailscale.NewClientWithOAuth(tsClientID, tsClientSecret, tailnetName, slog.Default())
+	} else if tsAPIKey != "" {
+		tsClient, err = tailscale.NewClient(tsAPIKey, tailnetName, slog.Default())
+	}
+	if err != nil {
+		setupLog.Error(err, "unable to create Tailscale client")
+		os.Exit(1)
+	}
+
 	// Set up Operation controller
 	if err = (&controller.OperationReconciler{
 		Client:                  mgr.GetClient(),
 		Scheme:                  mgr.GetScheme(),
+		Recorder:                mgr.GetEventRecorderFor("azure-controller"),
 		KindContainerName:       kindContainerName,
 		ControlPlaneTailscaleIP: controlPlaneTailscaleIP,
 		ControlPlaneHostname:    controlPlaneHostname,
@@ -178,31 +246,110 @@ func main() {
 		AzureSubnetName:         azureSubnetName,
 		Clientset:               clientset,
 		CACertBase64:            caCertBase64,
+		Tailscale:               tsClient,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Operation")
 		os.Exit(1)
 	}
 
+	// Set up ProvisioningProfile controller
+	if err = (&controller.ProvisioningProfileReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ProvisioningProfile")
+		os.Exit(1)
+	}
+
+	// Set up Server health controller (if enabled)
+	if serverProbeInterval > 0 {
+		if err = (&controller.ServerHealthReconciler{
+			Client:            mgr.GetClient(),
+			Scheme:            mgr.GetScheme(),
+			Recorder:          mgr.GetEventRecorderFor("azure-controller"),
+			Provider:          "azure",
+			ProbeInterval:     serverProbeInterval,
+			SSHPrivateKeyPath: sshPrivateKeyPath,
+			SSHPort:           sshPort,
+			AdminUsername:     adminUsername,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ServerHealth")
+			os.Exit(1)
+		}
+	}
+
+	// Export Server counts per state and SKU
+	if err = controller.RegisterServerMetrics(mgr.GetClient(), "azure"); err != nil {
+		setupLog.Error(err, "unable to register metrics")
+		os.Exit(1)
+	}
+
+	// Set up finished Operation garbage collection
+	if err = (&controller.OperationGCReconciler{
+		Client:     mgr.GetClient(),
+		Scheme:     mgr.GetScheme(),
+		Provider:   "azure",
+		DefaultTTL: operationTTL,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "OperationGC")
+		os.Exit(1)
+	}
+
+	// Set up OperationSet controller
+	if err = (&controller.OperationSetReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "OperationSet")
+		os.Exit(1)
+	}
+
+	if err = (&controller.ServerClaimReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ServerClaim")
+		os.Exit(1)
+	}
+
+	if err = (&controller.PodCIDRPoolReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PodCIDRPool")
+		os.Exit(1)
+	}
+
+	// Set up admission webhooks (if enabled)
+	if enableWebhooks {
+		if err = webhook.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhooks")
+			os.Exit(1)
+		}
+		setupLog.Info("Admission webhooks enabled", "port", webhookPort)
+	}
+
 	// Set up Route Sync controller (if enabled)
 	if enableRouteSync {
-		// Use env vars if flags not provided
-		tsAPIKey := tailscaleAPIKey
-		if tsAPIKey == "" {
-			tsAPIKey = os.Getenv("TAILSCALE_API_KEY")
+		if !controller.ValidRouteSyncMode(routeSyncMode) {
+			setupLog.Error(fmt.Errorf("unknown mode %q", routeSyncMode), "invalid route sync mode")
+			os.Exit(1)
 		}
-		tsClientID := tailscaleClientID
-		if tsClientID == "" {
-			tsClientID = os.Getenv("TAILSCALE_CLIENT_ID")
+		workerSelector, err := labels.Parse(workerNodeSelector)
+		if err != nil {
+			setupLog.Error(err, "invalid route sync worker selector")
+			os.Exit(1)
 		}
-		tsClientSecret := tailscaleClientSecret
-		if tsClientSecret == "" {
-			tsClientSecret = os.Getenv("TAILSCALE_CLIENT_SECRET")
+		aksSelector, err := labels.Parse(aksNodeSelector)
+		if err != nil {
+			setupLog.Error(err, "invalid route sync AKS selector")
+			os.Exit(1)
 		}
-
 		if err = (&controller.RouteSyncReconciler{
 			Client:                mgr.GetClient(),
 			Scheme:                mgr.GetScheme(),
 			Logger:                slog.Default(),
+			Recorder:              mgr.GetEventRecorderFor("azure-controller"),
 			SubscriptionID:        aksSubscriptionID,
 			AKSResourceGroup:      aksNodeResourceGroup,
 			ClusterResourceGroup:  aksResourceGroup,
@@ -211,6 +358,7 @@ func main() {
 			RouterRouteTableName:  routerRouteTableName,
 			RouterSubnetName:      routerSubnetName,
 			AKSSubnetName:         azureSubnetName,
+			AKSNodeSubnetCIDR:     aksNodeSubnetCIDR,
 			DCSubnetCIDR:          dcSubnetCIDR,
 			DCPodCIDR:             dcPodCIDR,
 			DCResourceGroup:       aksVMResourceGroup,
@@ -222,6 +370,12 @@ func main() {
 			TailscaleClientID:     tsClientID,
 			TailscaleClientSecret: tsClientSecret,
 			TailnetName:           tailnetName,
+			WorkerNodeSelector:    workerSelector,
+			AKSNodeSelector:       aksSelector,
+			NameHeuristics:        nameHeuristics,
+			ResyncInterval:        routeResyncInterval,
+			Mode:                  routeSyncMode,
+			PlanNamespace:         routeSyncPlanNamespace,
 		}).SetupWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create controller", "controller", "RouteSync")
 			os.Exit(1)
@@ -233,7 +387,12 @@ func main() {
 			"aksRouterPrivateIP", aksRouterPrivateIP,
 			"aksRouterTSIP", aksRouterTailscaleIP,
 			"dcRouterTSIP", dcRouterTailscaleIP,
-			"tailscaleOAuth", tsClientID != "")
+			"tailscaleOAuth", tsClientID != "",
+			"workerSelector", workerSelector.String(),
+			"aksSelector", aksSelector.String(),
+			"nameHeuristics", nameHeuristics,
+			"resyncInterval", routeResyncInterval,
+			"mode", routeSyncMode)
 	}
 
 	// Add health checks
@@ -308,6 +308,8 @@ write_files:
             value: /
           - name: node-ip
             value: "$TAILSCALE_IP"
+          - name: node-labels
+            value: stargate.io/role=worker
       ---
       apiVersion: kubelet.config.k8s.io/v1beta1
       kind: KubeletConfiguration
@@ -470,6 +472,8 @@ nodeRegistration:
       value: /
     - name: node-ip
       value: "$TAILSCALE_IP"
+    - name: node-labels
+      value: stargate.io/role=worker
 ---
 apiVersion: kubelet.config.k8s.io/v1beta1
 kind: KubeletConfiguration
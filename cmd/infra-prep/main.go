@@ -1688,40 +1688,8 @@ func ensureAKSRouteToWorkers(ctx context.Context, subscriptionID string, aksInfo
 		return fmt.Errorf("wait for route: %w", err)
 	}
 
-	// Add routes for DC worker pod CIDRs (10.244.50-250.0/24 range based on derivePodCIDR logic)
-	// These use the same formula as derivePodCIDR: (third_octet * 10 + fourth_octet) % 200 + 50
-	// For typical DC workers in 10.X.1.5, 10.X.1.6, etc., we need to add their pod CIDRs
-	// We derive based on workerVNetCIDR - extract the third octet
-	workerSubnetParts := strings.Split(workerVNetCIDR, ".")
-	if len(workerSubnetParts) >= 3 {
-		thirdOctet := 1 // Default for /24 worker subnet
-		if len(workerSubnetParts) >= 3 {
-			fmt.Sscanf(workerSubnetParts[2], "%d", &thirdOctet)
-		}
-		// Add routes for workers .5 through .10 (typical worker range)
-		for fourthOctet := 5; fourthOctet <= 10; fourthOctet++ {
-			uniqueOctet := (thirdOctet*10+fourthOctet)%200 + 50
-			podCIDR := fmt.Sprintf("10.244.%d.0/24", uniqueOctet)
-			routeName := fmt.Sprintf("pod-cidr-worker-%d", fourthOctet-4)
-			fmt.Printf("[aks-rt] adding DC worker pod route %s -> %s via %s...\n", routeName, podCIDR, aksRouterIP)
-
-			pollerRoute, err := routeClient.BeginCreateOrUpdate(ctx, aksInfo.NodeResourceGroup, rtName, routeName, armnetwork.Route{
-				Properties: &armnetwork.RoutePropertiesFormat{
-					AddressPrefix:    to.Ptr(podCIDR),
-					NextHopType:      to.Ptr(armnetwork.RouteNextHopTypeVirtualAppliance),
-					NextHopIPAddress: to.Ptr(aksRouterIP),
-				},
-			}, nil)
-			if err != nil {
-				fmt.Printf("[aks-rt] warning: failed to add route %s: %v\n", routeName, err)
-				continue
-			}
-			if _, err := pollerRoute.PollUntilDone(ctx, nil); err != nil {
-				fmt.Printf("[aks-rt] warning: failed to wait for route %s: %v\n", routeName, err)
-			}
-		}
-	}
-	fmt.Printf("[aks-rt] DC worker pod routes added\n")
+	// DC worker pod CIDRs are allocated from a PodCIDRPool when the workers are repaved,
+	// and route sync adds their routes to this table
 
 	// Associate route table with AKS subnet
 	subnet, err := subnetClient.Get(ctx, aksInfo.NodeResourceGroup, aksInfo.VNetName, aksInfo.SubnetName, nil)
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"os"
 	"path/filepath"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -31,6 +32,10 @@ func init() {
 func main() {
 	var metricsAddr string
 	var probeAddr string
+	var enableLeaderElection bool
+	var leaderElectionNamespace string
+	var serverProbeInterval time.Duration
+	var operationTTL time.Duration
 	var kubeconfig string
 
 	// Control plane configuration
@@ -56,6 +61,10 @@ func main() {
 	}
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8083", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8084", "The address the probe endpoint binds to.")
+	flag.BoolVar(&enableLeaderElection, "leader-elect", false, "Enable leader election so only one controller replica reconciles at a time.")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "", "Namespace for the leader election lease (defaults to the in-cluster namespace).")
+	flag.DurationVar(&serverProbeInterval, "server-probe-interval", 2*time.Minute, "How often to probe each Server's health over SSH, its Node and its BMC (0 disables probing).")
+	flag.DurationVar(&operationTTL, "operation-ttl", 7*24*time.Hour, "How long finished Operations are kept before they are deleted, unless they set spec.ttlSecondsAfterFinished (0 keeps them forever).")
 
 	// Control plane configuration flags
 	flag.StringVar(&controlPlaneTailscaleIP, "control-plane-ip", "", "Tailscale IP of the control plane (auto-detected if not provided).")
@@ -101,9 +110,12 @@ func main() {
 
 	// Create manager
 	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
-		Scheme:                 scheme,
-		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
-		HealthProbeBindAddress: probeAddr,
+		Scheme:                  scheme,
+		Metrics:                 metricsserver.Options{BindAddress: metricsAddr},
+		HealthProbeBindAddress:  probeAddr,
+		LeaderElection:          enableLeaderElection,
+		LeaderElectionID:        "stargate-qemu-controller.stargate.io",
+		LeaderElectionNamespace: leaderElectionNamespace,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to create manager")
@@ -114,6 +126,7 @@ func main() {
 	if err = (&controller.QemuOperationReconciler{
 		Client:                  mgr.GetClient(),
 		Scheme:                  mgr.GetScheme(),
+		Recorder:                mgr.GetEventRecorderFor("qemu-controller"),
 		ControlPlaneTailscaleIP: controlPlaneTailscaleIP,
 		ControlPlaneHostname:    controlPlaneHostname,
 		SSHPrivateKeyPath:       sshPrivateKeyPath,
@@ -124,6 +137,50 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Set up ProvisioningProfile controller. The azure-controller validates the same
+	// profiles; both compute the same status and only write it when it changes.
+	if err = (&controller.ProvisioningProfileReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ProvisioningProfile")
+		os.Exit(1)
+	}
+
+	// Set up Server health controller (if enabled)
+	if serverProbeInterval > 0 {
+		if err = (&controller.ServerHealthReconciler{
+			Client:            mgr.GetClient(),
+			Scheme:            mgr.GetScheme(),
+			Recorder:          mgr.GetEventRecorderFor("qemu-controller"),
+			Provider:          "qemu",
+			ProbeInterval:     serverProbeInterval,
+			SSHPrivateKeyPath: sshPrivateKeyPath,
+			SSHPort:           sshPort,
+			AdminUsername:     adminUsername,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ServerHealth")
+			os.Exit(1)
+		}
+	}
+
+	// Export Server counts per state and SKU
+	if err = controller.RegisterServerMetrics(mgr.GetClient(), "qemu"); err != nil {
+		setupLog.Error(err, "unable to register metrics")
+		os.Exit(1)
+	}
+
+	// Set up finished Operation garbage collection
+	if err = (&controller.OperationGCReconciler{
+		Client:     mgr.GetClient(),
+		Scheme:     mgr.GetScheme(),
+		Provider:   "qemu",
+		DefaultTTL: operationTTL,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "OperationGC")
+		os.Exit(1)
+	}
+
 	// Add health checks
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
@@ -6,9 +6,12 @@ import (
 	"os"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -71,6 +74,7 @@ func main() {
 	reconciler := &SimulatorReconciler{
 		Client:       mgr.GetClient(),
 		Scheme:       mgr.GetScheme(),
+		Recorder:     mgr.GetEventRecorderFor("simulator"),
 		NetworkMgr:   networkMgr,
 		ImageMgr:     imageMgr,
 		CloudInitGen: cloudInitGen,
@@ -96,6 +100,7 @@ func main() {
 type SimulatorReconciler struct {
 	client.Client
 	Scheme       *runtime.Scheme
+	Recorder     record.EventRecorder
 	NetworkMgr   *qemu.NetworkManager
 	ImageMgr     *qemu.ImageManager
 	CloudInitGen *qemu.CloudInitGenerator
@@ -118,8 +123,9 @@ func (r *SimulatorReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, nil
 	}
 
-	// Skip if already completed or failed
-	if operation.Status.Phase == api.OperationPhaseSucceeded || operation.Status.Phase == api.OperationPhaseFailed {
+	// Skip if already completed, failed or cancelled
+	if operation.Status.Phase == api.OperationPhaseSucceeded || operation.Status.Phase == api.OperationPhaseFailed ||
+		operation.Status.Phase == api.OperationPhaseCancelled {
 		return ctrl.Result{}, nil
 	}
 
@@ -225,18 +231,15 @@ func (r *SimulatorReconciler) handlePending(ctx context.Context, operation *api.
 	operation.Status.Phase = api.OperationPhaseRunning
 	operation.Status.StartTime = &now
 	operation.Status.Message = "VM started, waiting for provisioning"
+	setOperationConditions(operation)
 	if err := r.Status().Update(ctx, operation); err != nil {
 		log.Error(err, "Failed to update Operation status")
 		return ctrl.Result{}, err
 	}
+	r.Recorder.Eventf(operation, corev1.EventTypeNormal, api.EventReasonStarted, "VM %s started at %s", vmName, vmIP)
 
 	// Update server status
-	server.Status.State = "provisioning"
-	server.Status.Message = "VM started at " + vmIP
-	server.Status.LastUpdated = now
-	if err := r.Status().Update(ctx, server); err != nil {
-		log.Error(err, "Failed to update Server status")
-	}
+	r.setServerState(ctx, server, "provisioning", "VM started at "+vmIP)
 
 	// Requeue to check completion
 	return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
@@ -282,9 +285,11 @@ func (r *SimulatorReconciler) setOperationFailed(ctx context.Context, operation
 	operation.Status.Phase = api.OperationPhaseFailed
 	operation.Status.CompletionTime = &now
 	operation.Status.Message = message
+	setOperationConditions(operation)
 	if err := r.Status().Update(ctx, operation); err != nil {
 		return ctrl.Result{}, err
 	}
+	r.Recorder.Event(operation, corev1.EventTypeWarning, api.EventReasonFailed, message)
 	return ctrl.Result{}, nil
 }
 
@@ -296,23 +301,81 @@ func (r *SimulatorReconciler) setOperationSucceeded(ctx context.Context, operati
 	operation.Status.Phase = api.OperationPhaseSucceeded
 	operation.Status.CompletionTime = &now
 	operation.Status.Message = "VM provisioning complete"
+	setOperationConditions(operation)
 	if err := r.Status().Update(ctx, operation); err != nil {
 		log.Error(err, "Failed to update Operation status")
 		return ctrl.Result{}, err
 	}
+	r.Recorder.Event(operation, corev1.EventTypeNormal, api.EventReasonSucceeded, operation.Status.Message)
 
-	// Update server
+	// Update server. Updating the spec returns the stored status, so the status is set afterwards.
 	vmIP, _ := r.NetworkMgr.GetIP(vmName)
-	server.Status.State = "ready"
-	server.Status.Message = "Provisioned successfully"
-	server.Status.LastUpdated = now
 	server.Spec.IPv4 = vmIP
 	if err := r.Update(ctx, server); err != nil {
 		log.Error(err, "Failed to update Server")
 	}
+	r.setServerState(ctx, server, "ready", "Provisioned successfully")
+
+	return ctrl.Result{}, nil
+}
+
+// setServerState updates the Server's state and records a StateChanged Event if it changed
+func (r *SimulatorReconciler) setServerState(ctx context.Context, server *api.Server, state, message string) {
+	previous := server.Status.State
+	server.Status.State = state
+	server.Status.Message = message
+	server.Status.LastUpdated = metav1.Now()
+	setServerConditions(server)
 	if err := r.Status().Update(ctx, server); err != nil {
-		log.Error(err, "Failed to update Server status")
+		ctrl.LoggerFrom(ctx).Error(err, "Failed to update Server status")
+		return
+	}
+	if state != previous {
+		r.Recorder.Eventf(server, corev1.EventTypeNormal, api.EventReasonStateChanged, "State changed from %q to %q: %s", previous, state, message)
 	}
+}
 
-	return ctrl.Result{}, nil
+// setOperationConditions derives the Operation's conditions from its phase
+func setOperationConditions(operation *api.Operation) {
+	operation.Status.ObservedGeneration = operation.Generation
+
+	cond := metav1.Condition{
+		Type:               api.ConditionSucceeded,
+		Status:             metav1.ConditionUnknown,
+		ObservedGeneration: operation.Generation,
+		Reason:             api.ReasonInProgress,
+		Message:            operation.Status.Message,
+	}
+	switch operation.Status.Phase {
+	case api.OperationPhaseSucceeded:
+		cond.Status, cond.Reason = metav1.ConditionTrue, api.ReasonSucceeded
+		// A simulated VM that finished provisioning has booted and run cloud-init
+		meta.SetStatusCondition(&operation.Status.Conditions, metav1.Condition{
+			Type:               api.ConditionBootstrapped,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: operation.Generation,
+			Reason:             api.ReasonSucceeded,
+			Message:            "VM provisioned",
+		})
+	case api.OperationPhaseFailed:
+		cond.Status, cond.Reason = metav1.ConditionFalse, api.ReasonFailed
+	}
+	meta.SetStatusCondition(&operation.Status.Conditions, cond)
+}
+
+// setServerConditions derives the Server's Ready condition from its state
+func setServerConditions(server *api.Server) {
+	server.Status.ObservedGeneration = server.Generation
+
+	cond := metav1.Condition{
+		Type:               api.ConditionReady,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: server.Generation,
+		Reason:             api.ReasonInProgress,
+		Message:            server.Status.Message,
+	}
+	if server.Status.State == "ready" {
+		cond.Status, cond.Reason = metav1.ConditionTrue, api.ReasonSucceeded
+	}
+	meta.SetStatusCondition(&server.Status.Conditions, cond)
 }
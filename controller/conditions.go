@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// serverConditionTypes are the Operation conditions that also describe the Server itself
+var serverConditionTypes = []string{
+	api.ConditionSSHReachable,
+	api.ConditionBootstrapped,
+	api.ConditionNodeRegistered,
+	api.ConditionRoutesProgrammed,
+}
+
+// setCondition sets a condition, stamping it with the generation it was observed at
+func setCondition(conditions *[]metav1.Condition, generation int64, condType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// setOperationPhaseCondition derives the Operation's Succeeded condition from its phase
+func setOperationPhaseCondition(operation *api.Operation) {
+	operation.Status.ObservedGeneration = operation.Generation
+
+	switch operation.Status.Phase {
+	case api.OperationPhaseSucceeded:
+		setCondition(&operation.Status.Conditions, operation.Generation, api.ConditionSucceeded, metav1.ConditionTrue, api.ReasonSucceeded, operation.Status.Message)
+	case api.OperationPhaseFailed:
+		setCondition(&operation.Status.Conditions, operation.Generation, api.ConditionSucceeded, metav1.ConditionFalse, api.ReasonFailed, operation.Status.Message)
+	case api.OperationPhaseCancelled:
+		setCondition(&operation.Status.Conditions, operation.Generation, api.ConditionSucceeded, metav1.ConditionFalse, api.ReasonCancelled, operation.Status.Message)
+	default:
+		setCondition(&operation.Status.Conditions, operation.Generation, api.ConditionSucceeded, metav1.ConditionUnknown, api.ReasonInProgress, operation.Status.Message)
+	}
+}
+
+// setServerReadyCondition derives the Server's Ready condition from its state
+func setServerReadyCondition(server *api.Server) {
+	server.Status.ObservedGeneration = server.Generation
+
+	if server.Status.State == "ready" {
+		setCondition(&server.Status.Conditions, server.Generation, api.ConditionReady, metav1.ConditionTrue, api.ReasonSucceeded, server.Status.Message)
+		return
+	}
+
+	// The state itself (provisioning, rebooting, error, ...) is the most useful reason
+	reason := api.ReasonInProgress
+	if server.Status.State != "" {
+		reason = conditionReason(server.Status.State)
+	}
+	setCondition(&server.Status.Conditions, server.Generation, api.ConditionReady, metav1.ConditionFalse, reason, server.Status.Message)
+}
+
+// copyServerConditions copies the server-level conditions an operation has observed onto its Server
+func copyServerConditions(server *api.Server, operation *api.Operation) {
+	for _, condType := range serverConditionTypes {
+		if cond := meta.FindStatusCondition(operation.Status.Conditions, condType); cond != nil {
+			setCondition(&server.Status.Conditions, server.Generation, cond.Type, cond.Status, cond.Reason, cond.Message)
+		}
+	}
+}
+
+// updateProfileValidity records whether a ProvisioningProfile is valid, writing the
+// status only when it changes so every reconcile doesn't bump the resourceVersion
+func updateProfileValidity(ctx context.Context, c client.Client, profile *api.ProvisioningProfile, resolveErr error) error {
+	if profile.Name == "" {
+		return nil
+	}
+
+	status, reason, message := metav1.ConditionTrue, api.ReasonValid, "Profile is valid"
+	if resolveErr != nil {
+		status, reason, message = metav1.ConditionFalse, api.ReasonInvalid, resolveErr.Error()
+	}
+
+	current := meta.FindStatusCondition(profile.Status.Conditions, api.ConditionProfileValid)
+	if current != nil && current.Status == status && current.Message == message &&
+		current.ObservedGeneration == profile.Generation {
+		return nil
+	}
+
+	profile.Status.Ready = resolveErr == nil
+	profile.Status.Message = message
+	profile.Status.ObservedGeneration = profile.Generation
+	setCondition(&profile.Status.Conditions, profile.Generation, api.ConditionProfileValid, status, reason, message)
+	setCondition(&profile.Status.Conditions, profile.Generation, api.ConditionReady, status, reason, message)
+	if err := c.Status().Update(ctx, profile); err != nil {
+		return fmt.Errorf("update ProvisioningProfile %s status: %w", profile.Name, err)
+	}
+	return nil
+}
+
+// conditionReason converts a lowercase, dash-separated state (e.g. "power-cycling")
+// into a CamelCase condition reason ("PowerCycling")
+func conditionReason(state string) string {
+	reason := make([]byte, 0, len(state))
+	upper := true
+	for i := 0; i < len(state); i++ {
+		ch := state[i]
+		if ch == '-' || ch == '_' || ch == ' ' {
+			upper = true
+			continue
+		}
+		if upper && ch >= 'a' && ch <= 'z' {
+			ch -= 'a' - 'A'
+		}
+		upper = false
+		reason = append(reason, ch)
+	}
+	return string(reason)
+}
@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// getServerDatacenter returns the Datacenter a Server references, or nil if it has none.
+// Servers without a Datacenter use the routers and subnets configured by the controller's flags.
+func getServerDatacenter(ctx context.Context, c client.Reader, server *api.Server) (*api.Datacenter, error) {
+	if server.Spec.DatacenterRef == nil || server.Spec.DatacenterRef.Name == "" {
+		return nil, nil
+	}
+	var datacenter api.Datacenter
+	key := client.ObjectKey{Namespace: server.Namespace, Name: server.Spec.DatacenterRef.Name}
+	if err := c.Get(ctx, key, &datacenter); err != nil {
+		return nil, fmt.Errorf("get datacenter %s of server %s: %w", key.Name, server.Name, err)
+	}
+	return &datacenter, nil
+}
+
+// datacenterPodCIDRPool returns the name of the PodCIDRPool a Datacenter assigns its Servers,
+// or "" if the pool is chosen by selector
+func datacenterPodCIDRPool(datacenter *api.Datacenter) string {
+	if datacenter == nil || datacenter.Spec.PodCIDRPoolRef == nil {
+		return ""
+	}
+	return datacenter.Spec.PodCIDRPoolRef.Name
+}
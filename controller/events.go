@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// recordEvent records an Event on obj. It does nothing if recorder is nil, so reconcilers
+// built without one (e.g. in tests) still work.
+func recordEvent(recorder record.EventRecorder, obj runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if recorder == nil {
+		return
+	}
+	recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+}
+
+// recordServerState records a StateChanged Event on the Server if its state changed from
+// previous. Moving into error or unreachable is a Warning.
+func recordServerState(recorder record.EventRecorder, server *api.Server, previous string) {
+	if server.Status.State == previous {
+		return
+	}
+	eventType := corev1.EventTypeNormal
+	switch server.Status.State {
+	case "error", "unreachable":
+		eventType = corev1.EventTypeWarning
+	}
+	recordEvent(recorder, server, eventType, api.EventReasonStateChanged, "State changed from %q to %q: %s", previous, server.Status.State, server.Status.Message)
+}
+
+// recordOperationResult records a Succeeded or Failed Event once the operation finishes.
+// Cancellations are recorded when the operation is aborted.
+func recordOperationResult(recorder record.EventRecorder, operation *api.Operation) {
+	switch operation.Status.Phase {
+	case api.OperationPhaseSucceeded:
+		recordEvent(recorder, operation, corev1.EventTypeNormal, api.EventReasonSucceeded, operation.Status.Message)
+	case api.OperationPhaseFailed:
+		recordEvent(recorder, operation, corev1.EventTypeWarning, api.EventReasonFailed, operation.Status.Message)
+	}
+}
+
+// recordRetry records a RetryScheduled Event for an operation whose step failed and will be retried
+func recordRetry(recorder record.EventRecorder, operation *api.Operation, delay time.Duration) {
+	message := ""
+	if operation.Status.LastFailure != nil {
+		message = operation.Status.LastFailure.Message
+	}
+	recordEvent(recorder, operation, corev1.EventTypeWarning, api.EventReasonRetryScheduled,
+		"Step %s failed (attempt %d), retrying in %s: %s", operation.Status.Step, operation.Status.Attempts, delay, message)
+}
+
+// recordBootstrapStarted records a BootstrapStarted Event on the operation and its Server
+func recordBootstrapStarted(recorder record.EventRecorder, operation *api.Operation, server *api.Server) {
+	recordEvent(recorder, operation, corev1.EventTypeNormal, api.EventReasonBootstrapStarted, "Bootstrapping server %s", server.Name)
+	recordEvent(recorder, server, corev1.EventTypeNormal, api.EventReasonBootstrapStarted, "Bootstrap started by operation %s", operation.Name)
+}
+
+// recordBootstrapResult records a BootstrapSucceeded or BootstrapFailed Event on the operation
+// and its Server
+func recordBootstrapResult(recorder record.EventRecorder, operation *api.Operation, server *api.Server, err error) {
+	if err != nil {
+		recordEvent(recorder, operation, corev1.EventTypeWarning, api.EventReasonBootstrapFailed, "Bootstrap of server %s failed: %v", server.Name, err)
+		recordEvent(recorder, server, corev1.EventTypeWarning, api.EventReasonBootstrapFailed, "Bootstrap by operation %s failed: %v", operation.Name, err)
+		return
+	}
+	recordEvent(recorder, operation, corev1.EventTypeNormal, api.EventReasonBootstrapSucceeded, "Bootstrapped server %s", server.Name)
+	recordEvent(recorder, server, corev1.EventTypeNormal, api.EventReasonBootstrapSucceeded, "Bootstrapped by operation %s", operation.Name)
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+func TestRecordServerState(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous string
+		state    string
+		want     string
+	}{
+		{"unchanged", "ready", "ready", ""},
+		{"provisioning", "ready", "provisioning", "Normal StateChanged State changed from \"ready\" to \"provisioning\": msg"},
+		{"error is a warning", "provisioning", "error", "Warning StateChanged State changed from \"provisioning\" to \"error\": msg"},
+		{"unreachable is a warning", "ready", "unreachable", "Warning StateChanged State changed from \"ready\" to \"unreachable\": msg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(1)
+			server := &api.Server{Status: api.ServerStatus{State: tt.state, Message: "msg"}}
+			recordServerState(recorder, server, tt.previous)
+
+			got := ""
+			select {
+			case got = <-recorder.Events:
+			default:
+			}
+			if got != tt.want {
+				t.Errorf("event = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordOperationResult(t *testing.T) {
+	tests := []struct {
+		phase api.OperationPhase
+		want  string
+	}{
+		{api.OperationPhaseSucceeded, "Normal Succeeded done"},
+		{api.OperationPhaseFailed, "Warning Failed done"},
+		{api.OperationPhaseRunning, ""},
+		// Cancellations are recorded by abortOperation
+		{api.OperationPhaseCancelled, ""},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.phase), func(t *testing.T) {
+			recorder := record.NewFakeRecorder(1)
+			op := &api.Operation{Status: api.OperationStatus{Phase: tt.phase, Message: "done"}}
+			recordOperationResult(recorder, op)
+
+			got := ""
+			select {
+			case got = <-recorder.Events:
+			default:
+			}
+			if got != tt.want {
+				t.Errorf("event = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// A nil recorder is allowed
+	recordOperationResult(nil, &api.Operation{Status: api.OperationStatus{Phase: api.OperationPhaseFailed}})
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// Route sync layers, used as the "layer" label of the route sync metrics
+const (
+	routeLayerAzureRouteTable = "azure_route_table"
+	routeLayerKernelRoute     = "kernel_route"
+	routeLayerTailscale       = "tailscale"
+)
+
+// serverMetricsTimeout bounds the Server list done on each scrape
+const serverMetricsTimeout = 10 * time.Second
+
+var (
+	operationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "stargate_operation_duration_seconds",
+		Help:    "Time from start to completion of finished Operations.",
+		Buckets: []float64{30, 60, 120, 300, 600, 900, 1200, 1800, 2700, 3600, 7200},
+	}, []string{"operation", "provider", "result"})
+
+	bootstrapFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "stargate_bootstrap_failures_total",
+		Help: "Failed attempts of repave steps, by the step (phase) that failed.",
+	}, []string{"provider", "phase"})
+
+	routeSyncTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "stargate_route_sync_total",
+		Help: "Route programming attempts, by layer, router and result (success or error).",
+	}, []string{"layer", "router", "result"})
+
+	routeSyncLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "stargate_route_sync_last_success_timestamp_seconds",
+		Help: "Unix time of the last successful route programming on each router.",
+	}, []string{"router"})
+
+	routeSyncUnclassifiedNodes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "stargate_route_sync_unclassified_nodes",
+		Help: "Nodes route sync ignores because neither the worker nor the AKS node selector matches them.",
+	})
+
+	routeDriftRepairs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "stargate_route_drift_repairs_total",
+		Help: "Routes the route table resync changed to repair drift, by route table, action (upsert or delete) and result.",
+	}, []string{"route_table", "action", "result"})
+
+	serversDesc = prometheus.NewDesc(
+		"stargate_servers",
+		"Number of Servers, by provider, state and SKU.",
+		[]string{"provider", "state", "sku"}, nil,
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(operationDuration, bootstrapFailures, routeSyncTotal, routeSyncLastSuccess, routeSyncUnclassifiedNodes, routeDriftRepairs)
+}
+
+// observeOperationDuration records the duration of a finished operation
+func observeOperationDuration(operation *api.Operation, provider string) {
+	if operation.Status.StartTime == nil || operation.Status.CompletionTime == nil {
+		return
+	}
+	operationType := operation.Spec.Operation
+	if operationType == "" {
+		operationType = api.OperationTypeRepave
+	}
+	duration := operation.Status.CompletionTime.Sub(operation.Status.StartTime.Time)
+	operationDuration.WithLabelValues(string(operationType), provider, string(operation.Status.Phase)).Observe(duration.Seconds())
+}
+
+// observeBootstrapFailure counts a failed attempt of the current step of a repave operation
+func observeBootstrapFailure(operation *api.Operation, provider string) {
+	if operation.Spec.Operation != "" && operation.Spec.Operation != api.OperationTypeRepave {
+		return
+	}
+	bootstrapFailures.WithLabelValues(provider, string(operation.Status.Step)).Inc()
+}
+
+// observeRouteSync counts an attempt to program a route layer on a router and, if it
+// succeeded, records the time of the router's last successful sync. router is the router's
+// key ("aks", "dc" or "dc/namespace/name"), so every site's router has its own series.
+func observeRouteSync(layer, router string, err error) {
+	if err != nil {
+		routeSyncTotal.WithLabelValues(layer, router, "error").Inc()
+		return
+	}
+	routeSyncTotal.WithLabelValues(layer, router, "success").Inc()
+	routeSyncLastSuccess.WithLabelValues(router).SetToCurrentTime()
+}
+
+// observeRouteDrift counts a change the route table resync made to a route table
+func observeRouteDrift(routeTable, action string, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	routeDriftRepairs.WithLabelValues(routeTable, action, result).Inc()
+}
+
+// serverCollector reports the number of Servers per state and SKU. Servers are counted from
+// the manager's cache on each scrape, so the gauges never go stale.
+type serverCollector struct {
+	reader   client.Reader
+	provider string
+}
+
+// RegisterServerMetrics registers the stargate_servers gauges for the Servers of a provider.
+// reader should be the manager's cached client.
+func RegisterServerMetrics(reader client.Reader, provider string) error {
+	return metrics.Registry.Register(&serverCollector{reader: reader, provider: provider})
+}
+
+// Describe implements prometheus.Collector
+func (c *serverCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- serversDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *serverCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), serverMetricsTimeout)
+	defer cancel()
+
+	var servers api.ServerList
+	if err := c.reader.List(ctx, &servers); err != nil {
+		ctrllog.Log.WithName("metrics").Error(err, "Failed to list Servers")
+		return
+	}
+	for key, count := range countServers(servers.Items, c.provider) {
+		ch <- prometheus.MustNewConstMetric(serversDesc, prometheus.GaugeValue, float64(count), c.provider, key.state, key.sku)
+	}
+}
+
+// serverCountKey groups Servers for the stargate_servers gauges
+type serverCountKey struct {
+	state string
+	sku   string
+}
+
+// countServers counts the Servers managed by provider per state and SKU
+func countServers(servers []api.Server, provider string) map[serverCountKey]int {
+	counts := make(map[serverCountKey]int)
+	for i := range servers {
+		server := &servers[i]
+		if server.Spec.Provider != "" && server.Spec.Provider != provider {
+			continue
+		}
+		state := server.Status.State
+		if state == "" {
+			state = "unknown"
+		}
+		counts[serverCountKey{state: state, sku: server.Spec.Inventory.SKU}]++
+	}
+	return counts
+}
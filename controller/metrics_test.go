@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"testing"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+func TestCountServers(t *testing.T) {
+	server := func(provider, state, sku string) api.Server {
+		return api.Server{
+			Spec:   api.ServerSpec{Provider: provider, Inventory: api.ServerInventory{SKU: sku}},
+			Status: api.ServerStatus{State: state},
+		}
+	}
+	servers := []api.Server{
+		server("azure", "ready", "GPU-8xH100"),
+		server("azure", "ready", "GPU-8xH100"),
+		server("", "provisioning", "GPU-8xH100"),
+		server("azure", "", "CPU-64"),
+		server("qemu", "ready", "GPU-8xH100"),
+	}
+
+	got := countServers(servers, "azure")
+	want := map[serverCountKey]int{
+		{state: "ready", sku: "GPU-8xH100"}:        2,
+		{state: "provisioning", sku: "GPU-8xH100"}: 1,
+		{state: "unknown", sku: "CPU-64"}:          1,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("countServers() = %v, want %v", got, want)
+	}
+	for key, count := range want {
+		if got[key] != count {
+			t.Errorf("countServers()[%v] = %d, want %d", key, got[key], count)
+		}
+	}
+}
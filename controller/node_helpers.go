@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// podNodeNameField is the field index used to list the pods scheduled on a node
+const podNodeNameField = "spec.nodeName"
+
+// indexPodNodeName is the indexer function for podNodeNameField
+func indexPodNodeName(obj client.Object) []string {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || pod.Spec.NodeName == "" {
+		return nil
+	}
+	return []string{pod.Spec.NodeName}
+}
+
+// getNode fetches a Node by name, returning nil (and no error) if it does not exist
+func getNode(ctx context.Context, c client.Client, name string) (*corev1.Node, error) {
+	node := &corev1.Node{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return node, nil
+}
+
+// nodeDeletionTimeout bounds how long a deleted Node may stay Terminating, e.g. while
+// route sync removes its routes before releasing the stargate.io/route-cleanup finalizer
+const nodeDeletionTimeout = 5 * time.Minute
+
+// nodeDeletionPending reports whether a deleted Node is still Terminating. A kubelet registering
+// again under the same name would collide with it, so steps poll until it is gone and fail once
+// they have waited longer than nodeDeletionTimeout.
+func nodeDeletionPending(operation *api.Operation, node *corev1.Node) (bool, error) {
+	if node == nil {
+		return false, nil
+	}
+	if stepElapsed(operation) > nodeDeletionTimeout {
+		return false, timeoutError("node %s still terminating after %s", node.Name, nodeDeletionTimeout)
+	}
+	return true, nil
+}
+
+// setNodeUnschedulable cordons or uncordons a Node
+func setNodeUnschedulable(ctx context.Context, c client.Client, node *corev1.Node, unschedulable bool) error {
+	if node.Spec.Unschedulable == unschedulable {
+		return nil
+	}
+
+	patch := client.MergeFrom(node.DeepCopy())
+	node.Spec.Unschedulable = unschedulable
+	if err := c.Patch(ctx, node, patch); err != nil {
+		return fmt.Errorf("patch node %s unschedulable=%t: %w", node.Name, unschedulable, err)
+	}
+	return nil
+}
+
+// isNodeReady returns true if the Node reports a Ready condition of True
+func isNodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// drainNode evicts the evictable pods on a node through the Eviction API, so PodDisruptionBudgets
+// are honored. DaemonSet-managed pods, mirror pods and pods that have already finished are skipped.
+// A non-nil gracePeriodSeconds overrides the pods' termination grace period.
+// It returns the number of evictable pods still present on the node; callers should
+// requeue until this reaches zero.
+func drainNode(ctx context.Context, c client.Client, nodeName string, gracePeriodSeconds *int64) (int, error) {
+	logger := log.FromContext(ctx)
+
+	var pods corev1.PodList
+	if err := c.List(ctx, &pods, client.MatchingFields{podNodeNameField: nodeName}); err != nil {
+		return 0, fmt.Errorf("list pods on node %s: %w", nodeName, err)
+	}
+
+	remaining := 0
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !isEvictable(pod) {
+			continue
+		}
+		remaining++
+
+		// Already terminating - just wait for it to go away
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+
+		eviction := &policyv1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+			},
+		}
+		if gracePeriodSeconds != nil {
+			eviction.DeleteOptions = &metav1.DeleteOptions{GracePeriodSeconds: gracePeriodSeconds}
+		}
+		if err := c.SubResource("eviction").Create(ctx, pod, eviction); err != nil {
+			if apierrors.IsNotFound(err) {
+				remaining--
+				continue
+			}
+			// 429 means a PodDisruptionBudget is blocking the eviction; retry later
+			if apierrors.IsTooManyRequests(err) {
+				logger.Info("Eviction blocked by PodDisruptionBudget, will retry", "pod", client.ObjectKeyFromObject(pod))
+				continue
+			}
+			return remaining, fmt.Errorf("evict pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+		logger.Info("Evicted pod", "pod", client.ObjectKeyFromObject(pod), "node", nodeName)
+	}
+
+	return remaining, nil
+}
+
+// isEvictable returns true if a pod should be evicted when draining its node
+func isEvictable(pod *corev1.Pod) bool {
+	// Mirror (static) pods are managed by the kubelet and can't be evicted
+	if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+		return false
+	}
+	// Finished pods don't need to be evicted
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	// DaemonSet pods would just be recreated on the same node
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,224 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// operationFinalizer lets the controller put the Server back in a sane state before
+// an in-progress Operation is deleted
+const operationFinalizer = "stargate.io/operation-cleanup"
+
+// cancelPollInterval is how often a running step checks whether its Operation was cancelled
+const cancelPollInterval = 5 * time.Second
+
+var (
+	errOperationCancelled = errors.New("operation cancelled")
+	errDeadlineExceeded   = errors.New("operation exceeded its active deadline")
+)
+
+// isOperationFinished returns true if the operation is in a terminal phase
+func isOperationFinished(operation *api.Operation) bool {
+	switch operation.Status.Phase {
+	case api.OperationPhaseSucceeded, api.OperationPhaseFailed, api.OperationPhaseCancelled:
+		return true
+	}
+	return false
+}
+
+// operationDeadline returns when the operation's active deadline expires, if it has one
+func operationDeadline(operation *api.Operation) (time.Time, bool) {
+	if operation.Spec.ActiveDeadlineSeconds == nil || operation.Status.StartTime == nil {
+		return time.Time{}, false
+	}
+	return operation.Status.StartTime.Add(time.Duration(*operation.Spec.ActiveDeadlineSeconds) * time.Second), true
+}
+
+// abortReason returns why an in-progress operation must stop, or nil if it may continue
+func abortReason(operation *api.Operation) error {
+	if operation.DeletionTimestamp != nil || operation.Spec.Cancel {
+		return errOperationCancelled
+	}
+	if deadline, ok := operationDeadline(operation); ok && time.Now().After(deadline) {
+		return errDeadlineExceeded
+	}
+	return nil
+}
+
+// stepContext returns a context for running an operation step. It is cancelled when the
+// operation's deadline passes, or when the operation is cancelled or deleted while the step
+// is running; cancelling it kills any in-flight SSH session. context.Cause on the returned
+// context reports which of these happened.
+func stepContext(ctx context.Context, c client.Client, operation *api.Operation) (context.Context, context.CancelFunc) {
+	stepCtx, cancel := context.WithCancelCause(ctx)
+	stopDeadline := func() {}
+	if deadline, ok := operationDeadline(operation); ok {
+		stepCtx, stopDeadline = context.WithDeadlineCause(stepCtx, deadline, errDeadlineExceeded)
+	}
+
+	key := client.ObjectKeyFromObject(operation)
+	go func() {
+		ticker := time.NewTicker(cancelPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stepCtx.Done():
+				return
+			case <-ticker.C:
+				var latest api.Operation
+				if err := c.Get(stepCtx, key, &latest); err != nil {
+					continue
+				}
+				if latest.DeletionTimestamp != nil || latest.Spec.Cancel {
+					log.FromContext(ctx).Info("Operation cancelled, aborting in-flight step", "step", latest.Status.Step)
+					cancel(errOperationCancelled)
+					return
+				}
+			}
+		}
+	}()
+
+	return stepCtx, func() {
+		stopDeadline()
+		cancel(nil)
+	}
+}
+
+// stepAbortCause returns errOperationCancelled or errDeadlineExceeded if the step context
+// was stopped for one of those reasons, or nil otherwise
+func stepAbortCause(stepCtx context.Context) error {
+	cause := context.Cause(stepCtx)
+	if errors.Is(cause, errOperationCancelled) || errors.Is(cause, errDeadlineExceeded) {
+		return cause
+	}
+	return nil
+}
+
+// ensureOperationFinalizer adds the cleanup finalizer to an in-progress operation
+func ensureOperationFinalizer(ctx context.Context, c client.Client, operation *api.Operation) error {
+	if !controllerutil.AddFinalizer(operation, operationFinalizer) {
+		return nil
+	}
+	if err := c.Update(ctx, operation); err != nil {
+		return fmt.Errorf("add finalizer: %w", err)
+	}
+	return nil
+}
+
+// removeOperationFinalizer removes the cleanup finalizer, if present
+func removeOperationFinalizer(ctx context.Context, c client.Client, operation *api.Operation) error {
+	if !controllerutil.RemoveFinalizer(operation, operationFinalizer) {
+		return nil
+	}
+	if err := c.Update(ctx, operation); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("remove finalizer: %w", err)
+	}
+	return nil
+}
+
+// abortOperation stops an in-progress operation that was cancelled, deleted or ran past its
+// deadline. The Server is put back in a sane state and, unless the operation is being
+// deleted, its final status is recorded. provider labels the operation's metrics.
+func abortOperation(ctx context.Context, c client.Client, recorder record.EventRecorder, provider string, operation *api.Operation, server *api.Server, reason error) error {
+	logger := log.FromContext(ctx)
+	logger.Info("Aborting operation", "reason", reason.Error(), "step", operation.Status.Step)
+
+	eventReason := api.EventReasonCancelled
+	if errors.Is(reason, errDeadlineExceeded) {
+		eventReason = api.EventReasonDeadlineExceeded
+	}
+	recordEvent(recorder, operation, corev1.EventTypeWarning, eventReason, "Aborting during step %s: %v", operation.Status.Step, reason)
+
+	if operation.Status.Phase == api.OperationPhaseRunning {
+		if err := restoreServer(ctx, c, recorder, server, operation, reason); err != nil {
+			logger.Error(err, "Failed to restore Server after abort", "server", server.Name)
+		}
+	}
+
+	if operation.DeletionTimestamp != nil {
+		if err := releaseServerLock(ctx, c, operation); err != nil {
+			return err
+		}
+		return removeOperationFinalizer(ctx, c, operation)
+	}
+
+	// The spec was just changed (cancel) so re-fetch to avoid a conflict
+	if err := c.Get(ctx, client.ObjectKeyFromObject(operation), operation); err != nil {
+		return err
+	}
+
+	now := metav1.Now()
+	operation.Status.CompletionTime = &now
+	operation.Status.NextRetryTime = nil
+	if errors.Is(reason, errDeadlineExceeded) {
+		operation.Status.Phase = api.OperationPhaseFailed
+		operation.Status.Message = fmt.Sprintf("Operation exceeded its active deadline of %ds during step %s", *operation.Spec.ActiveDeadlineSeconds, operation.Status.Step)
+		setOperationPhaseCondition(operation)
+		setCondition(&operation.Status.Conditions, operation.Generation, api.ConditionSucceeded, metav1.ConditionFalse, api.ReasonDeadlineExceeded, operation.Status.Message)
+	} else {
+		operation.Status.Phase = api.OperationPhaseCancelled
+		operation.Status.Message = fmt.Sprintf("Operation cancelled during step %s", operation.Status.Step)
+		setOperationPhaseCondition(operation)
+	}
+	if err := c.Status().Update(ctx, operation); err != nil {
+		return fmt.Errorf("update aborted Operation status: %w", err)
+	}
+	observeOperationDuration(operation, provider)
+
+	return removeOperationFinalizer(ctx, c, operation)
+}
+
+// restoreServer puts the Server of an aborted operation back in a sane state: its Node is
+// uncordoned, and the Server is marked ready if the Node is Ready, or error otherwise
+func restoreServer(ctx context.Context, c client.Client, recorder record.EventRecorder, server *api.Server, operation *api.Operation, reason error) error {
+	node, err := getNode(ctx, c, server.Name)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Get(ctx, client.ObjectKeyFromObject(server), server); err != nil {
+		return err
+	}
+	previous := server.Status.State
+	server.Status.State = "error"
+	server.Status.Message = fmt.Sprintf("Operation %s aborted (%v) during step %s; server needs attention", operation.Name, reason, operation.Status.Step)
+	if node != nil {
+		if err := setNodeUnschedulable(ctx, c, node, false); err != nil {
+			return err
+		}
+		recordEvent(recorder, node, corev1.EventTypeNormal, api.EventReasonNodeUncordoned, "Uncordoned after operation %s was aborted", operation.Name)
+		if isNodeReady(node) {
+			server.Status.State = "ready"
+			server.Status.Message = fmt.Sprintf("Operation %s aborted (%v); node is Ready", operation.Name, reason)
+		}
+	}
+	server.Status.LastUpdated = metav1.Now()
+	setServerReadyCondition(server)
+	if err := c.Status().Update(ctx, server); err != nil {
+		return err
+	}
+	recordServerState(recorder, server, previous)
+	return nil
+}
+
+// handleOperationLifecycle handles deletion, cancellation and deadlines for an in-progress
+// operation, and adds the cleanup finalizer otherwise. It returns true if the operation
+// was aborted and the reconcile is finished.
+func handleOperationLifecycle(ctx context.Context, c client.Client, recorder record.EventRecorder, provider string, operation *api.Operation, server *api.Server) (bool, error) {
+	if reason := abortReason(operation); reason != nil {
+		return true, abortOperation(ctx, c, recorder, provider, operation, server, reason)
+	}
+	return false, ensureOperationFinalizer(ctx, c, operation)
+}
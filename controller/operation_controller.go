@@ -18,17 +18,21 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	api "github.com/vpatelsj/stargate/api/v1alpha1"
+	"github.com/vpatelsj/stargate/pkg/tailscale"
 )
 
 // OperationReconciler reconciles an Operation object
 type OperationReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 
 	// Bootstrap configuration (defaults, can be overridden by ProvisioningProfile)
 	KindContainerName       string
@@ -56,6 +60,9 @@ type OperationReconciler struct {
 	AzureVNetName        string // Azure VNet name containing the subnets
 	AzureSubnetName      string // Azure subnet name where AKS nodes reside
 
+	// Tailscale API client used to clean up the tailnet when a server is decommissioned (optional)
+	Tailscale *tailscale.Client
+
 	// Runtime fields (populated automatically)
 	Clientset    *kubernetes.Clientset // For creating SA tokens
 	CACertBase64 string                // Fetched from rest config
@@ -69,6 +76,13 @@ type bootstrapConfig struct {
 	sshPrivateKey     string // The actual key content or path
 	sshPrivateKeyPath string // Temp file path if from secret
 	sshPort           int
+
+	// Router of the server's datacenter, from its Datacenter or the controller's flags
+	dcRouterIP            string
+	dcRouterAdminUsername string
+	dcRouterSSHKeyPath    string
+
+	vmResourceGroup string // Azure resource group of the server's VM
 }
 
 // +kubebuilder:rbac:groups=stargate.io,resources=operations,verbs=get;list;watch;create;update;patch;delete
@@ -77,7 +91,15 @@ type bootstrapConfig struct {
 // +kubebuilder:rbac:groups=stargate.io,resources=servers,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=stargate.io,resources=servers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=stargate.io,resources=provisioningprofiles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=stargate.io,resources=podcidrpools,verbs=get;list;watch
+// +kubebuilder:rbac:groups=stargate.io,resources=podcidrpools/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=stargate.io,resources=datacenters,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods/eviction,verbs=create
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile handles Operation reconciliation
 func (r *OperationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -89,9 +111,13 @@ func (r *OperationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	// Skip if already completed
-	if operation.Status.Phase == api.OperationPhaseSucceeded || operation.Status.Phase == api.OperationPhaseFailed {
-		return ctrl.Result{}, nil
+	// Nothing to do once finished; just release the Server's lock and the finalizer so
+	// queued Operations can start and this one can be deleted
+	if isOperationFinished(&operation) {
+		if err := releaseServerLock(ctx, r.Client, &operation); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, removeOperationFinalizer(ctx, r.Client, &operation)
 	}
 
 	// Fetch referenced Server
@@ -111,20 +137,52 @@ func (r *OperationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, nil
 	}
 
-	// Fetch referenced ProvisioningProfile
-	var profile api.ProvisioningProfile
-	profileKey := client.ObjectKey{
-		Namespace: operation.Namespace,
-		Name:      operation.Spec.ProvisioningProfileRef.Name,
+	// Make the Operation a dependent of its Server so deleting the Server cascades
+	if err := ensureServerOwner(ctx, r.Client, r.Scheme, &operation, &server); err != nil {
+		return ctrl.Result{}, err
 	}
-	if err := r.Get(ctx, profileKey, &profile); err != nil {
-		logger.Error(err, "Failed to get ProvisioningProfile", "provisioningProfile", operation.Spec.ProvisioningProfileRef.Name)
-		return r.updateOperationStatus(ctx, &operation, api.OperationPhaseFailed, fmt.Sprintf("ProvisioningProfile not found: %v", err))
+
+	// Handle deletion, cancellation and deadlines before starting any more work
+	if aborted, err := handleOperationLifecycle(ctx, r.Client, r.Recorder, "azure", &operation, &server); aborted || err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Fetch referenced ProvisioningProfile. Only repave and upgrade require one; other
+	// operation types fall back to the controller defaults when it is omitted.
+	var profile api.ProvisioningProfile
+	if operation.Spec.ProvisioningProfileRef.Name != "" {
+		profileKey := client.ObjectKey{
+			Namespace: operation.Namespace,
+			Name:      operation.Spec.ProvisioningProfileRef.Name,
+		}
+		if err := r.Get(ctx, profileKey, &profile); err != nil {
+			logger.Error(err, "Failed to get ProvisioningProfile", "provisioningProfile", operation.Spec.ProvisioningProfileRef.Name)
+			return r.updateOperationStatus(ctx, &operation, api.OperationPhaseFailed, fmt.Sprintf("ProvisioningProfile not found: %v", err))
+		}
+	} else if operation.Spec.Operation == api.OperationTypeRepave || operation.Spec.Operation == "" {
+		return r.updateOperationStatus(ctx, &operation, api.OperationPhaseFailed, "provisioningProfileRef is required for repave operations")
+	} else if operation.Spec.Operation == api.OperationTypeUpgrade {
+		return r.updateOperationStatus(ctx, &operation, api.OperationPhaseFailed, "provisioningProfileRef is required for upgrade operations")
 	}
 
 	// Handle based on current phase
 	switch operation.Status.Phase {
 	case "", api.OperationPhasePending:
+		// Wait for the ProvisioningProfile to be validated rather than failing mid-bootstrap.
+		// Operations already running carry on if it turns not ready.
+		if profile.Name != "" {
+			if ready, message := profileReadiness(&profile); !ready {
+				return waitForProfile(ctx, r.Client, &operation, message)
+			}
+		}
+		// Only one Operation runs on a Server at a time; the rest queue in priority order
+		locked, message, err := acquireServerLock(ctx, r.Client, &operation, &server)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !locked {
+			return waitForServerLock(ctx, r.Client, r.Recorder, &operation, message)
+		}
 		return r.handlePending(ctx, &operation, &server, &profile)
 	case api.OperationPhaseRunning:
 		return r.handleRunning(ctx, &operation, &server, &profile)
@@ -133,84 +191,28 @@ func (r *OperationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	}
 }
 
-// handlePending initiates the repave operation
+// handlePending initiates the operation
 func (r *OperationReconciler) handlePending(ctx context.Context, operation *api.Operation, server *api.Server, profile *api.ProvisioningProfile) (ctrl.Result, error) {
-	logger := log.FromContext(ctx)
-	logger.Info("Initiating repave via SSH bootstrap", "server", server.Name, "ipv4", server.Spec.IPv4, "k8sVersion", profile.Spec.KubernetesVersion)
-
-	// Resolve bootstrap configuration from profile and secrets
-	cfg, cleanup, err := r.resolveBootstrapConfig(ctx, operation.Namespace, profile)
-	if err != nil {
-		logger.Error(err, "Failed to resolve bootstrap config")
-		return r.updateOperationStatus(ctx, operation, api.OperationPhaseFailed, fmt.Sprintf("Failed to resolve config: %v", err))
-	}
-	defer cleanup()
-
-	// Update server status to provisioning
-	server.Status.State = "provisioning"
-	server.Status.Message = fmt.Sprintf("Repave initiated by operation %s", operation.Name)
-	server.Status.LastUpdated = metav1.Now()
-	if err := r.Status().Update(ctx, server); err != nil {
-		logger.Error(err, "Failed to update Server status")
-		return ctrl.Result{RequeueAfter: 5 * time.Second}, err
-	}
-
-	// Update operation status to running
-	operation.Status.Phase = api.OperationPhaseRunning
-	now := metav1.Now()
-	operation.Status.StartTime = &now
-	operation.Status.Message = "Bootstrap in progress"
-
-	if err := r.Status().Update(ctx, operation); err != nil {
-		logger.Error(err, "Failed to update Operation status")
-		return ctrl.Result{RequeueAfter: 5 * time.Second}, err
-	}
-
-	// Perform SSH bootstrap
-	if err := r.bootstrapServer(ctx, server, profile, cfg); err != nil {
-		logger.Error(err, "Bootstrap failed", "server", server.Name)
-
-		// Update server status to error
-		server.Status.State = "error"
-		server.Status.Message = fmt.Sprintf("Bootstrap failed: %v", err)
-		server.Status.LastUpdated = metav1.Now()
-		if updateErr := r.Status().Update(ctx, server); updateErr != nil {
-			logger.Error(updateErr, "Failed to update Server status to error")
-		}
-
-		return r.updateOperationStatus(ctx, operation, api.OperationPhaseFailed, fmt.Sprintf("Bootstrap failed: %v", err))
-	}
-
-	// Configure routing for the new node (DC router, AKS router, Azure route tables)
-	if err := r.configureNodeRouting(ctx, server, cfg); err != nil {
-		logger.Error(err, "Failed to configure routing (node will function but may have connectivity issues)", "server", server.Name)
-		// Don't fail the operation, just log the warning - routing can be fixed manually
-	}
-
-	// Bootstrap succeeded - update server status
-	logger.Info("Bootstrap succeeded", "server", server.Name)
-	server.Status.State = "ready"
-	server.Status.CurrentOS = fmt.Sprintf("k8s-%s", profile.Spec.KubernetesVersion)
-	server.Status.AppliedProvisioningProfile = profile.Name
-	server.Status.Message = fmt.Sprintf("Repaved successfully by operation %s", operation.Name)
-	server.Status.LastUpdated = metav1.Now()
-	if err := r.Status().Update(ctx, server); err != nil {
-		logger.Error(err, "Failed to update Server status to ready")
-	}
-
-	return r.updateOperationStatus(ctx, operation, api.OperationPhaseSucceeded, "Repave completed successfully")
+	return r.startWorkflow(ctx, operation, server, profile)
 }
 
-// resolveBootstrapConfig resolves configuration from profile and secrets
-func (r *OperationReconciler) resolveBootstrapConfig(ctx context.Context, namespace string, profile *api.ProvisioningProfile) (*bootstrapConfig, func(), error) {
+// resolveBootstrapConfig resolves configuration from profile, the server's Datacenter and secrets
+func (r *OperationReconciler) resolveBootstrapConfig(ctx context.Context, server *api.Server, profile *api.ProvisioningProfile) (*bootstrapConfig, func(), error) {
 	cfg := &bootstrapConfig{
 		kubernetesVersion: profile.Spec.KubernetesVersion,
 		adminUsername:     r.AdminUsername,
 		sshPrivateKeyPath: r.SSHPrivateKeyPath,
 		sshPort:           r.SSHPort,
+		dcRouterIP:        r.DCRouterTailscaleIP,
+		vmResourceGroup:   r.AKSVMResourceGroup,
 	}
 
-	cleanup := func() {} // No-op by default
+	var cleanups []func()
+	cleanup := func() {
+		for _, f := range cleanups {
+			f()
+		}
+	}
 
 	// Override admin username from profile if set
 	if profile.Spec.AdminUsername != "" {
@@ -239,47 +241,96 @@ func (r *OperationReconciler) resolveBootstrapConfig(ctx context.Context, namesp
 
 	// Fetch SSH credentials from secret if specified
 	if profile.Spec.SSHCredentialsSecretRef != "" {
-		var secret corev1.Secret
-		secretKey := client.ObjectKey{
-			Namespace: namespace,
-			Name:      profile.Spec.SSHCredentialsSecretRef,
+		keyPath, username, remove, err := sshCredentialsFromSecret(ctx, r.Client, server.Namespace, profile.Spec.SSHCredentialsSecretRef)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		cleanups = append(cleanups, remove)
+		if keyPath != "" {
+			cfg.sshPrivateKeyPath = keyPath
 		}
-		if err := r.Get(ctx, secretKey, &secret); err != nil {
-			return nil, cleanup, fmt.Errorf("failed to get SSH credentials secret %s: %w", profile.Spec.SSHCredentialsSecretRef, err)
+		if username != "" {
+			cfg.adminUsername = username
 		}
+	}
+
+	// The DC router is reached with the same credentials as the server unless its Datacenter
+	// has its own
+	cfg.dcRouterAdminUsername = cfg.adminUsername
+	cfg.dcRouterSSHKeyPath = cfg.sshPrivateKeyPath
 
-		// Get private key
-		if privateKey, ok := secret.Data["privateKey"]; ok {
-			// Write to temp file
-			tmpFile, err := os.CreateTemp("", "ssh-key-*")
+	datacenter, err := getServerDatacenter(ctx, r.Client, server)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	if datacenter != nil {
+		cfg.dcRouterIP = datacenter.Spec.Router.TailscaleIP
+		if datacenter.Spec.ResourceGroup != "" {
+			cfg.vmResourceGroup = datacenter.Spec.ResourceGroup
+		}
+		if ref := datacenter.Spec.SSHCredentialsSecretRef; ref != "" {
+			keyPath, username, remove, err := sshCredentialsFromSecret(ctx, r.Client, server.Namespace, ref)
 			if err != nil {
-				return nil, cleanup, fmt.Errorf("failed to create temp file for SSH key: %w", err)
+				return nil, cleanup, err
 			}
-			if err := os.WriteFile(tmpFile.Name(), privateKey, 0600); err != nil {
-				os.Remove(tmpFile.Name())
-				return nil, cleanup, fmt.Errorf("failed to write SSH key to temp file: %w", err)
+			cleanups = append(cleanups, remove)
+			if keyPath != "" {
+				cfg.dcRouterSSHKeyPath = keyPath
 			}
-			cfg.sshPrivateKeyPath = tmpFile.Name()
-			cleanup = func() {
-				os.Remove(tmpFile.Name())
+			cfg.dcRouterAdminUsername = "ubuntu"
+			if username != "" {
+				cfg.dcRouterAdminUsername = username
 			}
 		}
+	}
+
+	return cfg, cleanup, nil
+}
 
-		// Get username if present
-		if username, ok := secret.Data["username"]; ok {
-			cfg.adminUsername = string(username)
+// sshCredentialsFromSecret reads SSH credentials from a Secret with keys "privateKey" and
+// optionally "username". The private key is written to a temp file, which remove deletes.
+func sshCredentialsFromSecret(ctx context.Context, c client.Reader, namespace, name string) (keyPath, username string, remove func(), err error) {
+	remove = func() {} // No-op by default
+
+	var secret corev1.Secret
+	secretKey := client.ObjectKey{
+		Namespace: namespace,
+		Name:      name,
+	}
+	if err := c.Get(ctx, secretKey, &secret); err != nil {
+		return "", "", remove, fmt.Errorf("failed to get SSH credentials secret %s: %w", name, err)
+	}
+
+	// Get private key
+	if privateKey, ok := secret.Data["privateKey"]; ok {
+		// Write to temp file
+		tmpFile, err := os.CreateTemp("", "ssh-key-*")
+		if err != nil {
+			return "", "", remove, fmt.Errorf("failed to create temp file for SSH key: %w", err)
+		}
+		tmpFile.Close()
+		if err := os.WriteFile(tmpFile.Name(), privateKey, 0600); err != nil {
+			os.Remove(tmpFile.Name())
+			return "", "", remove, fmt.Errorf("failed to write SSH key to temp file: %w", err)
+		}
+		keyPath = tmpFile.Name()
+		remove = func() {
+			os.Remove(tmpFile.Name())
 		}
 	}
 
-	return cfg, cleanup, nil
+	// Get username if present
+	if u, ok := secret.Data["username"]; ok {
+		username = string(u)
+	}
+
+	return keyPath, username, remove, nil
 }
 
-// handleRunning checks on an already-running operation (shouldn't normally happen with sync bootstrap)
+// handleRunning resumes a running operation at its persisted step. Each reconcile runs
+// a single step, so a restarted controller (or a new leader) picks up where the last one stopped.
 func (r *OperationReconciler) handleRunning(ctx context.Context, operation *api.Operation, server *api.Server, profile *api.ProvisioningProfile) (ctrl.Result, error) {
-	// With synchronous SSH bootstrap, we shouldn't get here often
-	// If we do, it means the controller restarted mid-operation
-	// Just mark as failed and let user retry
-	return r.updateOperationStatus(ctx, operation, api.OperationPhaseFailed, "Operation was interrupted (controller restart?). Please create a new operation to retry.")
+	return r.reconcileWorkflow(ctx, operation, server, profile)
 }
 
 // bootstrapServer runs the bootstrap script on the server via SSH
@@ -293,11 +344,6 @@ func (r *OperationReconciler) bootstrapServer(ctx context.Context, server *api.S
 	// Get router IP for SSH proxy (empty for router itself)
 	routerIP := server.Spec.RouterIP
 
-	// Delete existing K8s Node object if it exists (for repave)
-	if err := r.deleteNodeIfExists(ctx, server.Name); err != nil {
-		log.FromContext(ctx).Error(err, "Failed to delete existing node (continuing anyway)", "node", server.Name)
-	}
-
 	var script string
 
 	// AKS mode uses ServiceAccount token instead of kubeadm
@@ -307,8 +353,12 @@ func (r *OperationReconciler) bootstrapServer(ctx context.Context, server *api.S
 		if err != nil {
 			return fmt.Errorf("get SA token for AKS bootstrap: %w", err)
 		}
-		log.FromContext(ctx).Info("Building AKS bootstrap script", "nodeIP", target, "vmName", server.Name)
-		script = r.buildAKSBootstrapScript(cfg.kubernetesVersion, target, server.Name, saToken)
+		podCIDR, err := serverPodCIDR(server)
+		if err != nil {
+			return err
+		}
+		log.FromContext(ctx).Info("Building AKS bootstrap script", "nodeIP", target, "vmName", server.Name, "podCIDR", podCIDR)
+		script = r.buildAKSBootstrapScript(cfg.kubernetesVersion, target, server.Name, cfg.vmResourceGroup, saToken, podCIDR, stargateNodeLabels(server))
 		// Debug: write script to file for inspection
 		os.WriteFile("/tmp/aks-bootstrap-debug.sh", []byte(script), 0755)
 	} else {
@@ -329,7 +379,7 @@ func (r *OperationReconciler) bootstrapServer(ctx context.Context, server *api.S
 		}
 
 		// Build the bootstrap script with node's actual IP
-		script = r.buildBootstrapScript(controlPlaneIP, joinCmd, cfg.kubernetesVersion, target)
+		script = r.buildBootstrapScript(controlPlaneIP, joinCmd, cfg.kubernetesVersion, target, stargateNodeLabels(server))
 	}
 
 	// Run the script via SSH (via router proxy if routerIP is set)
@@ -439,7 +489,7 @@ func (r *OperationReconciler) generateKubeadmJoinCommand(controlPlaneTailscaleIP
 
 // buildBootstrapScript creates the bash script that installs k8s and joins the cluster
 // Workers behind a router don't have Tailscale - they use their local IP for node registration
-func (r *OperationReconciler) buildBootstrapScript(controlPlaneTailscaleIP, joinCmd, kubernetesVersion, nodeIP string) string {
+func (r *OperationReconciler) buildBootstrapScript(controlPlaneTailscaleIP, joinCmd, kubernetesVersion, nodeIP, nodeLabels string) string {
 	controlPlaneHostname := r.ControlPlaneHostname
 	if controlPlaneHostname == "" {
 		// Try to get hostname from Kind container
@@ -471,6 +521,7 @@ set -ex
 
 KUBERNETES_VERSION="%s"
 NODE_IP="%s"
+NODE_LABELS="%s"
 
 # Add control plane hostname to /etc/hosts for kubeadm to resolve
 echo '%s %s' >> /etc/hosts
@@ -496,6 +547,7 @@ nodeRegistration:
   kubeletExtraArgs:
     cgroup-root: /
     node-ip: "$NODE_IP"
+    node-labels: "$NODE_LABELS"
 EOF
 
 echo "Configuring kernel params..."
@@ -559,6 +611,7 @@ echo "Bootstrap complete!"
 `,
 		k8sRepoVersion,
 		nodeIP,
+		nodeLabels,
 		controlPlaneTailscaleIP,
 		controlPlaneHostname,
 		joinCmd,
@@ -568,8 +621,9 @@ echo "Bootstrap complete!"
 
 // buildAKSBootstrapScript creates a bash script for AKS node join
 // This uses a ServiceAccount token (not bootstrap tokens) because AKS doesn't support TLS bootstrapping
-// It also sets provider-id so the Azure cloud-controller-manager recognizes the node
-func (r *OperationReconciler) buildAKSBootstrapScript(kubernetesVersion, nodeIP, vmName, saToken string) string {
+// It also sets provider-id so the Azure cloud-controller-manager recognizes the node, and
+// assigns the node the pod CIDR allocated to its Server and the labels route sync classifies it by
+func (r *OperationReconciler) buildAKSBootstrapScript(kubernetesVersion, nodeIP, vmName, vmResourceGroup, saToken, podCIDR, nodeLabels string) string {
 	// Default values
 	clusterDNS := r.AKSClusterDNS
 	if clusterDNS == "" {
@@ -587,7 +641,6 @@ func (r *OperationReconciler) buildAKSBootstrapScript(kubernetesVersion, nodeIP,
 	}
 
 	subscriptionID := r.AKSSubscriptionID
-	vmResourceGroup := r.AKSVMResourceGroup
 	if vmResourceGroup == "" {
 		vmResourceGroup = resourceGroup
 	}
@@ -619,6 +672,8 @@ CLUSTER_DNS="%s"
 CLUSTER_NAME="%s"
 RESOURCE_GROUP="%s"
 PROVIDER_ID="%s"
+POD_CIDR="%s"
+NODE_LABELS="%s"
 
 echo "=== AKS Node Join for $NODE_NAME ==="
 echo "DEBUG: NODE_IP is '$NODE_IP'"
@@ -888,7 +943,7 @@ KUBELET_SVC
 # Kubelet environment with provider-id and node labels
 # NOTE: kubernetes.azure.com/ebpf-dataplane=cilium is required for Cilium DaemonSet to schedule on this node
 cat > /etc/default/kubelet <<KUBELET_ENV
-KUBELET_EXTRA_ARGS=--provider-id=${PROVIDER_ID} --node-ip=${NODE_IP} --node-labels=kubernetes.azure.com/cluster=MC_${RESOURCE_GROUP}_${CLUSTER_NAME},kubernetes.azure.com/agentpool=stargate,kubernetes.azure.com/mode=user,kubernetes.azure.com/role=agent,kubernetes.azure.com/managed=false,kubernetes.azure.com/stargate=true,kubernetes.azure.com/ebpf-dataplane=cilium
+KUBELET_EXTRA_ARGS=--provider-id=${PROVIDER_ID} --node-ip=${NODE_IP} --node-labels=kubernetes.azure.com/cluster=MC_${RESOURCE_GROUP}_${CLUSTER_NAME},kubernetes.azure.com/agentpool=stargate,kubernetes.azure.com/mode=user,kubernetes.azure.com/role=agent,kubernetes.azure.com/managed=false,kubernetes.azure.com/stargate=true,kubernetes.azure.com/ebpf-dataplane=cilium,${NODE_LABELS}
 KUBELET_ENV
 
 # Verify kubelet.service was written correctly
@@ -909,24 +964,14 @@ systemctl restart kubelet
 
 # Wait for node to register and patch it with a unique PodCIDR
 # Since AKS doesn't auto-allocate PodCIDRs to external nodes, we must set it ourselves
-# Use the last two octets of the node IP to generate a unique /24 subnet in 10.244.0.0/16
+# The PodCIDR is allocated by the controller and must be unique across the cluster
 echo "Waiting for node to register..."
 echo "DEBUG at wait: NODE_IP='$NODE_IP'"
 NODE_REGISTERED=false
 for i in {1..60}; do
   if kubectl --kubeconfig=/var/lib/kubelet/kubeconfig get node "$NODE_NAME" &>/dev/null; then
-    echo "Node registered, allocating PodCIDR..."
+    echo "Node registered, assigning PodCIDR..."
     NODE_REGISTERED=true
-    # Generate unique PodCIDR based on node IP (e.g., 10.70.1.5 -> 10.244.70.0/24)
-    # Use third octet of node IP to avoid collision with AKS nodes (which use 10.244.0-3.x)
-    THIRD_OCTET=$(echo "$NODE_IP" | cut -d. -f3)
-    FOURTH_OCTET=$(echo "$NODE_IP" | cut -d. -f4)
-    echo "DEBUG: THIRD=$THIRD_OCTET FOURTH=$FOURTH_OCTET"
-    # Combine to create unique subnet: 10.244.<third*10 + fourth mod 256>.0/24
-    # NOTE: use double percent to escape for Go fmt.Sprintf
-    UNIQUE_OCTET=$(( (THIRD_OCTET * 10 + FOURTH_OCTET) %% 200 + 50 ))
-    POD_CIDR="10.244.${UNIQUE_OCTET}.0/24"
-    echo "DEBUG: UNIQUE_OCTET=$UNIQUE_OCTET POD_CIDR=$POD_CIDR"
     
     echo "Patching node $NODE_NAME with PodCIDR: $POD_CIDR"
     kubectl --kubeconfig=/var/lib/kubelet/kubeconfig patch node "$NODE_NAME" --type='json' \
@@ -1030,11 +1075,27 @@ echo "kubelet is installed and running successfully"
 		clusterName,
 		resourceGroup,
 		providerID,
+		podCIDR,
+		nodeLabels,
 	)
 }
 
 // runRemoteBootstrap executes the bootstrap script on the remote server via SSH
 func (r *OperationReconciler) runRemoteBootstrap(ctx context.Context, host, routerIP, script string, cfg *bootstrapConfig) error {
+	output, err := r.runRemoteScript(ctx, host, routerIP, script, cfg)
+	if err != nil {
+		return fmt.Errorf("ssh bootstrap failed: %w\nOutput: %s", err, output)
+	}
+
+	// Log script output for debugging
+	os.WriteFile("/tmp/bootstrap-output.log", []byte(output), 0644)
+	log.FromContext(ctx).Info("Bootstrap script output written to /tmp/bootstrap-output.log", "bytes", len(output))
+
+	return nil
+}
+
+// runRemoteScript runs a script as root on the remote server via SSH and returns its combined output
+func (r *OperationReconciler) runRemoteScript(ctx context.Context, host, routerIP, script string, cfg *bootstrapConfig) (string, error) {
 	sshArgs := []string{
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
@@ -1062,15 +1123,15 @@ func (r *OperationReconciler) runRemoteBootstrap(ctx context.Context, host, rout
 	cmd.Stdout = &buf
 	cmd.Stderr = &buf
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("ssh bootstrap failed: %w\nOutput: %s", err, buf.String())
-	}
-
-	// Log script output for debugging
-	os.WriteFile("/tmp/bootstrap-output.log", buf.Bytes(), 0644)
-	log.FromContext(ctx).Info("Bootstrap script output written to /tmp/bootstrap-output.log", "bytes", len(buf.String()))
+	err := cmd.Run()
+	return buf.String(), err
+}
 
-	return nil
+// sshRunner returns a remoteRunner that reaches the server over SSH with cfg
+func (r *OperationReconciler) sshRunner(server *api.Server, cfg *bootstrapConfig) remoteRunner {
+	return func(ctx context.Context, script string) (string, error) {
+		return r.runRemoteScript(ctx, server.Spec.IPv4, server.Spec.RouterIP, script, cfg)
+	}
 }
 
 // updateOperationStatus updates the operation status and returns appropriate result
@@ -1080,47 +1141,64 @@ func (r *OperationReconciler) updateOperationStatus(ctx context.Context, operati
 	operation.Status.Phase = phase
 	operation.Status.Message = message
 
-	if phase == api.OperationPhaseSucceeded || phase == api.OperationPhaseFailed {
+	if phase == api.OperationPhaseSucceeded || phase == api.OperationPhaseFailed || phase == api.OperationPhaseCancelled {
 		now := metav1.Now()
 		operation.Status.CompletionTime = &now
 	}
+	setOperationPhaseCondition(operation)
 
 	if err := r.Status().Update(ctx, operation); err != nil {
 		logger.Error(err, "Failed to update Operation status")
 		return ctrl.Result{RequeueAfter: 5 * time.Second}, err
 	}
+	recordOperationResult(r.Recorder, operation)
+	if isOperationFinished(operation) {
+		observeOperationDuration(operation, "azure")
+	}
 
 	return ctrl.Result{}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager
 func (r *OperationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Index pods by node so drains can list the pods on a node from the cache
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.Pod{}, podNodeNameField, indexPodNodeName); err != nil {
+		return fmt.Errorf("index pods by node: %w", err)
+	}
+
+	// Index operations by server so queued Operations can be found when a Server's lock is released
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &api.Operation{}, operationServerRefField, indexOperationServerRef); err != nil {
+		return fmt.Errorf("index operations by server: %w", err)
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&api.Operation{}).
+		Watches(&api.Server{}, handler.EnqueueRequestsFromMapFunc(queuedOperationsForServer(r.Client))).
 		Complete(r)
 }
 
-// deleteNodeIfExists removes a Kubernetes Node object if it exists (for repave operations)
-func (r *OperationReconciler) deleteNodeIfExists(ctx context.Context, nodeName string) error {
+// deleteNodeIfExists removes a Kubernetes Node object if it exists (for repave operations).
+// It returns done=false while the Node is still Terminating so the step is polled until it is gone.
+func (r *OperationReconciler) deleteNodeIfExists(ctx context.Context, operation *api.Operation, nodeName string) (done bool, err error) {
 	logger := log.FromContext(ctx)
 
-	node := &corev1.Node{}
-	if err := r.Get(ctx, client.ObjectKey{Name: nodeName}, node); err != nil {
-		if client.IgnoreNotFound(err) != nil {
-			return err
-		}
+	node, err := getNode(ctx, r.Client, nodeName)
+	if err != nil || node == nil {
 		// Node doesn't exist, nothing to delete
-		return nil
+		return err == nil, err
 	}
 
-	logger.Info("Deleting existing node for repave", "node", nodeName)
-	if err := r.Delete(ctx, node); err != nil {
-		return fmt.Errorf("delete node %s: %w", nodeName, err)
+	if node.DeletionTimestamp.IsZero() {
+		logger.Info("Deleting existing node for repave", "node", nodeName)
+		if err := r.Delete(ctx, node); err != nil {
+			return false, fmt.Errorf("delete node %s: %w", nodeName, err)
+		}
+		recordEvent(r.Recorder, node, corev1.EventTypeNormal, api.EventReasonNodeDeleted, "Deleted stale Node before repave")
 	}
 
-	// Wait briefly for node deletion to propagate
-	time.Sleep(2 * time.Second)
-	return nil
+	// Finalizers such as route cleanup keep the Node Terminating for a while
+	pending, err := nodeDeletionPending(operation, node)
+	return !pending && err == nil, err
 }
 
 // getOrCreateSAToken creates a new token for the kubelet-bootstrap ServiceAccount
@@ -1185,21 +1263,16 @@ func (r *OperationReconciler) InitializeAKSCredentials(cfg interface{}) error {
 func (r *OperationReconciler) configureNodeRouting(ctx context.Context, server *api.Server, cfg *bootstrapConfig) error {
 	logger := log.FromContext(ctx)
 
-	// Calculate the pod CIDR for this node (same logic as in bootstrap script)
 	nodeIP := server.Spec.IPv4
-	parts := strings.Split(nodeIP, ".")
-	if len(parts) != 4 {
-		return fmt.Errorf("invalid node IP format: %s", nodeIP)
+	podCIDR, err := serverPodCIDR(server)
+	if err != nil {
+		return err
 	}
-	thirdOctet, _ := strconv.Atoi(parts[2])
-	fourthOctet, _ := strconv.Atoi(parts[3])
-	uniqueOctet := (thirdOctet*10+fourthOctet)%200 + 50
-	podCIDR := fmt.Sprintf("10.244.%d.0/24", uniqueOctet)
 
 	logger.Info("Configuring routing for node", "server", server.Name, "nodeIP", nodeIP, "podCIDR", podCIDR)
 
 	// Configure DC router route
-	if r.DCRouterTailscaleIP != "" {
+	if cfg.dcRouterIP != "" {
 		if err := r.configureDCRouterRoute(ctx, nodeIP, podCIDR, cfg); err != nil {
 			logger.Error(err, "Failed to configure DC router route")
 			// Continue with other configurations
@@ -1233,27 +1306,41 @@ func (r *OperationReconciler) configureDCRouterRoute(ctx context.Context, nodeIP
 		fi
 	`, podCIDR, nodeIP, podCIDR, nodeIP, podCIDR, nodeIP, podCIDR, nodeIP, podCIDR, nodeIP)
 
+	output, err := r.runDCRouterScript(ctx, routeCmd, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to configure DC router route: %w (output: %s)", err, output)
+	}
+
+	logger.Info("Configured DC router route", "podCIDR", podCIDR, "via", nodeIP, "output", output)
+	return nil
+}
+
+// dcRouterRunner returns a remoteRunner that reaches the DC router with cfg
+func (r *OperationReconciler) dcRouterRunner(cfg *bootstrapConfig) remoteRunner {
+	return func(ctx context.Context, script string) (string, error) {
+		return r.runDCRouterScript(ctx, script, cfg)
+	}
+}
+
+// runDCRouterScript runs a script as root on the router of the server's datacenter over its
+// Tailscale IP
+func (r *OperationReconciler) runDCRouterScript(ctx context.Context, script string, cfg *bootstrapConfig) (string, error) {
 	sshArgs := []string{
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
 		"-o", "ConnectTimeout=10",
-		"-i", cfg.sshPrivateKeyPath,
+		"-i", cfg.dcRouterSSHKeyPath,
 		"-p", strconv.Itoa(cfg.sshPort),
-		fmt.Sprintf("%s@%s", cfg.adminUsername, r.DCRouterTailscaleIP),
-		"sudo", "bash", "-c", routeCmd,
+		fmt.Sprintf("%s@%s", cfg.dcRouterAdminUsername, cfg.dcRouterIP),
+		"sudo", "bash", "-c", script,
 	}
 
 	cmd := exec.CommandContext(ctx, "ssh", sshArgs...)
 	var buf bytes.Buffer
 	cmd.Stdout = &buf
 	cmd.Stderr = &buf
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to configure DC router route: %w (output: %s)", err, buf.String())
-	}
-
-	logger.Info("Configured DC router route", "podCIDR", podCIDR, "via", nodeIP, "output", buf.String())
-	return nil
+	err := cmd.Run()
+	return buf.String(), err
 }
 
 // configureAzureRouteTable adds a route in the Azure route table for the node's pod CIDR
@@ -0,0 +1,233 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os/exec"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+	"github.com/vpatelsj/stargate/pkg/tailscale"
+)
+
+// decommissionSteps returns the decommission workflow: cordon and drain the Node and delete it,
+// remove the pod CIDR routes the repave added, return its pod CIDR to its PodCIDRPool, then
+// optionally wipe it. The Node's route sync routes and Tailscale advertisements are removed by
+// route sync's finalizer when the Node is deleted; without it the DC router's advertisement is
+// cleaned up here.
+// Every step is re-runnable, so a failed decommission can simply be retried.
+func (r *OperationReconciler) decommissionSteps(operation *api.Operation, server *api.Server, cfg *bootstrapConfig) []operationStep {
+	opts := operation.Spec.Decommission
+	if opts == nil {
+		opts = &api.DecommissionOptions{}
+	}
+
+	steps := []operationStep{
+		cordonStep(r.Client, r.Recorder, operation, server.Name),
+		drainStep(r.Client, r.Recorder, operation, server.Name),
+		{
+			name: api.OperationStepNodeCleanup,
+			run: func(ctx context.Context) (bool, error) {
+				// Remember whether route sync cleans up after the Node, since it is gone by TailscaleCleanup
+				node, err := getNode(ctx, r.Client, server.Name)
+				if err != nil {
+					return false, err
+				}
+				if node != nil && !operation.Status.RouteSyncManaged && controllerutil.ContainsFinalizer(node, routeCleanupFinalizer) {
+					operation.Status.RouteSyncManaged = true
+					if err := r.Status().Update(ctx, operation); err != nil {
+						return false, err
+					}
+				}
+
+				done, err := r.deleteNodeIfExists(ctx, operation, server.Name)
+				if err != nil {
+					return false, fmt.Errorf("delete node %s: %w", server.Name, err)
+				}
+				return done, nil
+			},
+		},
+		{
+			name: api.OperationStepRouteCleanup,
+			run: func(ctx context.Context) (bool, error) {
+				// Wait for route sync to finish its cleanup and release the Node
+				node, err := getNode(ctx, r.Client, server.Name)
+				if err != nil {
+					return false, err
+				}
+				if node != nil {
+					if stepElapsed(operation) > nodeDeletionTimeout {
+						return false, timeoutError("node %s still terminating after %s", server.Name, nodeDeletionTimeout)
+					}
+					return false, nil
+				}
+				err = r.removeNodeRouting(ctx, server, cfg, r.dcRouterRunner(cfg))
+				return err == nil, err
+			},
+		},
+		{
+			name: api.OperationStepTailscaleCleanup,
+			run: func(ctx context.Context) (bool, error) {
+				err := r.cleanupTailscale(ctx, server, cfg, !operation.Status.RouteSyncManaged, opts.DeleteTailscaleDevice)
+				return err == nil, err
+			},
+		},
+		{
+			name: api.OperationStepReleasePodCIDR,
+			run: func(ctx context.Context) (bool, error) {
+				err := releasePodCIDR(ctx, r.Client, server)
+				return err == nil, err
+			},
+		},
+	}
+	if opts.Wipe {
+		steps = append(steps, r.wipeStep(server, cfg))
+	}
+	return steps
+}
+
+// removeNodeRouting removes the routes configureNodeRouting added for the server's pod CIDR:
+// the kernel route via the server on the DC router and the Azure route table entry. A kernel
+// route for the same CIDR via another Node, which has reused it, is left in place.
+// A server without a pod CIDR was never given routes. router runs scripts on the DC router.
+func (r *OperationReconciler) removeNodeRouting(ctx context.Context, server *api.Server, cfg *bootstrapConfig, router remoteRunner) error {
+	logger := log.FromContext(ctx)
+
+	podCIDR := server.Status.PodCIDR
+	if podCIDR == "" {
+		logger.Info("Server has no pod CIDR, no routes to remove", "server", server.Name)
+		return nil
+	}
+
+	if cfg.dcRouterIP != "" {
+		prefix, err := netip.ParsePrefix(podCIDR)
+		if err != nil {
+			return fmt.Errorf("invalid pod CIDR %q: %w", podCIDR, err)
+		}
+		nodeIP, err := netip.ParseAddr(server.Spec.IPv4)
+		if err != nil {
+			return fmt.Errorf("invalid server IP %q: %w", server.Spec.IPv4, err)
+		}
+		script := fmt.Sprintf("ip route del %s via %s 2>/dev/null || true", prefix, nodeIP)
+		if output, err := router(ctx, script); err != nil {
+			return fmt.Errorf("remove DC router route: %w (output: %s)", err, output)
+		}
+		logger.Info("Removed DC router route", "podCIDR", podCIDR)
+	}
+
+	if r.AzureRouteTableName != "" && r.AKSVMResourceGroup != "" {
+		routeName := fmt.Sprintf("pod-cidr-%s", strings.ReplaceAll(server.Name, "-", ""))
+		cmd := exec.CommandContext(ctx, "az", "network", "route-table", "route", "delete",
+			"--resource-group", r.AKSVMResourceGroup,
+			"--route-table-name", r.AzureRouteTableName,
+			"--name", routeName,
+		)
+		var buf bytes.Buffer
+		cmd.Stdout = &buf
+		cmd.Stderr = &buf
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("delete Azure route %s: %w (output: %s)", routeName, err, buf.String())
+		}
+		logger.Info("Deleted Azure route", "routeName", routeName, "podCIDR", podCIDR)
+	}
+
+	return nil
+}
+
+// cleanupTailscale stops the DC router advertising the server's pod CIDR if removeAdvertisement
+// is set and, if deleteDevice is set, removes the server's device from the tailnet. The
+// advertisement is left to route sync when its finalizer was on the Node.
+func (r *OperationReconciler) cleanupTailscale(ctx context.Context, server *api.Server, cfg *bootstrapConfig, removeAdvertisement, deleteDevice bool) error {
+	logger := log.FromContext(ctx)
+
+	if podCIDR := server.Status.PodCIDR; removeAdvertisement && podCIDR != "" && cfg.dcRouterIP != "" {
+		if err := r.removeDCRouterAdvertisement(ctx, podCIDR, cfg); err != nil {
+			return classify(api.FailureClassTailscale, err)
+		}
+		logger.Info("Removed pod CIDR from DC router Tailscale advertisements", "podCIDR", podCIDR)
+	}
+
+	if !deleteDevice {
+		return nil
+	}
+	if r.Tailscale == nil {
+		return fmt.Errorf("deleting the Tailscale device of server %s requires Tailscale API credentials", server.Name)
+	}
+	device, err := r.Tailscale.FindDeviceByHostname(ctx, server.Name)
+	if errors.Is(err, tailscale.ErrDeviceNotFound) {
+		return nil
+	}
+	if err != nil {
+		return classify(api.FailureClassTailscale, fmt.Errorf("find Tailscale device: %w", err))
+	}
+	if err := r.Tailscale.DeleteDevice(ctx, device.ID); err != nil {
+		return classify(api.FailureClassTailscale, fmt.Errorf("delete Tailscale device %s: %w", device.Name, err))
+	}
+	logger.Info("Deleted Tailscale device", "device", device.Name, "id", device.ID)
+	return nil
+}
+
+// removeDCRouterAdvertisement re-advertises the DC router's routes without podCIDR. The
+// router's own prefs are the source of truth for what it advertises; if a Tailscale API client
+// is configured the route is also disabled in the tailnet.
+func (r *OperationReconciler) removeDCRouterAdvertisement(ctx context.Context, podCIDR string, cfg *bootstrapConfig) error {
+	output, err := r.runDCRouterScript(ctx, "tailscale debug prefs 2>/dev/null", cfg)
+	if err != nil {
+		return fmt.Errorf("read DC router prefs: %w (output: %s)", err, output)
+	}
+	var prefs struct {
+		AdvertiseRoutes []string
+	}
+	if err := json.Unmarshal([]byte(output), &prefs); err != nil {
+		return fmt.Errorf("parse DC router prefs: %w", err)
+	}
+
+	if routes, removed := withoutRoute(prefs.AdvertiseRoutes, podCIDR); removed {
+		script := fmt.Sprintf("tailscale set --advertise-routes=%s", strings.Join(routes, ","))
+		if output, err := r.runDCRouterScript(ctx, script, cfg); err != nil {
+			return fmt.Errorf("advertise routes: %w (output: %s)", err, output)
+		}
+	}
+
+	if r.Tailscale == nil {
+		return nil
+	}
+	devices, err := r.Tailscale.ListDevices(ctx)
+	if err != nil {
+		return fmt.Errorf("list devices: %w", err)
+	}
+	for _, device := range devices {
+		if device.TailscaleIP != cfg.dcRouterIP {
+			continue
+		}
+		current, err := r.Tailscale.GetDeviceRoutes(ctx, device.ID)
+		if err != nil {
+			return fmt.Errorf("get device routes: %w", err)
+		}
+		if enabled, removed := withoutRoute(current.EnabledRoutes, podCIDR); removed {
+			if err := r.Tailscale.SetDeviceRoutes(ctx, device.ID, enabled); err != nil {
+				return fmt.Errorf("set device routes: %w", err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("DC router %s not found in Tailscale devices", cfg.dcRouterIP)
+}
+
+// withoutRoute returns routes without cidr, and whether it was present
+func withoutRoute(routes []string, cidr string) ([]string, bool) {
+	kept := make([]string, 0, len(routes))
+	for _, route := range routes {
+		if route != cidr {
+			kept = append(kept, route)
+		}
+	}
+	return kept, len(kept) != len(routes)
+}
@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+func TestWithoutRoute(t *testing.T) {
+	tests := []struct {
+		name        string
+		routes      []string
+		cidr        string
+		want        []string
+		wantRemoved bool
+	}{
+		{"present", []string{"10.50.0.0/16", "10.244.90.0/24", "10.244.70.0/24"}, "10.244.90.0/24", []string{"10.50.0.0/16", "10.244.70.0/24"}, true},
+		{"absent", []string{"10.50.0.0/16"}, "10.244.90.0/24", []string{"10.50.0.0/16"}, false},
+		{"only route", []string{"10.244.90.0/24"}, "10.244.90.0/24", []string{}, true},
+		{"none", nil, "10.244.90.0/24", []string{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, removed := withoutRoute(tt.routes, tt.cidr)
+			if removed != tt.wantRemoved {
+				t.Errorf("removed = %v, want %v", removed, tt.wantRemoved)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("routes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecommissionCleanupRerun(t *testing.T) {
+	ctx := context.Background()
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "w1", Finalizers: []string{routeCleanupFinalizer}}}
+	operation := &api.Operation{ObjectMeta: metav1.ObjectMeta{Name: "op1", Namespace: "dc"}}
+	c := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(node, operation).WithStatusSubresource(operation).Build()
+	if err := c.Get(ctx, client.ObjectKeyFromObject(operation), operation); err != nil {
+		t.Fatal(err)
+	}
+	server := &api.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "w1", Namespace: "dc"},
+		Spec:       api.ServerSpec{IPv4: "10.0.0.5"},
+		Status:     api.ServerStatus{PodCIDR: "10.244.5.0/24"},
+	}
+	r := &OperationReconciler{Client: c}
+	steps := r.decommissionSteps(operation, server, &bootstrapConfig{})
+	nodeCleanup := findStep(t, steps, api.OperationStepNodeCleanup)
+	routeCleanup := findStep(t, steps, api.OperationStepRouteCleanup)
+
+	run := func(step operationStep, wantDone bool) {
+		t.Helper()
+		done, err := step.run(ctx)
+		if err != nil || done != wantDone {
+			t.Fatalf("%s = %v, %v, want %v", step.name, done, err, wantDone)
+		}
+	}
+
+	// Route sync's finalizer keeps the deleted Node Terminating
+	run(nodeCleanup, false)
+	if !operation.Status.RouteSyncManaged {
+		t.Fatal("RouteSyncManaged not recorded")
+	}
+	run(routeCleanup, false)
+
+	// A rerun doesn't record RouteSyncManaged again
+	version := operation.ResourceVersion
+	run(nodeCleanup, false)
+	if operation.ResourceVersion != version {
+		t.Errorf("operation updated again on rerun")
+	}
+
+	// Route sync releases the Node
+	if err := c.Get(ctx, client.ObjectKeyFromObject(node), node); err != nil {
+		t.Fatal(err)
+	}
+	node.Finalizers = nil
+	if err := c.Update(ctx, node); err != nil {
+		t.Fatal(err)
+	}
+	run(nodeCleanup, true)
+	run(nodeCleanup, true)
+	run(routeCleanup, true)
+	run(routeCleanup, true)
+	if !operation.Status.RouteSyncManaged {
+		t.Error("RouteSyncManaged lost on rerun")
+	}
+}
+
+// fakeRouter is a DC router kernel route table mapping pod CIDRs to the Node they route via.
+// It understands the "ip route del <cidr> via <ip>" scripts removeNodeRouting runs.
+type fakeRouter map[string]string
+
+func (f fakeRouter) run(ctx context.Context, script string) (string, error) {
+	fields := strings.Fields(script)
+	if len(fields) < 6 || strings.Join(fields[:3], " ") != "ip route del" || fields[4] != "via" {
+		return "", fmt.Errorf("unexpected script %q", script)
+	}
+	if f[fields[3]] == fields[5] {
+		delete(f, fields[3])
+	}
+	return "", nil
+}
+
+func TestRemoveNodeRouting(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes fakeRouter
+		want   fakeRouter
+	}{
+		{"own route", fakeRouter{"10.244.5.0/24": "10.0.0.5", "10.244.6.0/24": "10.0.0.6"}, fakeRouter{"10.244.6.0/24": "10.0.0.6"}},
+		{"reused by another node", fakeRouter{"10.244.5.0/24": "10.0.0.9"}, fakeRouter{"10.244.5.0/24": "10.0.0.9"}},
+		{"already removed", fakeRouter{}, fakeRouter{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &api.Server{
+				ObjectMeta: metav1.ObjectMeta{Name: "w1", Namespace: "dc"},
+				Spec:       api.ServerSpec{IPv4: "10.0.0.5"},
+				Status:     api.ServerStatus{PodCIDR: "10.244.5.0/24"},
+			}
+			r := &OperationReconciler{}
+			cfg := &bootstrapConfig{dcRouterIP: "100.64.0.1"}
+
+			// Rerunning is harmless
+			for i := 0; i < 2; i++ {
+				if err := r.removeNodeRouting(context.Background(), server, cfg, tt.routes.run); err != nil {
+					t.Fatalf("removeNodeRouting: %v", err)
+				}
+			}
+			if !reflect.DeepEqual(tt.routes, tt.want) {
+				t.Errorf("routes = %v, want %v", tt.routes, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// defaultDrainTimeout bounds how long a drain waits for pods to be evicted
+const defaultDrainTimeout = 10 * time.Minute
+
+// drainOptions returns the operation's drain options, or the defaults if unset
+func drainOptions(operation *api.Operation) api.DrainOptions {
+	if operation.Spec.Drain == nil {
+		return api.DrainOptions{}
+	}
+	return *operation.Spec.Drain
+}
+
+// drainTimeout returns how long the operation's drain may take
+func drainTimeout(operation *api.Operation) time.Duration {
+	if opts := drainOptions(operation); opts.TimeoutSeconds > 0 {
+		return time.Duration(opts.TimeoutSeconds) * time.Second
+	}
+	return defaultDrainTimeout
+}
+
+// cordonStep marks the server's Node unschedulable. It does nothing if the server has no
+// Node or draining is disabled.
+func cordonStep(c client.Client, recorder record.EventRecorder, operation *api.Operation, nodeName string) operationStep {
+	return operationStep{
+		name: api.OperationStepCordon,
+		run: func(ctx context.Context) (bool, error) {
+			if drainOptions(operation).Disabled {
+				return true, nil
+			}
+			node, err := getNode(ctx, c, nodeName)
+			if err != nil || node == nil {
+				return err == nil, err
+			}
+			if err := setNodeUnschedulable(ctx, c, node, true); err != nil {
+				return false, err
+			}
+			recordEvent(recorder, node, corev1.EventTypeNormal, api.EventReasonNodeCordoned, "Cordoned by operation %s", operation.Name)
+			return true, nil
+		},
+	}
+}
+
+// uncordonStep marks the server's Node schedulable again. It does nothing if the server has
+// no Node.
+func uncordonStep(c client.Client, recorder record.EventRecorder, operation *api.Operation, nodeName string) operationStep {
+	return operationStep{
+		name: api.OperationStepUncordon,
+		run: func(ctx context.Context) (bool, error) {
+			node, err := getNode(ctx, c, nodeName)
+			if err != nil || node == nil {
+				return err == nil, err
+			}
+			if err := setNodeUnschedulable(ctx, c, node, false); err != nil {
+				return false, err
+			}
+			recordEvent(recorder, node, corev1.EventTypeNormal, api.EventReasonNodeUncordoned, "Uncordoned by operation %s", operation.Name)
+			return true, nil
+		},
+	}
+}
+
+// drainStep evicts the pods on the server's Node, honoring PodDisruptionBudgets, until none
+// are left or the drain timeout expires
+func drainStep(c client.Client, recorder record.EventRecorder, operation *api.Operation, nodeName string) operationStep {
+	return operationStep{
+		name: api.OperationStepDrain,
+		run: func(ctx context.Context) (bool, error) {
+			opts := drainOptions(operation)
+			if opts.Disabled {
+				return true, nil
+			}
+
+			remaining, err := drainNode(ctx, c, nodeName, opts.GracePeriodSeconds)
+			if err != nil {
+				return false, err
+			}
+			if remaining == 0 {
+				if node, err := getNode(ctx, c, nodeName); err == nil && node != nil {
+					recordEvent(recorder, node, corev1.EventTypeNormal, api.EventReasonNodeDrained, "Drained by operation %s", operation.Name)
+				}
+				return true, nil
+			}
+			if timeout := drainTimeout(operation); stepElapsed(operation) > timeout {
+				if opts.Force {
+					log.FromContext(ctx).Info("Drain timed out, continuing because force is set", "node", nodeName, "remaining", remaining)
+					return true, nil
+				}
+				return false, timeoutError("drain timed out after %s with %d pods remaining", timeout, remaining)
+			}
+			operation.Status.Message = fmt.Sprintf("Draining node: %d pods remaining", remaining)
+			return false, c.Status().Update(ctx, operation)
+		},
+	}
+}
@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// OperationGCReconciler deletes finished Operations once their TTL expires, first recording a
+// summary in their Server's status.operationHistory
+type OperationGCReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Provider is the Server provider this controller cleans up after ("azure" or "qemu")
+	Provider string
+
+	// DefaultTTL applies to Operations without spec.ttlSecondsAfterFinished. Zero keeps them forever.
+	DefaultTTL time.Duration
+}
+
+// operationTTL returns how long the finished operation is kept, or false if it is kept forever
+func operationTTL(operation *api.Operation, defaultTTL time.Duration) (time.Duration, bool) {
+	if operation.Spec.TTLSecondsAfterFinished != nil {
+		return time.Duration(*operation.Spec.TTLSecondsAfterFinished) * time.Second, true
+	}
+	return defaultTTL, defaultTTL > 0
+}
+
+// appendOperationHistory records a summary of the finished operation on its Server, dropping
+// the oldest entries beyond api.MaxOperationHistory. It returns false if the operation was
+// already recorded.
+func appendOperationHistory(server *api.Server, operation *api.Operation) bool {
+	for _, entry := range server.Status.OperationHistory {
+		if entry.Name == operation.Name && entry.CompletionTime.Equal(operation.Status.CompletionTime) {
+			return false
+		}
+	}
+
+	entry := api.OperationHistoryEntry{
+		Name:                operation.Name,
+		Operation:           operation.Spec.Operation,
+		Result:              operation.Status.Phase,
+		ProvisioningProfile: operation.Spec.ProvisioningProfileRef.Name,
+		StartTime:           operation.Status.StartTime,
+		CompletionTime:      operation.Status.CompletionTime,
+		Message:             operation.Status.Message,
+	}
+	if entry.Operation == "" {
+		entry.Operation = api.OperationTypeRepave
+	}
+	if entry.StartTime != nil && entry.CompletionTime != nil {
+		entry.Duration = &metav1.Duration{Duration: entry.CompletionTime.Sub(entry.StartTime.Time).Round(time.Second)}
+	}
+
+	history := append(server.Status.OperationHistory, entry)
+	if len(history) > api.MaxOperationHistory {
+		history = history[len(history)-api.MaxOperationHistory:]
+	}
+	server.Status.OperationHistory = history
+	return true
+}
+
+// ensureServerOwner adds the Server as an owner of the operation, so deleting the Server
+// deletes its Operations. The Server is not the controller; an OperationSet or ServerClaim may be.
+func ensureServerOwner(ctx context.Context, c client.Client, scheme *runtime.Scheme, operation *api.Operation, server *api.Server) error {
+	for _, ref := range operation.OwnerReferences {
+		if ref.UID == server.UID {
+			return nil
+		}
+	}
+	if err := controllerutil.SetOwnerReference(server, operation, scheme); err != nil {
+		return fmt.Errorf("set server owner: %w", err)
+	}
+	if err := c.Update(ctx, operation); err != nil {
+		return fmt.Errorf("set server owner: %w", err)
+	}
+	return nil
+}
+
+// +kubebuilder:rbac:groups=stargate.io,resources=operations,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=stargate.io,resources=servers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=stargate.io,resources=servers/status,verbs=get;update;patch
+
+// Reconcile deletes a finished Operation once its TTL expires, or requeues until it does
+func (r *OperationGCReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var operation api.Operation
+	if err := r.Get(ctx, req.NamespacedName, &operation); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !isOperationFinished(&operation) || operation.DeletionTimestamp != nil || operation.Status.CompletionTime == nil {
+		return ctrl.Result{}, nil
+	}
+
+	// Operations created by an OperationSet or ServerClaim are deleted with their owner,
+	// which needs them to track its progress
+	if metav1.GetControllerOf(&operation) != nil {
+		return ctrl.Result{}, nil
+	}
+
+	ttl, ok := operationTTL(&operation, r.DefaultTTL)
+	if !ok {
+		return ctrl.Result{}, nil
+	}
+	if remaining := time.Until(operation.Status.CompletionTime.Add(ttl)); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	var server api.Server
+	serverKey := client.ObjectKey{Namespace: operation.Namespace, Name: operation.Spec.ServerRef.Name}
+	if err := r.Get(ctx, serverKey, &server); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, fmt.Errorf("get server %s: %w", serverKey.Name, err)
+		}
+	} else {
+		// Provider gating: only clean up after servers this controller manages
+		if server.Spec.Provider != "" && server.Spec.Provider != r.Provider {
+			return ctrl.Result{}, nil
+		}
+		if appendOperationHistory(&server, &operation) {
+			if err := r.Status().Update(ctx, &server); err != nil {
+				return ctrl.Result{}, fmt.Errorf("record operation history: %w", err)
+			}
+		}
+	}
+
+	logger.Info("Deleting finished operation past its TTL", "server", operation.Spec.ServerRef.Name, "phase", operation.Status.Phase, "ttl", ttl)
+	if err := r.Delete(ctx, &operation); err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, fmt.Errorf("delete operation: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *OperationGCReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("operationgc").
+		For(&api.Operation{}).
+		Complete(r)
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+func TestOperationTTL(t *testing.T) {
+	zero := int32(0)
+	hour := int32(3600)
+
+	tests := []struct {
+		name       string
+		spec       *int32
+		defaultTTL time.Duration
+		ttl        time.Duration
+		ok         bool
+	}{
+		{"controller default", nil, 24 * time.Hour, 24 * time.Hour, true},
+		{"kept forever", nil, 0, 0, false},
+		{"spec overrides default", &hour, 24 * time.Hour, time.Hour, true},
+		{"spec zero deletes immediately", &zero, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := &api.Operation{Spec: api.OperationSpec{TTLSecondsAfterFinished: tt.spec}}
+			ttl, ok := operationTTL(op, tt.defaultTTL)
+			if ttl != tt.ttl || ok != tt.ok {
+				t.Errorf("operationTTL() = %s, %v; want %s, %v", ttl, ok, tt.ttl, tt.ok)
+			}
+		})
+	}
+}
+
+func TestAppendOperationHistory(t *testing.T) {
+	start := metav1.NewTime(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	operation := func(i int) *api.Operation {
+		completion := metav1.NewTime(start.Add(time.Duration(i+1) * time.Minute))
+		return &api.Operation{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("op-%d", i)},
+			Spec: api.OperationSpec{
+				ProvisioningProfileRef: api.LocalObjectReference{Name: "k8s-worker"},
+			},
+			Status: api.OperationStatus{Phase: api.OperationPhaseSucceeded, StartTime: &start, CompletionTime: &completion},
+		}
+	}
+
+	server := &api.Server{}
+	for i := 0; i < api.MaxOperationHistory+2; i++ {
+		if !appendOperationHistory(server, operation(i)) {
+			t.Fatalf("expected op-%d to be recorded", i)
+		}
+	}
+	if appendOperationHistory(server, operation(api.MaxOperationHistory+1)) {
+		t.Error("expected a recorded operation not to be recorded again")
+	}
+
+	history := server.Status.OperationHistory
+	if len(history) != api.MaxOperationHistory {
+		t.Fatalf("expected %d entries, got %d", api.MaxOperationHistory, len(history))
+	}
+	if history[0].Name != "op-2" {
+		t.Errorf("expected the oldest entries to be dropped, first entry is %s", history[0].Name)
+	}
+	last := history[len(history)-1]
+	if last.Operation != api.OperationTypeRepave || last.ProvisioningProfile != "k8s-worker" || last.Duration.Duration != 12*time.Minute {
+		t.Errorf("unexpected summary %+v", last)
+	}
+}
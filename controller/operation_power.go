@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+	"github.com/vpatelsj/stargate/pkg/bmc"
+)
+
+// powerTimeout bounds how long a power operation waits for the BMC to report the target state
+const powerTimeout = 5 * time.Minute
+
+// powerSteps returns the power workflow: issue the power action through the BMC,
+// then wait for the system to report the expected power state. Power-on and
+// power-cycle also wait for the host to boot and its Node to become Ready.
+func (r *OperationReconciler) powerSteps(operation *api.Operation, server *api.Server, cfg *bootstrapConfig) []operationStep {
+	want := bmc.PowerStateOn
+	if operation.Spec.Operation == api.OperationTypePowerOff {
+		want = bmc.PowerStateOff
+	}
+
+	steps := []operationStep{
+		{
+			name: api.OperationStepPowerAction,
+			run: func(ctx context.Context) (bool, error) {
+				var state bmc.PowerState
+				err := withBMCSession(ctx, r.Client, server, func(bmcClient *bmc.Client) error {
+					var err error
+					state, err = bmcClient.GetPowerState(ctx)
+					return err
+				})
+				if err != nil {
+					return false, err
+				}
+				// A host that is already on won't boot again, so WaitForNode accepts the boot it is
+				// on. A boot ID recorded by an earlier run means that run found the host off.
+				if operation.Spec.Operation == api.OperationTypePowerOn && operation.Status.NodeBootID == "" &&
+					(state == bmc.PowerStateOn || state == bmc.PowerStatePoweringOn) {
+					log.FromContext(ctx).Info("Host already powered on", "server", server.Name, "powerState", state)
+					return true, nil
+				}
+
+				// Remember the boot the host is on so WaitForNode can tell it booted again.
+				// Kept across retries of this step, which may already have reset the host.
+				if operation.Status.NodeBootID == "" {
+					node, err := getNode(ctx, r.Client, server.Name)
+					if err != nil {
+						return false, err
+					}
+					if node != nil {
+						operation.Status.NodeBootID = node.Status.NodeInfo.BootID
+					} else if operation.Spec.Operation == api.OperationTypePowerCycle {
+						// Best effort: a host being recovered out-of-band usually doesn't answer
+						operation.Status.NodeBootID, _ = readBootID(ctx, server, r.sshRunner(server, cfg))
+					}
+				}
+
+				err = withBMCSession(ctx, r.Client, server, func(bmcClient *bmc.Client) error {
+					switch operation.Spec.Operation {
+					case api.OperationTypePowerOn:
+						return bmcClient.PowerOn(ctx)
+					case api.OperationTypePowerOff:
+						return bmcClient.PowerOff(ctx)
+					default:
+						return bmcClient.PowerCycle(ctx)
+					}
+				})
+				return err == nil, err
+			},
+		},
+		{
+			name: api.OperationStepWaitForPowerState,
+			run: func(ctx context.Context) (bool, error) {
+				if stepElapsed(operation) > powerTimeout {
+					return false, timeoutError("system did not reach power state %s within %s", want, powerTimeout)
+				}
+
+				var state bmc.PowerState
+				err := withBMCSession(ctx, r.Client, server, func(bmcClient *bmc.Client) error {
+					var err error
+					state, err = bmcClient.GetPowerState(ctx)
+					return err
+				})
+				if err != nil {
+					// BMCs are often briefly unresponsive right after a power action
+					log.FromContext(ctx).V(1).Info("BMC not reachable yet", "server", server.Name, "error", err.Error())
+					return false, nil
+				}
+				return state == want, nil
+			},
+		},
+	}
+	if want == bmc.PowerStateOff {
+		return steps
+	}
+
+	return append(steps, operationStep{
+		name:      api.OperationStepWaitForNode,
+		condition: api.ConditionNodeRegistered,
+		run: func(ctx context.Context) (bool, error) {
+			if stepElapsed(operation) > rebootTimeout {
+				return false, timeoutError("host did not come back within %s", rebootTimeout)
+			}
+
+			node, err := getNode(ctx, r.Client, server.Name)
+			if err != nil {
+				return false, err
+			}
+			// With no boot ID recorded, e.g. for a host that was already on, any boot will do
+			if node != nil {
+				return node.Status.NodeInfo.BootID != operation.Status.NodeBootID && isNodeReady(node), nil
+			}
+
+			// No Node registered for this server - wait for the host itself to answer on a new boot
+			bootID, err := readBootID(ctx, server, r.sshRunner(server, cfg))
+			if err != nil {
+				log.FromContext(ctx).V(1).Info("Host not reachable yet", "server", server.Name, "error", err.Error())
+				return false, nil
+			}
+			return bootID != operation.Status.NodeBootID, nil
+		},
+	})
+}
+
+// withBMCSession runs fn against the server's BMC inside a Redfish session.
+// Errors talking to the BMC are classified as BMC failures.
+func withBMCSession(ctx context.Context, c client.Client, server *api.Server, fn func(*bmc.Client) error) error {
+	bmcClient, err := bmcClientFor(ctx, c, server)
+	if err != nil {
+		return err
+	}
+	if err := bmcClient.Login(ctx); err != nil {
+		return classify(api.FailureClassBMC, err)
+	}
+	defer func() {
+		if err := bmcClient.Logout(ctx); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to log out of BMC", "server", server.Name)
+		}
+	}()
+
+	return classify(api.FailureClassBMC, fn(bmcClient))
+}
+
+// bmcClientFor builds a Redfish client from the server's BMC config and credential Secret.
+// The Secret should have keys "username" and "password".
+func bmcClientFor(ctx context.Context, c client.Client, server *api.Server) (*bmc.Client, error) {
+	if server.Spec.BMC == nil || server.Spec.BMC.Address == "" {
+		return nil, fmt.Errorf("server %s has no BMC configured", server.Name)
+	}
+
+	var username, password string
+	if ref := server.Spec.BMC.CredentialSecretRef; ref != "" {
+		var secret corev1.Secret
+		if err := c.Get(ctx, client.ObjectKey{Namespace: server.Namespace, Name: ref}, &secret); err != nil {
+			return nil, fmt.Errorf("failed to get BMC credentials secret %s: %w", ref, err)
+		}
+		username = string(secret.Data["username"])
+		password = string(secret.Data["password"])
+	}
+
+	return bmc.NewClient(server.Spec.BMC.Address, username, password), nil
+}
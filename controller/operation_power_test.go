@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+func TestPowerSteps(t *testing.T) {
+	tests := []struct {
+		name         string
+		operation    api.OperationType
+		powerState   string
+		recorded     string // boot ID an earlier run of PowerAction persisted
+		wantResets   []string
+		wantBootID   string
+		wantNodeDone bool
+	}{
+		{name: "power-on already on", operation: api.OperationTypePowerOn, powerState: "On", wantNodeDone: true},
+		{name: "power-on off", operation: api.OperationTypePowerOn, powerState: "Off", wantResets: []string{"On"}, wantBootID: "boot-1"},
+		{name: "power-on retried", operation: api.OperationTypePowerOn, powerState: "On", recorded: "boot-1", wantBootID: "boot-1"},
+		{name: "power-cycle", operation: api.OperationTypePowerCycle, powerState: "On", wantResets: []string{"PowerCycle"}, wantBootID: "boot-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			bmc, bmcServer := newFakeBMC(t, tt.powerState)
+			server := &api.Server{
+				ObjectMeta: metav1.ObjectMeta{Name: "w1", Namespace: "dc"},
+				Spec:       api.ServerSpec{IPv4: "10.0.0.5", BMC: &api.BMCConfig{Address: bmcServer.URL}},
+			}
+			r := &OperationReconciler{Client: fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(testNode("w1", "boot-1")).Build()}
+			operation := &api.Operation{
+				ObjectMeta: metav1.ObjectMeta{Name: "op1", Namespace: "dc"},
+				Spec:       api.OperationSpec{Operation: tt.operation},
+			}
+			operation.Status.NodeBootID = tt.recorded
+			steps := r.powerSteps(operation, server, nil)
+
+			done, err := findStep(t, steps, api.OperationStepPowerAction).run(ctx)
+			if err != nil || !done {
+				t.Fatalf("PowerAction = %v, %v, want done", done, err)
+			}
+			bmc.mu.Lock()
+			resets := bmc.resets
+			bmc.mu.Unlock()
+			if !reflect.DeepEqual(resets, tt.wantResets) {
+				t.Errorf("BMC resets = %v, want %v", resets, tt.wantResets)
+			}
+			if operation.Status.NodeBootID != tt.wantBootID {
+				t.Errorf("NodeBootID = %q, want %q", operation.Status.NodeBootID, tt.wantBootID)
+			}
+
+			// The Node is still Ready on its original boot
+			done, err = findStep(t, steps, api.OperationStepWaitForNode).run(ctx)
+			if err != nil {
+				t.Fatalf("WaitForNode: %v", err)
+			}
+			if done != tt.wantNodeDone {
+				t.Errorf("WaitForNode done = %v, want %v", done, tt.wantNodeDone)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+	"github.com/vpatelsj/stargate/pkg/bmc"
+)
+
+// rebootTimeout bounds how long a reboot waits for the host and Node to come back
+const rebootTimeout = 15 * time.Minute
+
+// remoteRunner runs a script as root on a server over SSH and returns its output
+type remoteRunner func(ctx context.Context, script string) (string, error)
+
+// rebootSteps returns the reboot workflow: cordon and drain the Node, record the boot ID,
+// reboot the host, wait for it to come back Ready, then uncordon it. It is shared by the
+// azure and qemu controllers, which differ only in how they reach the host over SSH.
+func rebootSteps(c client.Client, recorder record.EventRecorder, operation *api.Operation, server *api.Server, run remoteRunner) []operationStep {
+	return []operationStep{
+		cordonStep(c, recorder, operation, server.Name),
+		drainStep(c, recorder, operation, server.Name),
+		{
+			// Persisted on its own so a retried Reboot step can tell the host already restarted
+			name: api.OperationStepRecordBootID,
+			run: func(ctx context.Context) (bool, error) {
+				bootID, err := currentBootID(ctx, c, server, run)
+				if err != nil {
+					// A BMC resets the host without it answering; WaitForNode then waits for
+					// any boot ID from the host
+					if server.Spec.BMC == nil {
+						return false, fmt.Errorf("read boot ID before reboot: %w", err)
+					}
+					log.FromContext(ctx).Info("Boot ID unknown, rebooting through the BMC", "server", server.Name, "error", err.Error())
+				}
+				operation.Status.NodeBootID = bootID
+				return true, nil
+			},
+		},
+		{
+			name: api.OperationStepReboot,
+			run: func(ctx context.Context) (bool, error) {
+				// A previous run may have rebooted the host without persisting its progress
+				if server.Spec.BMC != nil {
+					// The reset is out of band, so SSH is not needed; the Node's boot ID, or the
+					// host's if it answers, shows whether the host already restarted
+					bootID, err := currentBootID(ctx, c, server, run)
+					if err == nil && operation.Status.NodeBootID != "" && bootID != operation.Status.NodeBootID {
+						log.FromContext(ctx).Info("Host already rebooted, skipping reboot", "server", server.Name, "bootID", bootID)
+						return true, nil
+					}
+					err = withBMCSession(ctx, c, server, func(bmcClient *bmc.Client) error {
+						return bmcClient.Reset(ctx, bmc.ResetGracefulRestart)
+					})
+					if err != nil {
+						return false, fmt.Errorf("BMC reset: %w", err)
+					}
+					return true, nil
+				}
+
+				// Without a BMC the reboot goes over SSH. A host that doesn't answer is most likely
+				// going down from an earlier run's reboot, so wait for it to come back and compare
+				// boot IDs rather than fail.
+				bootID, err := readBootID(ctx, server, run)
+				if err != nil {
+					if stepElapsed(operation) > rebootTimeout {
+						return false, timeoutError("host not reachable to reboot within %s", rebootTimeout)
+					}
+					log.FromContext(ctx).V(1).Info("Host not reachable before reboot", "server", server.Name, "error", err.Error())
+					return false, nil
+				}
+				if bootID != operation.Status.NodeBootID {
+					log.FromContext(ctx).Info("Host already rebooted, skipping reboot", "server", server.Name, "bootID", bootID)
+					return true, nil
+				}
+
+				// Schedule the reboot a few seconds out so the SSH session can exit cleanly
+				script := "systemd-run --on-active=5 /bin/systemctl reboot"
+				if output, err := run(ctx, script); err != nil {
+					return false, fmt.Errorf("ssh reboot: %w (output: %s)", err, output)
+				}
+				return true, nil
+			},
+		},
+		{
+			name:      api.OperationStepWaitForNode,
+			condition: api.ConditionNodeRegistered,
+			run: func(ctx context.Context) (bool, error) {
+				if stepElapsed(operation) > rebootTimeout {
+					return false, timeoutError("host did not come back within %s", rebootTimeout)
+				}
+
+				node, err := getNode(ctx, c, server.Name)
+				if err != nil {
+					return false, err
+				}
+				if node != nil {
+					return node.Status.NodeInfo.BootID != operation.Status.NodeBootID && isNodeReady(node), nil
+				}
+
+				// No Node registered for this server - confirm the host itself restarted
+				bootID, err := readBootID(ctx, server, run)
+				if err != nil {
+					log.FromContext(ctx).V(1).Info("Host not reachable yet", "server", server.Name, "error", err.Error())
+					return false, nil
+				}
+				return bootID != operation.Status.NodeBootID, nil
+			},
+		},
+		uncordonStep(c, recorder, operation, server.Name),
+	}
+}
+
+// currentBootID returns the host boot ID, preferring the one reported by the Node
+func currentBootID(ctx context.Context, c client.Client, server *api.Server, run remoteRunner) (string, error) {
+	node, err := getNode(ctx, c, server.Name)
+	if err != nil {
+		return "", err
+	}
+	if node != nil && node.Status.NodeInfo.BootID != "" {
+		return node.Status.NodeInfo.BootID, nil
+	}
+	return readBootID(ctx, server, run)
+}
+
+// readBootID reads the kernel boot ID from the host over SSH
+func readBootID(ctx context.Context, server *api.Server, run remoteRunner) (string, error) {
+	if server.Spec.IPv4 == "" {
+		return "", fmt.Errorf("server %s has no IPv4 address", server.Name)
+	}
+	output, err := run(ctx, "cat /proc/sys/kernel/random/boot_id")
+	if err != nil {
+		return "", fmt.Errorf("%w (output: %s)", err, output)
+	}
+	return strings.TrimSpace(output), nil
+}
@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// fakeBMC is a minimal Redfish service exposing a single system. It records the reset
+// actions it receives.
+type fakeBMC struct {
+	mu         sync.Mutex
+	powerState string
+	resets     []string
+}
+
+func newFakeBMC(t *testing.T, powerState string) (*fakeBMC, *httptest.Server) {
+	b := &fakeBMC{powerState: powerState}
+	server := httptest.NewServer(http.HandlerFunc(b.serveHTTP))
+	t.Cleanup(server.Close)
+	return b, server
+}
+
+func (b *fakeBMC) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/redfish/v1/SessionService/Sessions":
+		w.Header().Set("X-Auth-Token", "token-1")
+		w.Header().Set("Location", "/redfish/v1/SessionService/Sessions/1")
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete && r.URL.Path == "/redfish/v1/SessionService/Sessions/1":
+	case r.Method == http.MethodGet && r.URL.Path == "/redfish/v1/Systems":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"Members": []map[string]string{{"@odata.id": "/redfish/v1/Systems/1"}},
+		})
+	case r.Method == http.MethodGet && r.URL.Path == "/redfish/v1/Systems/1":
+		json.NewEncoder(w).Encode(map[string]interface{}{"Id": "1", "PowerState": b.powerState})
+	case r.Method == http.MethodPost && r.URL.Path == "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset":
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		b.resets = append(b.resets, body["ResetType"])
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (b *fakeBMC) resetCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.resets)
+}
+
+// testNode returns a Ready Node on the given boot
+func testNode(name, bootID string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			NodeInfo:   corev1.NodeSystemInfo{BootID: bootID},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+// findStep returns the named step, failing the test if there is none
+func findStep(t *testing.T, steps []operationStep, name api.OperationStep) operationStep {
+	t.Helper()
+	for _, step := range steps {
+		if step.name == name {
+			return step
+		}
+	}
+	t.Fatalf("no step %s", name)
+	return operationStep{}
+}
+
+// sshHost fakes a host reached over SSH: it answers with bootID, or fails while down
+func sshHost(bootID string, down bool) remoteRunner {
+	return func(ctx context.Context, script string) (string, error) {
+		if down {
+			return "", errors.New("connection refused")
+		}
+		if script == "cat /proc/sys/kernel/random/boot_id" {
+			return bootID + "\n", nil
+		}
+		return "", nil
+	}
+}
+
+func TestRebootSteps(t *testing.T) {
+	tests := []struct {
+		name       string
+		withBMC    bool
+		node       *corev1.Node
+		hostDown   bool
+		hostBootID string
+		recorded   string // boot ID RecordBootID persisted before the step is rerun
+		wantDone   bool
+		wantResets int
+		wantSSH    bool // whether the reboot is issued over SSH
+	}{
+		{name: "bmc reset", withBMC: true, node: testNode("w1", "boot-1"), recorded: "boot-1", wantDone: true, wantResets: 1},
+		{name: "bmc already rebooted", withBMC: true, node: testNode("w1", "boot-2"), recorded: "boot-1", wantDone: true},
+		{name: "bmc host unreachable", withBMC: true, hostDown: true, wantDone: true, wantResets: 1},
+		{name: "ssh host going down", hostDown: true, recorded: "boot-1"},
+		{name: "ssh already rebooted", hostBootID: "boot-2", recorded: "boot-1", wantDone: true},
+		{name: "ssh reboot", hostBootID: "boot-1", recorded: "boot-1", wantDone: true, wantSSH: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			server := &api.Server{
+				ObjectMeta: metav1.ObjectMeta{Name: "w1", Namespace: "dc"},
+				Spec:       api.ServerSpec{IPv4: "10.0.0.5"},
+			}
+			bmc, bmcServer := newFakeBMC(t, "On")
+			if tt.withBMC {
+				server.Spec.BMC = &api.BMCConfig{Address: bmcServer.URL}
+			}
+			builder := fake.NewClientBuilder().WithScheme(newTestScheme(t))
+			if tt.node != nil {
+				builder = builder.WithObjects(tt.node)
+			}
+			c := builder.Build()
+
+			var scripts []string
+			host := sshHost(tt.hostBootID, tt.hostDown)
+			run := func(ctx context.Context, script string) (string, error) {
+				scripts = append(scripts, script)
+				return host(ctx, script)
+			}
+			operation := &api.Operation{ObjectMeta: metav1.ObjectMeta{Name: "op1", Namespace: "dc"}}
+			operation.Status.NodeBootID = tt.recorded
+
+			done, err := findStep(t, rebootSteps(c, nil, operation, server, run), api.OperationStepReboot).run(ctx)
+			if err != nil {
+				t.Fatalf("Reboot: %v", err)
+			}
+			if done != tt.wantDone {
+				t.Errorf("done = %v, want %v", done, tt.wantDone)
+			}
+			if got := bmc.resetCount(); got != tt.wantResets {
+				t.Errorf("BMC resets = %d, want %d", got, tt.wantResets)
+			}
+			sshRebooted := len(scripts) > 0 && scripts[len(scripts)-1] != "cat /proc/sys/kernel/random/boot_id"
+			if sshRebooted != tt.wantSSH {
+				t.Errorf("SSH reboot = %v, want %v (scripts %v)", sshRebooted, tt.wantSSH, scripts)
+			}
+		})
+	}
+}
+
+func TestRecordBootID(t *testing.T) {
+	tests := []struct {
+		name     string
+		withBMC  bool
+		node     *corev1.Node
+		wantErr  bool
+		wantBoot string
+	}{
+		{name: "from node", node: testNode("w1", "boot-1"), wantBoot: "boot-1"},
+		{name: "ssh unreachable", wantErr: true},
+		{name: "ssh unreachable with bmc", withBMC: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &api.Server{
+				ObjectMeta: metav1.ObjectMeta{Name: "w1", Namespace: "dc"},
+				Spec:       api.ServerSpec{IPv4: "10.0.0.5"},
+			}
+			if tt.withBMC {
+				server.Spec.BMC = &api.BMCConfig{Address: "http://bmc.invalid"}
+			}
+			builder := fake.NewClientBuilder().WithScheme(newTestScheme(t))
+			if tt.node != nil {
+				builder = builder.WithObjects(tt.node)
+			}
+			c := builder.Build()
+			operation := &api.Operation{ObjectMeta: metav1.ObjectMeta{Name: "op1", Namespace: "dc"}}
+
+			step := findStep(t, rebootSteps(c, nil, operation, server, sshHost("", true)), api.OperationStepRecordBootID)
+			done, err := step.run(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RecordBootID error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if done == tt.wantErr {
+				t.Errorf("done = %v, want %v", done, !tt.wantErr)
+			}
+			if operation.Status.NodeBootID != tt.wantBoot {
+				t.Errorf("NodeBootID = %q, want %q", operation.Status.NodeBootID, tt.wantBoot)
+			}
+		})
+	}
+}
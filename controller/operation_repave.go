@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// nodeJoinTimeout bounds how long a repave waits for the new Node to register and become Ready
+const nodeJoinTimeout = 10 * time.Minute
+
+// repaveSteps returns the repave workflow: check the host is reachable, cordon and drain the Node,
+// remove the stale Node, allocate its pod CIDR, run the bootstrap script, program routes, then wait
+// for the Node to join.
+// Bootstrap re-runs from the top if the controller restarts mid-step, so the scripts must stay re-runnable.
+func (r *OperationReconciler) repaveSteps(operation *api.Operation, server *api.Server, profile *api.ProvisioningProfile, cfg *bootstrapConfig) []operationStep {
+	return []operationStep{
+		{
+			name:      api.OperationStepPreflight,
+			condition: api.ConditionSSHReachable,
+			run: func(ctx context.Context) (bool, error) {
+				if server.Spec.IPv4 == "" {
+					return false, fmt.Errorf("server %s has no IPv4 address", server.Name)
+				}
+				if output, err := r.runRemoteScript(ctx, server.Spec.IPv4, server.Spec.RouterIP, "true", cfg); err != nil {
+					return false, fmt.Errorf("ssh to %s: %w (output: %s)", server.Spec.IPv4, err, output)
+				}
+				return true, nil
+			},
+		},
+		cordonStep(r.Client, r.Recorder, operation, server.Name),
+		drainStep(r.Client, r.Recorder, operation, server.Name),
+		{
+			name: api.OperationStepNodeCleanup,
+			run: func(ctx context.Context) (bool, error) {
+				done, err := r.deleteNodeIfExists(ctx, operation, server.Name)
+				if err != nil {
+					// Bootstrapping while the old Node is still Terminating would collide with it
+					if failureClassOf(err) == api.FailureClassTimeout {
+						return false, err
+					}
+					log.FromContext(ctx).Error(err, "Failed to delete existing node (continuing anyway)", "node", server.Name)
+					return true, nil
+				}
+				return done, nil
+			},
+		},
+		{
+			name: api.OperationStepAllocatePodCIDR,
+			run: func(ctx context.Context) (bool, error) {
+				_, err := allocatePodCIDR(ctx, r.Client, server)
+				return err == nil, err
+			},
+		},
+		{
+			name:      api.OperationStepBootstrap,
+			condition: api.ConditionBootstrapped,
+			run: func(ctx context.Context) (bool, error) {
+				log.FromContext(ctx).Info("Running bootstrap", "server", server.Name, "ipv4", server.Spec.IPv4, "k8sVersion", cfg.kubernetesVersion)
+				recordBootstrapStarted(r.Recorder, operation, server)
+				err := r.bootstrapServer(ctx, server, profile, cfg)
+				recordBootstrapResult(r.Recorder, operation, server, err)
+				return err == nil, err
+			},
+		},
+		{
+			name: api.OperationStepRouting,
+			run: func(ctx context.Context) (bool, error) {
+				// Configure routing for the new node (DC router, AKS router, Azure route tables)
+				if err := r.configureNodeRouting(ctx, server, cfg); err != nil {
+					// Don't fail the operation - routing can be fixed manually
+					log.FromContext(ctx).Error(err, "Failed to configure routing (node will function but may have connectivity issues)", "server", server.Name)
+					setCondition(&operation.Status.Conditions, operation.Generation, api.ConditionRoutesProgrammed, metav1.ConditionFalse, api.ReasonFailed, err.Error())
+					return true, nil
+				}
+				setCondition(&operation.Status.Conditions, operation.Generation, api.ConditionRoutesProgrammed, metav1.ConditionTrue, api.ReasonSucceeded, "Pod CIDR routes configured")
+				return true, nil
+			},
+		},
+		{
+			name:      api.OperationStepVerify,
+			condition: api.ConditionNodeRegistered,
+			run: func(ctx context.Context) (bool, error) {
+				node, err := getNode(ctx, r.Client, server.Name)
+				if err != nil {
+					return false, err
+				}
+				if node != nil && isNodeReady(node) {
+					return true, nil
+				}
+				if stepElapsed(operation) > nodeJoinTimeout {
+					return false, timeoutError("node %s did not become Ready within %s", server.Name, nodeJoinTimeout)
+				}
+				return false, nil
+			},
+		},
+	}
+}
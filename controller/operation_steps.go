@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// stepPollInterval is how often a step that is waiting on something external is re-run
+const stepPollInterval = 10 * time.Second
+
+// operationStep is one persisted unit of work within a multi-step Operation workflow.
+// Steps are re-run on every reconcile until they report done, so they must be idempotent.
+type operationStep struct {
+	name api.OperationStep
+
+	// condition, if set, is marked True when the step completes and False if it fails
+	condition string
+
+	// run performs the step. It returns false while the step is still waiting
+	// on something external (e.g. a node coming back after a reboot).
+	run func(ctx context.Context) (done bool, err error)
+}
+
+// advanceOperation runs the current step of a Running operation and persists progress.
+// It returns finished=true once the last step has completed. A non-zero requeueAfter
+// means the current step is still waiting; otherwise the caller should requeue
+// immediately to start the next step.
+func advanceOperation(ctx context.Context, c client.Client, recorder record.EventRecorder, operation *api.Operation, steps []operationStep) (finished bool, requeueAfter time.Duration, err error) {
+	logger := log.FromContext(ctx)
+
+	idx := 0
+	if operation.Status.Step != "" {
+		idx = stepIndex(steps, operation.Status.Step)
+		if idx < 0 {
+			return false, 0, fmt.Errorf("unknown step %q for %s operation", operation.Status.Step, operation.Spec.Operation)
+		}
+	}
+	step := steps[idx]
+
+	done, err := step.run(ctx)
+	if err != nil {
+		if step.condition != "" {
+			setCondition(&operation.Status.Conditions, operation.Generation, step.condition, metav1.ConditionFalse, api.ReasonFailed, err.Error())
+		}
+		return false, 0, fmt.Errorf("step %s: %w", step.name, err)
+	}
+	if !done {
+		return false, stepPollInterval, nil
+	}
+
+	logger.Info("Operation step completed", "step", step.name)
+	recordEvent(recorder, operation, corev1.EventTypeNormal, api.EventReasonStepCompleted, "Step %s completed", step.name)
+	if step.condition != "" {
+		setCondition(&operation.Status.Conditions, operation.Generation, step.condition, metav1.ConditionTrue, api.ReasonSucceeded, fmt.Sprintf("Step %s completed", step.name))
+	}
+	operation.Status.CompletedSteps = append(operation.Status.CompletedSteps, step.name)
+	if idx+1 == len(steps) {
+		return true, 0, nil
+	}
+
+	// Persist the transition so the next reconcile picks up at the following step
+	now := metav1.Now()
+	operation.Status.Step = steps[idx+1].name
+	operation.Status.StepStartTime = &now
+	operation.Status.Message = fmt.Sprintf("Step %s in progress", operation.Status.Step)
+	if err := c.Status().Update(ctx, operation); err != nil {
+		return false, 0, fmt.Errorf("persist step %s: %w", operation.Status.Step, err)
+	}
+
+	return false, 0, nil
+}
+
+// stepIndex returns the index of the named step, or -1 if it isn't part of the workflow
+func stepIndex(steps []operationStep, name api.OperationStep) int {
+	for i, s := range steps {
+		if s.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// stepElapsed returns how long the operation has been in its current step
+func stepElapsed(operation *api.Operation) time.Duration {
+	if operation.Status.StepStartTime == nil {
+		return 0
+	}
+	return time.Since(operation.Status.StepStartTime.Time)
+}
@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+const (
+	// upgradeTimeout bounds how long an upgrade waits for the Node to report the new version
+	upgradeTimeout = 10 * time.Minute
+
+	// maxKubeletSkew is how many minor versions the kubelet may trail the API server
+	maxKubeletSkew = 3
+)
+
+// upgradeScript switches the Kubernetes apt repository to the target minor version and
+// upgrades kubelet and kubectl (and kubeadm, if the node was joined with it), then restarts
+// kubelet. Node credentials, kubelet config and CNI state are left in place, so the Node keeps
+// its identity and pod CIDR. The script is re-runnable.
+const upgradeScript = `set -euo pipefail
+# PACKAGE_VERSION may end in a wildcard for apt, not the shell
+set -f
+export DEBIAN_FRONTEND=noninteractive
+KUBERNETES_MINOR='%s'
+PACKAGE_VERSION='%s'
+
+mkdir -p /etc/apt/keyrings
+curl -fsSL "https://pkgs.k8s.io/core:/stable:/v${KUBERNETES_MINOR}/deb/Release.key" | gpg --batch --yes --dearmor -o /etc/apt/keyrings/kubernetes-apt-keyring.gpg
+echo "deb [signed-by=/etc/apt/keyrings/kubernetes-apt-keyring.gpg] https://pkgs.k8s.io/core:/stable:/v${KUBERNETES_MINOR}/deb/ /" > /etc/apt/sources.list.d/kubernetes.list
+apt-get update
+
+PACKAGES="kubelet kubectl"
+if command -v kubeadm >/dev/null; then
+  PACKAGES="kubeadm $PACKAGES"
+fi
+if [[ -n "$PACKAGE_VERSION" ]]; then
+  PACKAGES=$(for p in $PACKAGES; do printf '%%s=%%s ' "$p" "$PACKAGE_VERSION"; done)
+fi
+apt-mark unhold kubelet kubectl kubeadm 2>/dev/null || true
+apt-get install -y --allow-change-held-packages -o Dpkg::Options::="--force-confold" $PACKAGES
+apt-mark hold kubelet kubectl
+if command -v kubeadm >/dev/null; then
+  apt-mark hold kubeadm
+  kubeadm upgrade node
+fi
+
+systemctl daemon-reload
+systemctl restart kubelet
+`
+
+// upgradeSteps returns the upgrade workflow: check version skew, cordon and drain the Node,
+// upgrade kubelet and kubectl in place, wait for the Node to report the new version, then
+// uncordon it. Unlike a repave, the Node is never deleted.
+func (r *OperationReconciler) upgradeSteps(operation *api.Operation, server *api.Server, cfg *bootstrapConfig) []operationStep {
+	return []operationStep{
+		{
+			name: api.OperationStepPreflight,
+			run: func(ctx context.Context) (bool, error) {
+				node, err := getNode(ctx, r.Client, server.Name)
+				if err != nil {
+					return false, err
+				}
+				if node == nil {
+					return false, fmt.Errorf("server %s has no Node to upgrade; repave it instead", server.Name)
+				}
+				if r.Clientset == nil {
+					return false, fmt.Errorf("no Kubernetes clientset configured to read the control plane version")
+				}
+				controlPlane, err := r.Clientset.Discovery().ServerVersion()
+				if err != nil {
+					return false, fmt.Errorf("get control plane version: %w", err)
+				}
+				kubelet := node.Status.NodeInfo.KubeletVersion
+				if err := checkVersionSkew(kubelet, cfg.kubernetesVersion, controlPlane.GitVersion); err != nil {
+					return false, err
+				}
+				log.FromContext(ctx).Info("Version skew check passed", "server", server.Name,
+					"kubelet", kubelet, "target", cfg.kubernetesVersion, "controlPlane", controlPlane.GitVersion)
+				return true, nil
+			},
+		},
+		cordonStep(r.Client, r.Recorder, operation, server.Name),
+		drainStep(r.Client, r.Recorder, operation, server.Name),
+		{
+			name:      api.OperationStepUpgrade,
+			condition: api.ConditionSSHReachable,
+			run: func(ctx context.Context) (bool, error) {
+				if server.Spec.IPv4 == "" {
+					return false, fmt.Errorf("server %s has no IPv4 address", server.Name)
+				}
+				script, err := buildUpgradeScript(cfg.kubernetesVersion)
+				if err != nil {
+					return false, err
+				}
+				log.FromContext(ctx).Info("Upgrading kubelet", "server", server.Name, "k8sVersion", cfg.kubernetesVersion)
+				if output, err := r.runRemoteScript(ctx, server.Spec.IPv4, server.Spec.RouterIP, script, cfg); err != nil {
+					return false, fmt.Errorf("ssh upgrade: %w (output: %s)", err, output)
+				}
+				return true, nil
+			},
+		},
+		{
+			name:      api.OperationStepWaitForNode,
+			condition: api.ConditionNodeRegistered,
+			run: func(ctx context.Context) (bool, error) {
+				if stepElapsed(operation) > upgradeTimeout {
+					return false, timeoutError("node did not report kubelet %s within %s", cfg.kubernetesVersion, upgradeTimeout)
+				}
+				node, err := getNode(ctx, r.Client, server.Name)
+				if err != nil || node == nil {
+					return false, err
+				}
+				return isNodeReady(node) && kubeletAtVersion(node.Status.NodeInfo.KubeletVersion, cfg.kubernetesVersion), nil
+			},
+		},
+		uncordonStep(r.Client, r.Recorder, operation, server.Name),
+	}
+}
+
+// buildUpgradeScript returns upgradeScript for a target version such as "1.34" (latest patch)
+// or "1.34.1" (exact patch)
+func buildUpgradeScript(kubernetesVersion string) (string, error) {
+	target, err := version.ParseGeneric(kubernetesVersion)
+	if err != nil {
+		return "", fmt.Errorf("invalid Kubernetes version %q: %w", kubernetesVersion, err)
+	}
+	minor := fmt.Sprintf("%d.%d", target.Major(), target.Minor())
+	packageVersion := ""
+	if hasPatchVersion(kubernetesVersion) {
+		packageVersion = fmt.Sprintf("%d.%d.%d-*", target.Major(), target.Minor(), target.Patch())
+	}
+	return fmt.Sprintf(upgradeScript, minor, packageVersion), nil
+}
+
+// checkVersionSkew returns an error if upgrading a kubelet from current to target would
+// downgrade it, skip a minor version, make it newer than the control plane, or leave it more
+// than maxKubeletSkew minor versions behind the control plane
+func checkVersionSkew(current, target, controlPlane string) error {
+	cur, err := version.ParseGeneric(current)
+	if err != nil {
+		return fmt.Errorf("invalid kubelet version %q: %w", current, err)
+	}
+	tgt, err := version.ParseGeneric(target)
+	if err != nil {
+		return fmt.Errorf("invalid Kubernetes version %q: %w", target, err)
+	}
+	cp, err := version.ParseGeneric(controlPlane)
+	if err != nil {
+		return fmt.Errorf("invalid control plane version %q: %w", controlPlane, err)
+	}
+
+	if tgt.Major() != cur.Major() || tgt.Major() != cp.Major() {
+		return fmt.Errorf("cannot upgrade across major versions (kubelet %s, target %s, control plane %s)", current, target, controlPlane)
+	}
+	switch {
+	case tgt.Minor() < cur.Minor():
+		return fmt.Errorf("target %s is older than kubelet %s; downgrades are not supported, repave instead", target, current)
+	case tgt.Minor() > cur.Minor()+1:
+		return fmt.Errorf("cannot upgrade kubelet %s to %s; upgrade one minor version at a time", current, target)
+	case tgt.Minor() > cp.Minor():
+		return fmt.Errorf("target %s is newer than the control plane %s; upgrade the control plane first", target, controlPlane)
+	case cp.Minor() > tgt.Minor()+maxKubeletSkew:
+		return fmt.Errorf("target %s is more than %d minor versions behind the control plane %s", target, maxKubeletSkew, controlPlane)
+	}
+	return nil
+}
+
+// kubeletAtVersion returns true if a Node's kubelet version matches the target version: the
+// same minor version, and the same patch if the target has one
+func kubeletAtVersion(kubeletVersion, target string) bool {
+	kubelet, err := version.ParseGeneric(kubeletVersion)
+	if err != nil {
+		return false
+	}
+	tgt, err := version.ParseGeneric(target)
+	if err != nil {
+		return false
+	}
+	if kubelet.Major() != tgt.Major() || kubelet.Minor() != tgt.Minor() {
+		return false
+	}
+	if hasPatchVersion(target) {
+		return kubelet.Patch() == tgt.Patch()
+	}
+	return true
+}
+
+// hasPatchVersion returns true if a version such as "1.34.1" pins a patch release, rather than
+// naming a minor version such as "1.34"
+func hasPatchVersion(v string) bool {
+	return strings.Count(strings.TrimPrefix(v, "v"), ".") >= 2
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckVersionSkew(t *testing.T) {
+	tests := []struct {
+		name         string
+		current      string
+		target       string
+		controlPlane string
+		wantErr      string
+	}{
+		{"next minor", "v1.33.5", "1.34", "v1.34.1", ""},
+		{"patch upgrade", "v1.34.0", "1.34.1", "v1.34.1", ""},
+		{"behind control plane", "v1.31.2", "1.32", "v1.34.1", ""},
+		{"downgrade", "v1.34.0", "1.33", "v1.34.1", "downgrades are not supported"},
+		{"skips a minor", "v1.32.3", "1.34", "v1.34.1", "one minor version at a time"},
+		{"newer than control plane", "v1.33.5", "1.34", "v1.33.2", "upgrade the control plane first"},
+		{"too far behind", "v1.29.0", "1.30", "v1.34.1", "more than 3 minor versions behind"},
+		{"major version", "v1.34.0", "2.0", "v2.0.0", "across major versions"},
+		{"invalid target", "v1.33.5", "latest", "v1.34.1", "invalid Kubernetes version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkVersionSkew(tt.current, tt.target, tt.controlPlane)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkVersionSkew() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkVersionSkew() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKubeletAtVersion(t *testing.T) {
+	tests := []struct {
+		kubelet string
+		target  string
+		want    bool
+	}{
+		{"v1.34.1", "1.34", true},
+		{"v1.33.5", "1.34", false},
+		{"v1.34.1", "1.34.1", true},
+		{"v1.34.0", "1.34.1", false},
+		{"", "1.34", false},
+	}
+	for _, tt := range tests {
+		if got := kubeletAtVersion(tt.kubelet, tt.target); got != tt.want {
+			t.Errorf("kubeletAtVersion(%q, %q) = %v, want %v", tt.kubelet, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestBuildUpgradeScript(t *testing.T) {
+	script, err := buildUpgradeScript("1.34")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(script, "KUBERNETES_MINOR='1.34'") || !strings.Contains(script, "PACKAGE_VERSION=''") {
+		t.Errorf("script for 1.34 does not install the latest 1.34 patch:\n%s", script)
+	}
+
+	script, err = buildUpgradeScript("v1.34.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(script, "KUBERNETES_MINOR='1.34'") || !strings.Contains(script, "PACKAGE_VERSION='1.34.2-*'") {
+		t.Errorf("script for v1.34.2 does not pin the patch:\n%s", script)
+	}
+
+	if _, err := buildUpgradeScript("latest"); err == nil {
+		t.Error("expected an error for an invalid version")
+	}
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// wipeScript resets kubeadm and removes all Kubernetes and container state from the host, then
+// erases the signatures on every data disk. Disks with a mounted filesystem (the OS disk) are
+// left alone. The script is re-runnable.
+const wipeScript = `set -euo pipefail
+systemctl stop kubelet 2>/dev/null || true
+if command -v kubeadm >/dev/null; then
+  kubeadm reset -f || true
+fi
+systemctl stop containerd 2>/dev/null || true
+rm -rf /etc/kubernetes /var/lib/kubelet /var/lib/etcd /var/lib/containerd /etc/cni/net.d /var/lib/cni
+for disk in $(lsblk -dnpo NAME,TYPE | awk '$2 == "disk" {print $1}'); do
+  if lsblk -nro MOUNTPOINT "$disk" | grep -q .; then
+    continue
+  fi
+  echo "Wiping $disk"
+  wipefs -a "$disk"
+done
+systemctl start containerd 2>/dev/null || true
+`
+
+// wipeSteps returns the wipe workflow: cordon and drain the Node, remove it, then erase the
+// host's Kubernetes state and data disks so it can be handed to another tenant
+func (r *OperationReconciler) wipeSteps(operation *api.Operation, server *api.Server, cfg *bootstrapConfig) []operationStep {
+	return []operationStep{
+		cordonStep(r.Client, r.Recorder, operation, server.Name),
+		drainStep(r.Client, r.Recorder, operation, server.Name),
+		{
+			name: api.OperationStepNodeCleanup,
+			run: func(ctx context.Context) (bool, error) {
+				done, err := r.deleteNodeIfExists(ctx, operation, server.Name)
+				if err != nil {
+					return false, fmt.Errorf("delete node %s: %w", server.Name, err)
+				}
+				return done, nil
+			},
+		},
+		r.wipeStep(server, cfg),
+	}
+}
+
+// wipeStep runs wipeScript on the server
+func (r *OperationReconciler) wipeStep(server *api.Server, cfg *bootstrapConfig) operationStep {
+	return operationStep{
+		name:      api.OperationStepWipe,
+		condition: api.ConditionSSHReachable,
+		run: func(ctx context.Context) (bool, error) {
+			if server.Spec.IPv4 == "" {
+				return false, fmt.Errorf("server %s has no IPv4 address", server.Name)
+			}
+			log.FromContext(ctx).Info("Wiping server", "server", server.Name, "ipv4", server.Spec.IPv4)
+			if output, err := r.runRemoteScript(ctx, server.Spec.IPv4, server.Spec.RouterIP, wipeScript, cfg); err != nil {
+				return false, fmt.Errorf("ssh wipe: %w (output: %s)", err, output)
+			}
+			return true, nil
+		},
+	}
+}
@@ -0,0 +1,250 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// workflow describes a stepped Operation type and the Server states it moves through
+type workflow struct {
+	// name is used in status messages (e.g. "Reboot")
+	name string
+
+	// activeState is the Server state while the workflow runs
+	activeState string
+
+	// finalState is the Server state once the workflow succeeds
+	finalState string
+
+	// onSuccess optionally records additional Server status when the workflow succeeds
+	onSuccess func(server *api.Server)
+
+	steps []operationStep
+}
+
+// workflowFor returns the stepped workflow for an operation's type
+func (r *OperationReconciler) workflowFor(operation *api.Operation, server *api.Server, profile *api.ProvisioningProfile, cfg *bootstrapConfig) (*workflow, error) {
+	switch operation.Spec.Operation {
+	case "", api.OperationTypeRepave:
+		return &workflow{
+			name:        "Repave",
+			activeState: "provisioning",
+			finalState:  "ready",
+			onSuccess: func(server *api.Server) {
+				server.Status.CurrentOS = fmt.Sprintf("k8s-%s", profile.Spec.KubernetesVersion)
+				server.Status.AppliedProvisioningProfile = profile.Name
+			},
+			steps: r.repaveSteps(operation, server, profile, cfg),
+		}, nil
+	case api.OperationTypeReboot:
+		return &workflow{
+			name:        "Reboot",
+			activeState: "rebooting",
+			finalState:  "ready",
+			steps:       rebootSteps(r.Client, r.Recorder, operation, server, r.sshRunner(server, cfg)),
+		}, nil
+	case api.OperationTypeWipe:
+		return &workflow{
+			name:        "Wipe",
+			activeState: "wiping",
+			finalState:  "available",
+			onSuccess: func(server *api.Server) {
+				server.Status.CurrentOS = ""
+				server.Status.AppliedProvisioningProfile = ""
+			},
+			steps: r.wipeSteps(operation, server, cfg),
+		}, nil
+	case api.OperationTypeDecommission:
+		return &workflow{
+			name:        "Decommission",
+			activeState: "decommissioning",
+			finalState:  "available",
+			onSuccess: func(server *api.Server) {
+				server.Status.CurrentOS = ""
+				server.Status.AppliedProvisioningProfile = ""
+			},
+			steps: r.decommissionSteps(operation, server, cfg),
+		}, nil
+	case api.OperationTypeUpgrade:
+		return &workflow{
+			name:        "Upgrade",
+			activeState: "upgrading",
+			finalState:  "ready",
+			onSuccess: func(server *api.Server) {
+				server.Status.CurrentOS = fmt.Sprintf("k8s-%s", profile.Spec.KubernetesVersion)
+				server.Status.AppliedProvisioningProfile = profile.Name
+			},
+			steps: r.upgradeSteps(operation, server, cfg),
+		}, nil
+	case api.OperationTypePowerOn, api.OperationTypePowerOff, api.OperationTypePowerCycle:
+		if server.Spec.BMC == nil {
+			return nil, fmt.Errorf("operation %s requires spec.bmc on server %s", operation.Spec.Operation, server.Name)
+		}
+		wf := &workflow{
+			name:        "Power cycle",
+			activeState: "power-cycling",
+			finalState:  "ready",
+			steps:       r.powerSteps(operation, server, cfg),
+		}
+		switch operation.Spec.Operation {
+		case api.OperationTypePowerOn:
+			wf.name, wf.activeState = "Power on", "powering-on"
+		case api.OperationTypePowerOff:
+			wf.name, wf.activeState, wf.finalState = "Power off", "powering-off", "off"
+		}
+		return wf, nil
+	default:
+		return nil, fmt.Errorf("unsupported operation type %q", operation.Spec.Operation)
+	}
+}
+
+// startWorkflow moves a pending stepped operation to Running at its first step
+func (r *OperationReconciler) startWorkflow(ctx context.Context, operation *api.Operation, server *api.Server, profile *api.ProvisioningProfile) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	// Steps are only built here to validate the operation; the config isn't needed yet
+	wf, err := r.workflowFor(operation, server, profile, nil)
+	if err != nil {
+		return r.updateOperationStatus(ctx, operation, api.OperationPhaseFailed, err.Error())
+	}
+
+	logger.Info("Initiating operation", "operation", operation.Spec.Operation, "server", server.Name, "bmc", server.Spec.BMC != nil)
+
+	if err := r.setServerState(ctx, server, wf.activeState, fmt.Sprintf("%s initiated by operation %s", wf.name, operation.Name)); err != nil {
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, err
+	}
+
+	now := metav1.Now()
+	operation.Status.Phase = api.OperationPhaseRunning
+	operation.Status.StartTime = &now
+	operation.Status.Attempts = 1
+	operation.Status.Step = wf.steps[0].name
+	operation.Status.StepStartTime = &now
+	operation.Status.Message = fmt.Sprintf("Step %s in progress", operation.Status.Step)
+	setOperationPhaseCondition(operation)
+	if err := r.Status().Update(ctx, operation); err != nil {
+		logger.Error(err, "Failed to update Operation status")
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, err
+	}
+	recordEvent(r.Recorder, operation, corev1.EventTypeNormal, api.EventReasonStarted, "%s of server %s started", wf.name, server.Name)
+
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// reconcileWorkflow advances a running stepped operation by one step
+func (r *OperationReconciler) reconcileWorkflow(ctx context.Context, operation *api.Operation, server *api.Server, profile *api.ProvisioningProfile) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	// Wait out the backoff of a scheduled retry
+	if wait := retryWait(operation); wait > 0 {
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	cfg, cleanup, err := r.resolveBootstrapConfig(ctx, server, profile)
+	if err != nil {
+		logger.Error(err, "Failed to resolve bootstrap config")
+		return r.updateOperationStatus(ctx, operation, api.OperationPhaseFailed, fmt.Sprintf("Failed to resolve config: %v", err))
+	}
+	defer cleanup()
+
+	wf, err := r.workflowFor(operation, server, profile, cfg)
+	if err != nil {
+		return r.updateOperationStatus(ctx, operation, api.OperationPhaseFailed, err.Error())
+	}
+
+	// Run the step under a context that is cancelled if the operation is cancelled,
+	// deleted or runs past its deadline
+	stepCtx, stop := stepContext(ctx, r.Client, operation)
+	finished, requeueAfter, err := advanceOperation(stepCtx, r.Client, r.Recorder, operation, wf.steps)
+	stop()
+	if err != nil {
+		if reason := stepAbortCause(stepCtx); reason != nil {
+			return ctrl.Result{}, abortOperation(ctx, r.Client, r.Recorder, "azure", operation, server, reason)
+		}
+		observeBootstrapFailure(operation, "azure")
+		if delay, ok := scheduleRetry(operation, err); ok {
+			logger.Error(err, "Operation step failed, will retry", "operation", operation.Spec.Operation, "server", server.Name,
+				"class", operation.Status.LastFailure.Class, "attempt", operation.Status.Attempts, "retryIn", delay)
+			if err := r.Status().Update(ctx, operation); err != nil {
+				return ctrl.Result{RequeueAfter: 5 * time.Second}, err
+			}
+			recordRetry(r.Recorder, operation, delay)
+			return ctrl.Result{RequeueAfter: delay}, nil
+		}
+
+		logger.Error(err, "Operation failed", "operation", operation.Spec.Operation, "server", server.Name)
+		message := fmt.Sprintf("%s failed: %v", wf.name, err)
+		if err := recordServerOutcome(ctx, r.Client, r.Recorder, operation, server, "error", message, nil); err != nil {
+			return ctrl.Result{}, err
+		}
+		return r.updateOperationStatus(ctx, operation, api.OperationPhaseFailed, fmt.Sprintf("%s failed: %v", wf.name, err))
+	}
+	if !finished {
+		if requeueAfter > 0 {
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	logger.Info("Operation succeeded", "operation", operation.Spec.Operation, "server", server.Name)
+	message := fmt.Sprintf("%s completed by operation %s", wf.name, operation.Name)
+	if err := recordServerOutcome(ctx, r.Client, r.Recorder, operation, server, wf.finalState, message, wf.onSuccess); err != nil {
+		return ctrl.Result{}, err
+	}
+	return r.updateOperationStatus(ctx, operation, api.OperationPhaseSucceeded, fmt.Sprintf("%s completed successfully", wf.name))
+}
+
+// setServerState updates the Server's state and message
+func (r *OperationReconciler) setServerState(ctx context.Context, server *api.Server, state, message string) error {
+	previous := server.Status.State
+	server.Status.State = state
+	server.Status.Message = message
+	server.Status.LastUpdated = metav1.Now()
+	setServerReadyCondition(server)
+	if err := r.Status().Update(ctx, server); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to update Server status", "state", state)
+		return err
+	}
+	recordServerState(r.Recorder, server, previous)
+	return nil
+}
+
+// recordServerOutcome records how an operation ended on its Server: its state, message and the
+// conditions the operation observed, plus whatever onSuccess sets. The Server is re-fetched
+// first, since it may have changed while the steps ran, and the update is retried on conflicts.
+// The Server is updated in place. The operation must not be marked finished if this fails; the
+// final step is run again instead.
+func recordServerOutcome(ctx context.Context, c client.Client, recorder record.EventRecorder, operation *api.Operation, server *api.Server, state, message string, onSuccess func(server *api.Server)) error {
+	var previous string
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := c.Get(ctx, client.ObjectKeyFromObject(server), server); err != nil {
+			return err
+		}
+		previous = server.Status.State
+		server.Status.State = state
+		server.Status.Message = message
+		server.Status.LastUpdated = metav1.Now()
+		if onSuccess != nil {
+			onSuccess(server)
+		}
+		copyServerConditions(server, operation)
+		setServerReadyCondition(server)
+		return c.Status().Update(ctx, server)
+	})
+	if err != nil {
+		return fmt.Errorf("record state %s on server %s: %w", state, server.Name, err)
+	}
+	recordServerState(recorder, server, previous)
+	return nil
+}
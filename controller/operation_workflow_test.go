@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// newTestScheme returns a scheme with the core and stargate types registered
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func TestRecordServerOutcome(t *testing.T) {
+	ctx := context.Background()
+	server := &api.Server{
+		ObjectMeta: metav1.ObjectMeta{Name: "w1", Namespace: "dc"},
+		Status:     api.ServerStatus{State: "provisioning"},
+	}
+	c := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(server).WithStatusSubresource(server).Build()
+
+	// The Server the workflow fetched goes stale while its steps run, e.g. when a probe writes
+	// its conditions
+	stale := &api.Server{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(server), stale); err != nil {
+		t.Fatal(err)
+	}
+	fresh := stale.DeepCopy()
+	fresh.Status.Message = "probed"
+	if err := c.Status().Update(ctx, fresh); err != nil {
+		t.Fatal(err)
+	}
+
+	operation := &api.Operation{ObjectMeta: metav1.ObjectMeta{Name: "op", Namespace: "dc"}}
+	onSuccess := func(server *api.Server) { server.Status.AppliedProvisioningProfile = "p1" }
+	if err := recordServerOutcome(ctx, c, record.NewFakeRecorder(10), operation, stale, "ready", "done", onSuccess); err != nil {
+		t.Fatalf("recordServerOutcome() = %v", err)
+	}
+
+	var got api.Server
+	if err := c.Get(ctx, client.ObjectKeyFromObject(server), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.State != "ready" || got.Status.Message != "done" || got.Status.AppliedProvisioningProfile != "p1" {
+		t.Errorf("server status = %+v, want state ready, message done and profile p1", got.Status)
+	}
+}
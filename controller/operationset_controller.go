@@ -0,0 +1,338 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// OperationSetReconciler rolls an Operation out across the Servers selected by an OperationSet
+type OperationSetReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// operationSetPlan is the outcome of planning one reconcile of an OperationSet
+type operationSetPlan struct {
+	// start lists the Servers to create an Operation for now, in order
+	start []string
+
+	total, pending, running, succeeded, failed int32
+	currentRack                                string
+	thresholdReached                           bool
+
+	// unavailable counts the Servers with a running Operation, and those with a failed one that
+	// aren't Ready again
+	unavailable int32
+	// blocked is set when Servers are waiting but failed, still unavailable Servers use up
+	// maxUnavailable with nothing running, so no Operation can start until one recovers
+	blocked bool
+}
+
+// rackOf returns the rack part of an inventory location ("rack-5-slot-12" -> "rack-5")
+func rackOf(location string) string {
+	if i := strings.Index(location, "-slot"); i >= 0 {
+		return location[:i]
+	}
+	return location
+}
+
+// childOperationName returns the name of the Operation an OperationSet creates for a Server
+func childOperationName(set *api.OperationSet, serverName string) string {
+	return fmt.Sprintf("%s-%s", set.Name, serverName)
+}
+
+// selectServers returns the Servers matched by an inventory selector, ordered by rack and then
+// name so rollouts and claims are deterministic. A nil selector matches every Server.
+func selectServers(inv *api.InventorySelector, servers []api.Server) []api.Server {
+	var selected []api.Server
+	for _, server := range servers {
+		if inv != nil {
+			if inv.SKU != "" && server.Spec.Inventory.SKU != inv.SKU {
+				continue
+			}
+			if !strings.HasPrefix(server.Spec.Inventory.Location, inv.LocationPrefix) {
+				continue
+			}
+		}
+		selected = append(selected, server)
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool {
+		ri, rj := rackOf(selected[i].Spec.Inventory.Location), rackOf(selected[j].Spec.Inventory.Location)
+		if ri != rj {
+			return ri < rj
+		}
+		return selected[i].Name < selected[j].Name
+	})
+	return selected
+}
+
+// planOperationSet counts the progress of the set's Operations and picks the Servers whose
+// Operation can be started without exceeding maxParallel, maxUnavailable or the failure threshold.
+// A Server whose Operation failed counts as unavailable only until it is Ready again.
+// servers must be ordered as returned by selectServers; children maps Server name to its Operation.
+func planOperationSet(set *api.OperationSet, servers []api.Server, children map[string]*api.Operation) operationSetPlan {
+	maxParallel := set.Spec.MaxParallel
+	if maxParallel <= 0 {
+		maxParallel = 1
+	}
+	maxUnavailable := set.Spec.MaxUnavailable
+	if maxUnavailable <= 0 {
+		maxUnavailable = maxParallel
+	}
+	failureThreshold := set.Spec.FailureThreshold
+	if failureThreshold <= 0 {
+		failureThreshold = 1
+	}
+
+	plan := operationSetPlan{total: int32(len(servers))}
+	var candidates []string
+	for _, server := range servers {
+		rack := rackOf(server.Spec.Inventory.Location)
+		child, ok := children[server.Name]
+		switch {
+		case !ok:
+			plan.pending++
+		case child.Status.Phase == api.OperationPhaseSucceeded:
+			plan.succeeded++
+			continue
+		case child.Status.Phase == api.OperationPhaseFailed || child.Status.Phase == api.OperationPhaseCancelled:
+			plan.failed++
+			if !meta.IsStatusConditionTrue(server.Status.Conditions, api.ConditionReady) {
+				plan.unavailable++
+			}
+			continue
+		default:
+			plan.running++
+			plan.unavailable++
+		}
+
+		// Servers are ordered by rack, so the first unfinished Server is in the current rack
+		if plan.currentRack == "" {
+			plan.currentRack = rack
+		}
+		if !ok && (!set.Spec.RackByRack || rack == plan.currentRack) {
+			candidates = append(candidates, server.Name)
+		}
+	}
+	if !set.Spec.RackByRack {
+		plan.currentRack = ""
+	}
+
+	plan.thresholdReached = plan.failed >= failureThreshold
+	if plan.thresholdReached || set.Spec.Paused {
+		return plan
+	}
+
+	budget := min(maxParallel-plan.running, maxUnavailable-plan.unavailable)
+	plan.blocked = budget <= 0 && plan.running == 0 && len(candidates) > 0
+	for i := 0; i < len(candidates) && int32(i) < budget; i++ {
+		plan.start = append(plan.start, candidates[i])
+	}
+	return plan
+}
+
+// +kubebuilder:rbac:groups=stargate.io,resources=operationsets,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=stargate.io,resources=operationsets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=stargate.io,resources=operationsets/finalizers,verbs=update
+// +kubebuilder:rbac:groups=stargate.io,resources=operations,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=stargate.io,resources=servers,verbs=get;list;watch
+
+// Reconcile creates the next batch of Operations for an OperationSet and aggregates their progress
+func (r *OperationSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var set api.OperationSet
+	if err := r.Get(ctx, req.NamespacedName, &set); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// Skip if the rollout already finished
+	if set.Status.Phase == api.OperationSetPhaseSucceeded || set.Status.Phase == api.OperationSetPhaseFailed {
+		return ctrl.Result{}, nil
+	}
+
+	selector := labels.Everything()
+	if set.Spec.Selector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(set.Spec.Selector); err != nil {
+			return r.updateStatus(ctx, &set, operationSetPlan{}, api.OperationSetPhaseFailed, fmt.Sprintf("Invalid selector: %v", err))
+		}
+	}
+
+	var serverList api.ServerList
+	if err := r.List(ctx, &serverList, client.InNamespace(set.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("list servers: %w", err)
+	}
+	servers := selectServers(set.Spec.Inventory, serverList.Items)
+
+	var operationList api.OperationList
+	if err := r.List(ctx, &operationList, client.InNamespace(set.Namespace), client.MatchingLabels{api.OperationSetLabel: set.Name}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("list operations: %w", err)
+	}
+	children := make(map[string]*api.Operation, len(operationList.Items))
+	for i := range operationList.Items {
+		op := &operationList.Items[i]
+		if metav1.IsControlledBy(op, &set) {
+			children[op.Spec.ServerRef.Name] = op
+		}
+	}
+
+	plan := planOperationSet(&set, servers, children)
+	for _, serverName := range plan.start {
+		if err := r.createChild(ctx, &set, serverName); err != nil {
+			return ctrl.Result{}, err
+		}
+		logger.Info("Started operation", "server", serverName, "operation", set.Spec.Template.Operation)
+		plan.pending--
+		plan.running++
+	}
+
+	switch {
+	case plan.pending == 0 && plan.running == 0 && plan.failed == 0:
+		return r.updateStatus(ctx, &set, plan, api.OperationSetPhaseSucceeded, fmt.Sprintf("All %d operations succeeded", plan.succeeded))
+	case plan.pending == 0 && plan.running == 0:
+		return r.updateStatus(ctx, &set, plan, api.OperationSetPhaseFailed, fmt.Sprintf("%d of %d operations failed", plan.failed, plan.total))
+	case plan.thresholdReached:
+		return r.updateStatus(ctx, &set, plan, api.OperationSetPhasePaused, fmt.Sprintf("Paused after %d failed operations", plan.failed))
+	case set.Spec.Paused:
+		return r.updateStatus(ctx, &set, plan, api.OperationSetPhasePaused, "Paused by spec.paused")
+	case plan.blocked:
+		return r.updateStatus(ctx, &set, plan, api.OperationSetPhasePaused,
+			fmt.Sprintf("Paused: %d failed Servers are unavailable, the maxUnavailable budget is used up", plan.unavailable))
+	default:
+		return r.updateStatus(ctx, &set, plan, api.OperationSetPhaseRunning,
+			fmt.Sprintf("%d/%d succeeded, %d running", plan.succeeded, plan.total, plan.running))
+	}
+}
+
+// createChild creates the Operation for one Server, owned by the set
+func (r *OperationSetReconciler) createChild(ctx context.Context, set *api.OperationSet, serverName string) error {
+	tmpl := set.Spec.Template.DeepCopy()
+	op := &api.Operation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      childOperationName(set, serverName),
+			Namespace: set.Namespace,
+			Labels:    map[string]string{api.OperationSetLabel: set.Name},
+		},
+		Spec: api.OperationSpec{
+			ServerRef:              api.LocalObjectReference{Name: serverName},
+			ProvisioningProfileRef: tmpl.ProvisioningProfileRef,
+			Operation:              tmpl.Operation,
+			RetryPolicy:            tmpl.RetryPolicy,
+			ActiveDeadlineSeconds:  tmpl.ActiveDeadlineSeconds,
+			Drain:                  tmpl.Drain,
+			Decommission:           tmpl.Decommission,
+			Priority:               tmpl.Priority,
+		},
+	}
+	if err := controllerutil.SetControllerReference(set, op, r.Scheme); err != nil {
+		return fmt.Errorf("set owner on operation %s: %w", op.Name, err)
+	}
+	if err := r.Create(ctx, op); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create operation %s: %w", op.Name, err)
+	}
+	return nil
+}
+
+// updateStatus records the set's progress
+func (r *OperationSetReconciler) updateStatus(ctx context.Context, set *api.OperationSet, plan operationSetPlan, phase api.OperationSetPhase, message string) (ctrl.Result, error) {
+	now := metav1.Now()
+	if set.Status.StartTime == nil {
+		set.Status.StartTime = &now
+	}
+	if phase == api.OperationSetPhaseSucceeded || phase == api.OperationSetPhaseFailed {
+		set.Status.CompletionTime = &now
+	}
+
+	set.Status.Phase = phase
+	set.Status.Message = message
+	set.Status.Total = plan.total
+	set.Status.Pending = plan.pending
+	set.Status.Running = plan.running
+	set.Status.Succeeded = plan.succeeded
+	set.Status.Failed = plan.failed
+	set.Status.CurrentRack = plan.currentRack
+	set.Status.ObservedGeneration = set.Generation
+
+	switch phase {
+	case api.OperationSetPhaseSucceeded:
+		setCondition(&set.Status.Conditions, set.Generation, api.ConditionSucceeded, metav1.ConditionTrue, api.ReasonSucceeded, message)
+	case api.OperationSetPhaseFailed:
+		setCondition(&set.Status.Conditions, set.Generation, api.ConditionSucceeded, metav1.ConditionFalse, api.ReasonFailed, message)
+	case api.OperationSetPhasePaused:
+		setCondition(&set.Status.Conditions, set.Generation, api.ConditionSucceeded, metav1.ConditionUnknown, api.ReasonPaused, message)
+	default:
+		setCondition(&set.Status.Conditions, set.Generation, api.ConditionSucceeded, metav1.ConditionUnknown, api.ReasonInProgress, message)
+	}
+
+	if err := r.Status().Update(ctx, set); err != nil {
+		return ctrl.Result{}, fmt.Errorf("update OperationSet status: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// setSelectsServer returns true if the set's selector and inventory selector match the Server
+func setSelectsServer(set *api.OperationSet, server *api.Server) (bool, error) {
+	if set.Spec.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(set.Spec.Selector)
+		if err != nil {
+			return false, err
+		}
+		if !selector.Matches(labels.Set(server.Labels)) {
+			return false, nil
+		}
+	}
+	return len(selectServers(set.Spec.Inventory, []api.Server{*server})) == 1, nil
+}
+
+// setsForServer maps a Server to the unfinished OperationSets that select it, so a set blocked
+// by unavailable Servers resumes once one is Ready again
+func (r *OperationSetReconciler) setsForServer(ctx context.Context, obj client.Object) []reconcile.Request {
+	server, ok := obj.(*api.Server)
+	if !ok {
+		return nil
+	}
+	var sets api.OperationSetList
+	if err := r.List(ctx, &sets, client.InNamespace(server.Namespace)); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list OperationSets for server", "server", server.Name)
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, set := range sets.Items {
+		if set.Status.Phase == api.OperationSetPhaseSucceeded || set.Status.Phase == api.OperationSetPhaseFailed {
+			continue
+		}
+		// A set with an invalid selector fails on its own reconcile
+		if selected, err := setSelectsServer(&set, server); err != nil || !selected {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&set)})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *OperationSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&api.OperationSet{}).
+		Owns(&api.Operation{}).
+		Watches(&api.Server{}, handler.EnqueueRequestsFromMapFunc(r.setsForServer)).
+		Complete(r)
+}
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+func TestRackOf(t *testing.T) {
+	tests := map[string]string{
+		"rack-5-slot-12": "rack-5",
+		"rack-5":         "rack-5",
+		"":               "",
+	}
+	for location, expected := range tests {
+		if got := rackOf(location); got != expected {
+			t.Errorf("rackOf(%q) = %q, want %q", location, got, expected)
+		}
+	}
+}
+
+func TestPlanOperationSet(t *testing.T) {
+	server := func(name, location string) api.Server {
+		return api.Server{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       api.ServerSpec{Inventory: api.ServerInventory{SKU: "GPU-8xH100", Location: location}},
+		}
+	}
+	child := func(phase api.OperationPhase) *api.Operation {
+		return &api.Operation{Status: api.OperationStatus{Phase: phase}}
+	}
+
+	set := &api.OperationSet{}
+	set.Spec.Inventory = &api.InventorySelector{SKU: "GPU-8xH100"}
+	servers := selectServers(set.Spec.Inventory, []api.Server{
+		server("s4", "rack-2-slot-1"),
+		server("s2", "rack-1-slot-2"),
+		server("s3", "rack-2-slot-2"),
+		server("s1", "rack-1-slot-1"),
+		{ObjectMeta: metav1.ObjectMeta{Name: "other"}, Spec: api.ServerSpec{Inventory: api.ServerInventory{SKU: "CPU"}}},
+	})
+
+	tests := []struct {
+		name      string
+		spec      api.OperationSetSpec
+		children  map[string]*api.Operation
+		start     []string
+		ready     []string // Servers that are Ready
+		paused    bool
+		blocked   bool
+		rack      string
+		succeeded int32
+	}{
+		{
+			name:  "defaults start one at a time",
+			start: []string{"s1"},
+		},
+		{
+			name:  "max parallel",
+			spec:  api.OperationSetSpec{MaxParallel: 3},
+			start: []string{"s1", "s2", "s3"},
+		},
+		{
+			name:     "max unavailable counts running operations",
+			spec:     api.OperationSetSpec{MaxParallel: 3, MaxUnavailable: 2},
+			children: map[string]*api.Operation{"s1": child(api.OperationPhaseRunning)},
+			start:    []string{"s2"},
+		},
+		{
+			name:      "rack by rack waits for the current rack",
+			spec:      api.OperationSetSpec{MaxParallel: 4, RackByRack: true},
+			children:  map[string]*api.Operation{"s1": child(api.OperationPhaseSucceeded)},
+			start:     []string{"s2"},
+			rack:      "rack-1",
+			succeeded: 1,
+		},
+		{
+			name: "rack by rack moves to the next rack",
+			spec: api.OperationSetSpec{MaxParallel: 4, RackByRack: true},
+			children: map[string]*api.Operation{
+				"s1": child(api.OperationPhaseSucceeded),
+				"s2": child(api.OperationPhaseSucceeded),
+			},
+			start:     []string{"s3", "s4"},
+			rack:      "rack-2",
+			succeeded: 2,
+		},
+		{
+			name:     "failure threshold pauses",
+			spec:     api.OperationSetSpec{MaxParallel: 4},
+			children: map[string]*api.Operation{"s1": child(api.OperationPhaseFailed)},
+			paused:   true,
+		},
+		{
+			name:     "failures below threshold count as unavailable",
+			spec:     api.OperationSetSpec{MaxParallel: 4, MaxUnavailable: 2, FailureThreshold: 2},
+			children: map[string]*api.Operation{"s1": child(api.OperationPhaseFailed)},
+			start:    []string{"s2"},
+		},
+		{
+			name:     "failed servers still unavailable block the rollout",
+			spec:     api.OperationSetSpec{MaxParallel: 2, MaxUnavailable: 1, FailureThreshold: 3},
+			children: map[string]*api.Operation{"s1": child(api.OperationPhaseFailed)},
+			blocked:  true,
+		},
+		{
+			name:     "failed servers that are ready again don't count as unavailable",
+			spec:     api.OperationSetSpec{MaxParallel: 2, MaxUnavailable: 1, FailureThreshold: 3},
+			children: map[string]*api.Operation{"s1": child(api.OperationPhaseFailed)},
+			ready:    []string{"s1"},
+			start:    []string{"s2"},
+		},
+		{
+			name: "paused by spec",
+			spec: api.OperationSetSpec{Paused: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			servers := append([]api.Server(nil), servers...)
+			for i := range servers {
+				for _, name := range tt.ready {
+					if servers[i].Name == name {
+						servers[i].Status.Conditions = []metav1.Condition{{Type: api.ConditionReady, Status: metav1.ConditionTrue}}
+					}
+				}
+			}
+			set := &api.OperationSet{Spec: tt.spec}
+			plan := planOperationSet(set, servers, tt.children)
+			if plan.total != 4 {
+				t.Errorf("expected 4 selected servers, got %d", plan.total)
+			}
+			if !reflect.DeepEqual(plan.start, tt.start) {
+				t.Errorf("expected to start %v, got %v", tt.start, plan.start)
+			}
+			if plan.thresholdReached != tt.paused {
+				t.Errorf("expected thresholdReached=%v, got %v", tt.paused, plan.thresholdReached)
+			}
+			if plan.blocked != tt.blocked {
+				t.Errorf("expected blocked=%v, got %v", tt.blocked, plan.blocked)
+			}
+			if plan.currentRack != tt.rack {
+				t.Errorf("expected current rack %q, got %q", tt.rack, plan.currentRack)
+			}
+			if plan.succeeded != tt.succeeded {
+				t.Errorf("expected %d succeeded, got %d", tt.succeeded, plan.succeeded)
+			}
+		})
+	}
+}
+
+func TestSetSelectsServer(t *testing.T) {
+	server := &api.Server{ObjectMeta: metav1.ObjectMeta{Name: "s1", Labels: map[string]string{"pool": "a"}}}
+	server.Spec.Inventory.SKU = "gpu"
+	server.Spec.Inventory.Location = "rack-5-slot-1"
+
+	tests := []struct {
+		name      string
+		selector  *metav1.LabelSelector
+		inventory *api.InventorySelector
+		want      bool
+	}{
+		{"everything", nil, nil, true},
+		{"matching labels", &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "a"}}, nil, true},
+		{"other labels", &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "b"}}, nil, false},
+		{"matching inventory", nil, &api.InventorySelector{SKU: "gpu", LocationPrefix: "rack-5"}, true},
+		{"other rack", nil, &api.InventorySelector{LocationPrefix: "rack-6"}, false},
+		{"labels match but SKU doesn't", &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "a"}}, &api.InventorySelector{SKU: "cpu"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := &api.OperationSet{Spec: api.OperationSetSpec{Selector: tt.selector, Inventory: tt.inventory}}
+			got, err := setSelectsServer(set, server)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("setSelectsServer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
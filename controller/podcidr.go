@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+	"github.com/vpatelsj/stargate/pkg/ipam"
+)
+
+// defaultPodCIDRBlockSize is the prefix length of pod CIDRs when a pool doesn't set one
+const defaultPodCIDRBlockSize = 24
+
+// serverPodCIDR returns the pod CIDR allocated to a Server from its PodCIDRPool
+func serverPodCIDR(server *api.Server) (string, error) {
+	if server.Status.PodCIDR == "" {
+		return "", fmt.Errorf("server %s has no pod CIDR allocated from a PodCIDRPool", server.Name)
+	}
+	return server.Status.PodCIDR, nil
+}
+
+// ipamPool returns the allocator for a PodCIDRPool's spec
+func ipamPool(pool *api.PodCIDRPool) (*ipam.Pool, error) {
+	blockSize := int(pool.Spec.BlockSize)
+	if blockSize == 0 {
+		blockSize = defaultPodCIDRBlockSize
+	}
+	return ipam.NewPool(pool.Spec.CIDR, blockSize, pool.Spec.Reserved)
+}
+
+// poolSelects returns true if the pool allocates for the Server
+func poolSelects(pool *api.PodCIDRPool, server *api.Server) (bool, error) {
+	if pool.Spec.Selector == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(pool.Spec.Selector)
+	if err != nil {
+		return false, fmt.Errorf("invalid selector on PodCIDRPool %s: %w", pool.Name, err)
+	}
+	return selector.Matches(labels.Set(server.Labels)), nil
+}
+
+// poolForServer picks the PodCIDRPool a Server's pod CIDR comes from: the pool that already
+// has an allocation for it, otherwise the pool named by its Datacenter (datacenterPool), otherwise
+// the first pool by name whose selector matches. It returns nil if no pool serves the Server.
+func poolForServer(pools []api.PodCIDRPool, server *api.Server, datacenterPool string) (*api.PodCIDRPool, error) {
+	sort.Slice(pools, func(i, j int) bool { return pools[i].Name < pools[j].Name })
+	for i := range pools {
+		if allocationFor(&pools[i], server.Name) != "" {
+			return &pools[i], nil
+		}
+	}
+	if datacenterPool != "" {
+		for i := range pools {
+			if pools[i].Name == datacenterPool {
+				return &pools[i], nil
+			}
+		}
+		return nil, fmt.Errorf("PodCIDRPool %s of server %s's datacenter not found", datacenterPool, server.Name)
+	}
+	for i := range pools {
+		selected, err := poolSelects(&pools[i], server)
+		if err != nil {
+			return nil, err
+		}
+		if selected {
+			return &pools[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// allocationFor returns the CIDR allocated to the Server from the pool, or ""
+func allocationFor(pool *api.PodCIDRPool, serverName string) string {
+	for _, a := range pool.Status.Allocations {
+		if a.Server == serverName {
+			return a.CIDR
+		}
+	}
+	return ""
+}
+
+// assignPodCIDR allocates a pod CIDR to the Server in the pool's status, or returns the one
+// it already has. changed reports whether the pool's status must be saved.
+func assignPodCIDR(pool *api.PodCIDRPool, serverName string) (cidr string, changed bool, err error) {
+	if cidr := allocationFor(pool, serverName); cidr != "" {
+		return cidr, false, nil
+	}
+	allocator, err := ipamPool(pool)
+	if err != nil {
+		return "", false, fmt.Errorf("PodCIDRPool %s: %w", pool.Name, err)
+	}
+	used := make([]string, 0, len(pool.Status.Allocations))
+	for _, a := range pool.Status.Allocations {
+		used = append(used, a.CIDR)
+	}
+	cidr, err = allocator.Allocate(used)
+	if err != nil {
+		return "", false, fmt.Errorf("PodCIDRPool %s: %w", pool.Name, err)
+	}
+	pool.Status.Allocations = append(pool.Status.Allocations, api.PodCIDRAllocation{Server: serverName, CIDR: cidr})
+	setPoolCounts(pool, allocator)
+	return cidr, true, nil
+}
+
+// unassignPodCIDR removes the Server's allocation from the pool's status. It returns true if
+// there was one.
+func unassignPodCIDR(pool *api.PodCIDRPool, serverName string) bool {
+	kept := pool.Status.Allocations[:0]
+	for _, a := range pool.Status.Allocations {
+		if a.Server != serverName {
+			kept = append(kept, a)
+		}
+	}
+	if len(kept) == len(pool.Status.Allocations) {
+		return false
+	}
+	pool.Status.Allocations = kept
+	if allocator, err := ipamPool(pool); err == nil {
+		setPoolCounts(pool, allocator)
+	}
+	return true
+}
+
+// setPoolCounts records the number of allocated and available pod CIDRs in the pool's status
+func setPoolCounts(pool *api.PodCIDRPool, allocator *ipam.Pool) {
+	used := make([]string, 0, len(pool.Status.Allocations))
+	for _, a := range pool.Status.Allocations {
+		used = append(used, a.CIDR)
+	}
+	pool.Status.Allocated = int32(len(pool.Status.Allocations))
+	pool.Status.Available = int32(allocator.Available(used))
+}
+
+// allocatePodCIDR gives the Server a pod CIDR from the PodCIDRPool that serves it and records
+// it in the Server's status. The allocation is kept across repaves. It fails if no pool serves
+// the Server. The Server is updated in place.
+func allocatePodCIDR(ctx context.Context, c client.Client, server *api.Server) (string, error) {
+	if server.Status.PodCIDR != "" {
+		return server.Status.PodCIDR, nil
+	}
+
+	var pools api.PodCIDRPoolList
+	if err := c.List(ctx, &pools, client.InNamespace(server.Namespace)); err != nil {
+		return "", fmt.Errorf("list PodCIDRPools: %w", err)
+	}
+	datacenter, err := getServerDatacenter(ctx, c, server)
+	if err != nil {
+		return "", err
+	}
+	pool, err := poolForServer(pools.Items, server, datacenterPodCIDRPool(datacenter))
+	if err != nil {
+		return "", err
+	}
+	if pool == nil {
+		return "", fmt.Errorf("no PodCIDRPool serves server %s", server.Name)
+	}
+
+	cidr, changed, err := assignPodCIDR(pool, server.Name)
+	if err != nil {
+		return "", err
+	}
+	// The update fails on a conflict if another Server took a CIDR from the pool first
+	if changed {
+		if err := c.Status().Update(ctx, pool); err != nil {
+			return "", fmt.Errorf("record allocation in PodCIDRPool %s: %w", pool.Name, err)
+		}
+	}
+
+	server.Status.PodCIDR = cidr
+	if err := c.Status().Update(ctx, server); err != nil {
+		return "", fmt.Errorf("record pod CIDR on server %s: %w", server.Name, err)
+	}
+	log.FromContext(ctx).Info("Allocated pod CIDR", "server", server.Name, "pool", pool.Name, "podCIDR", cidr)
+	return cidr, nil
+}
+
+// releasePodCIDR returns the Server's pod CIDR to its PodCIDRPool and clears it from the
+// Server's status. The Server is updated in place.
+func releasePodCIDR(ctx context.Context, c client.Client, server *api.Server) error {
+	var pools api.PodCIDRPoolList
+	if err := c.List(ctx, &pools, client.InNamespace(server.Namespace)); err != nil {
+		return fmt.Errorf("list PodCIDRPools: %w", err)
+	}
+	for i := range pools.Items {
+		pool := &pools.Items[i]
+		if !unassignPodCIDR(pool, server.Name) {
+			continue
+		}
+		if err := c.Status().Update(ctx, pool); err != nil {
+			return fmt.Errorf("release allocation in PodCIDRPool %s: %w", pool.Name, err)
+		}
+		log.FromContext(ctx).Info("Released pod CIDR", "server", server.Name, "pool", pool.Name)
+	}
+
+	if server.Status.PodCIDR == "" {
+		return nil
+	}
+	server.Status.PodCIDR = ""
+	if err := c.Status().Update(ctx, server); err != nil {
+		return fmt.Errorf("clear pod CIDR on server %s: %w", server.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+func TestAssignPodCIDR(t *testing.T) {
+	pool := &api.PodCIDRPool{
+		ObjectMeta: metav1.ObjectMeta{Name: "dc1"},
+		Spec:       api.PodCIDRPoolSpec{CIDR: "10.244.0.0/22", BlockSize: 24, Reserved: []string{"10.244.0.0/24"}},
+	}
+
+	cidr, changed, err := assignPodCIDR(pool, "worker-1")
+	if err != nil || !changed || cidr != "10.244.1.0/24" {
+		t.Fatalf("assignPodCIDR(worker-1) = %q, %v, %v; want 10.244.1.0/24, true, nil", cidr, changed, err)
+	}
+	cidr, changed, err = assignPodCIDR(pool, "worker-2")
+	if err != nil || !changed || cidr != "10.244.2.0/24" {
+		t.Fatalf("assignPodCIDR(worker-2) = %q, %v, %v; want 10.244.2.0/24, true, nil", cidr, changed, err)
+	}
+
+	// A Server keeps its allocation
+	cidr, changed, err = assignPodCIDR(pool, "worker-1")
+	if err != nil || changed || cidr != "10.244.1.0/24" {
+		t.Fatalf("assignPodCIDR(worker-1) again = %q, %v, %v; want 10.244.1.0/24, false, nil", cidr, changed, err)
+	}
+	if pool.Status.Allocated != 2 || pool.Status.Available != 1 {
+		t.Errorf("allocated/available = %d/%d, want 2/1", pool.Status.Allocated, pool.Status.Available)
+	}
+
+	if _, _, err := assignPodCIDR(pool, "worker-3"); err != nil {
+		t.Fatalf("assignPodCIDR(worker-3): %v", err)
+	}
+	if _, _, err := assignPodCIDR(pool, "worker-4"); err == nil {
+		t.Error("assignPodCIDR on an exhausted pool succeeded")
+	}
+
+	// A released CIDR is handed out again
+	if !unassignPodCIDR(pool, "worker-1") {
+		t.Fatal("unassignPodCIDR(worker-1) = false")
+	}
+	if unassignPodCIDR(pool, "worker-1") {
+		t.Error("unassignPodCIDR(worker-1) twice = true")
+	}
+	cidr, _, err = assignPodCIDR(pool, "worker-4")
+	if err != nil || cidr != "10.244.1.0/24" {
+		t.Errorf("assignPodCIDR(worker-4) = %q, %v; want 10.244.1.0/24", cidr, err)
+	}
+}
+
+func TestPoolForServer(t *testing.T) {
+	selected := func(name string, labels map[string]string) api.PodCIDRPool {
+		pool := api.PodCIDRPool{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if labels != nil {
+			pool.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+		}
+		return pool
+	}
+	server := &api.Server{ObjectMeta: metav1.ObjectMeta{Name: "worker-1", Labels: map[string]string{"datacenter": "dc2"}}}
+
+	pools := []api.PodCIDRPool{selected("b-dc2", map[string]string{"datacenter": "dc2"}), selected("a-dc1", map[string]string{"datacenter": "dc1"})}
+	if pool, err := poolForServer(pools, server, ""); err != nil || pool == nil || pool.Name != "b-dc2" {
+		t.Errorf("poolForServer = %v, %v; want b-dc2", pool, err)
+	}
+
+	// A pool that already holds an allocation for the Server wins over a selector match
+	pools = []api.PodCIDRPool{selected("a-any", nil), selected("b-dc1", map[string]string{"datacenter": "dc1"})}
+	pools[1].Status.Allocations = []api.PodCIDRAllocation{{Server: "worker-1", CIDR: "10.244.1.0/24"}}
+	if pool, err := poolForServer(pools, server, ""); err != nil || pool == nil || pool.Name != "b-dc1" {
+		t.Errorf("poolForServer = %v, %v; want b-dc1", pool, err)
+	}
+
+	pools = []api.PodCIDRPool{selected("a-dc1", map[string]string{"datacenter": "dc1"})}
+	if pool, err := poolForServer(pools, server, ""); err != nil || pool != nil {
+		t.Errorf("poolForServer = %v, %v; want nil", pool, err)
+	}
+
+	// The Datacenter's pool is used regardless of selectors
+	if pool, err := poolForServer(pools, server, "a-dc1"); err != nil || pool == nil || pool.Name != "a-dc1" {
+		t.Errorf("poolForServer with datacenter pool = %v, %v; want a-dc1", pool, err)
+	}
+	if _, err := poolForServer(pools, server, "missing"); err == nil {
+		t.Error("poolForServer with a missing datacenter pool succeeded")
+	}
+}
+
+func TestServerPodCIDR(t *testing.T) {
+	server := &api.Server{Spec: api.ServerSpec{IPv4: "10.50.0.4"}}
+	if cidr, err := serverPodCIDR(server); err == nil {
+		t.Errorf("serverPodCIDR without allocation = %q, want an error", cidr)
+	}
+	server.Status.PodCIDR = "10.100.3.0/24"
+	if cidr, err := serverPodCIDR(server); err != nil || cidr != "10.100.3.0/24" {
+		t.Errorf("serverPodCIDR with allocation = %q, %v; want 10.100.3.0/24", cidr, err)
+	}
+}
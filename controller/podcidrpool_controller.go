@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// PodCIDRPoolReconciler keeps a PodCIDRPool's status current: allocations of deleted Servers
+// are released and the allocated and available counts are recomputed. Allocations themselves
+// are made by the Operation controller when it repaves a Server.
+type PodCIDRPoolReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=stargate.io,resources=podcidrpools,verbs=get;list;watch
+// +kubebuilder:rbac:groups=stargate.io,resources=podcidrpools/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=stargate.io,resources=servers,verbs=get;list;watch
+
+// Reconcile releases the pool's allocations for Servers that no longer exist
+func (r *PodCIDRPoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var pool api.PodCIDRPool
+	if err := r.Get(ctx, req.NamespacedName, &pool); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	allocator, err := ipamPool(&pool)
+	if err != nil {
+		logger.Error(err, "Invalid PodCIDRPool")
+		return ctrl.Result{}, nil
+	}
+
+	var servers api.ServerList
+	if err := r.List(ctx, &servers, client.InNamespace(pool.Namespace)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("list servers: %w", err)
+	}
+	exists := make(map[string]bool, len(servers.Items))
+	for _, server := range servers.Items {
+		exists[server.Name] = true
+	}
+
+	original := pool.Status.DeepCopy()
+	for _, a := range original.Allocations {
+		if !exists[a.Server] {
+			logger.Info("Releasing pod CIDR of deleted server", "server", a.Server, "podCIDR", a.CIDR)
+			unassignPodCIDR(&pool, a.Server)
+		}
+	}
+	setPoolCounts(&pool, allocator)
+
+	if equality.Semantic.DeepEqual(*original, pool.Status) {
+		return ctrl.Result{}, nil
+	}
+	if err := r.Status().Update(ctx, &pool); err != nil {
+		return ctrl.Result{}, fmt.Errorf("update PodCIDRPool status: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// poolsForServer maps a deleted Server to the PodCIDRPools in its namespace
+func (r *PodCIDRPoolReconciler) poolsForServer(ctx context.Context, obj client.Object) []reconcile.Request {
+	var pools api.PodCIDRPoolList
+	if err := r.List(ctx, &pools, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list PodCIDRPools for server", "server", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(pools.Items))
+	for i := range pools.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&pools.Items[i])})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *PodCIDRPoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	serverDeleted := predicate.Funcs{
+		CreateFunc:  func(event.CreateEvent) bool { return false },
+		UpdateFunc:  func(event.UpdateEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&api.PodCIDRPool{}).
+		Watches(&api.Server{}, handler.EnqueueRequestsFromMapFunc(r.poolsForServer), builder.WithPredicates(serverDeleted)).
+		Complete(r)
+}
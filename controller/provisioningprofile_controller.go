@@ -0,0 +1,209 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+const (
+	// profileSecretRefsField is the field index used to find the profiles that reference a Secret
+	profileSecretRefsField = "spec.secretRefs"
+
+	// minKubernetesMinor is the oldest Kubernetes minor version the bootstrap scripts support
+	minKubernetesMinor = 30
+
+	// profileWaitInterval is how often an Operation re-checks a ProvisioningProfile that is not ready
+	profileWaitInterval = 30 * time.Second
+)
+
+// kubernetesVersionPattern matches versions like "1.34" or "1.34.1"
+var kubernetesVersionPattern = regexp.MustCompile(`^([0-9]+)\.([0-9]+)(\.[0-9]+)?$`)
+
+// ProvisioningProfileReconciler validates ProvisioningProfiles and records the result in
+// status.ready and the ProfileValid and Ready conditions
+type ProvisioningProfileReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=stargate.io,resources=provisioningprofiles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=stargate.io,resources=provisioningprofiles/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+
+// Reconcile validates a ProvisioningProfile and updates its status
+func (r *ProvisioningProfileReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var profile api.ProvisioningProfile
+	if err := r.Get(ctx, req.NamespacedName, &profile); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	validateErr, err := validateProfile(ctx, r.Client, &profile)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if validateErr != nil {
+		logger.Info("ProvisioningProfile is not ready", "reason", validateErr.Error())
+	}
+	return ctrl.Result{}, updateProfileValidity(ctx, r.Client, &profile, validateErr)
+}
+
+// validateProfile checks that a profile is usable: its Kubernetes version is supported, the
+// Secrets it references exist with the expected keys, and its custom bootstrap script parses.
+// It returns the problems found as validateErr, and err only for errors worth retrying.
+func validateProfile(ctx context.Context, c client.Client, profile *api.ProvisioningProfile) (validateErr error, err error) {
+	var problems []string
+
+	if msg := checkKubernetesVersion(profile.Spec.KubernetesVersion); msg != "" {
+		problems = append(problems, msg)
+	}
+
+	secretKeys := []struct {
+		name, key, field string
+	}{
+		{profile.Spec.SSHCredentialsSecretRef, "privateKey", "sshCredentialsSecretRef"},
+		{profile.Spec.TailscaleAuthKeySecretRef, "authKey", "tailscaleAuthKeySecretRef"},
+	}
+	for _, ref := range secretKeys {
+		if ref.name == "" {
+			continue
+		}
+		var secret corev1.Secret
+		if err := c.Get(ctx, client.ObjectKey{Namespace: profile.Namespace, Name: ref.name}, &secret); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("get secret %s: %w", ref.name, err)
+			}
+			problems = append(problems, fmt.Sprintf("%s: secret %s not found", ref.field, ref.name))
+			continue
+		}
+		if len(secret.Data[ref.key]) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: secret %s has no %q key", ref.field, ref.name, ref.key))
+		}
+	}
+
+	if profile.Spec.CustomBootstrapScript != "" {
+		if msg := checkBootstrapScript(ctx, profile.Spec.CustomBootstrapScript); msg != "" {
+			problems = append(problems, msg)
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil, nil
+	}
+	return fmt.Errorf("%s", strings.Join(problems, "; ")), nil
+}
+
+// checkKubernetesVersion returns a problem description if the version is not supported.
+// An empty version is allowed; the controllers fall back to their default.
+func checkKubernetesVersion(version string) string {
+	if version == "" {
+		return ""
+	}
+	m := kubernetesVersionPattern.FindStringSubmatch(version)
+	if m == nil {
+		return fmt.Sprintf("kubernetesVersion %q must be a version like 1.34 or 1.34.1", version)
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	if major != 1 || minor < minKubernetesMinor {
+		return fmt.Sprintf("kubernetesVersion %q is not supported (minimum 1.%d)", version, minKubernetesMinor)
+	}
+	return ""
+}
+
+// checkBootstrapScript returns a problem description if the script does not parse with bash -n
+func checkBootstrapScript(ctx context.Context, script string) string {
+	cmd := exec.CommandContext(ctx, "bash", "-n")
+	cmd.Stdin = strings.NewReader(script)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Sprintf("customBootstrapScript does not parse: %s", strings.TrimSpace(string(output)))
+	}
+	return ""
+}
+
+// profileReadiness reports whether a ProvisioningProfile has been validated at its current
+// generation and found usable. If not, the message explains what the Operation is waiting for.
+func profileReadiness(profile *api.ProvisioningProfile) (bool, string) {
+	cond := meta.FindStatusCondition(profile.Status.Conditions, api.ConditionProfileValid)
+	switch {
+	case cond == nil || cond.ObservedGeneration != profile.Generation:
+		return false, fmt.Sprintf("Waiting for ProvisioningProfile %s to be validated", profile.Name)
+	case cond.Status != metav1.ConditionTrue:
+		return false, fmt.Sprintf("Waiting for ProvisioningProfile %s to become ready: %s", profile.Name, cond.Message)
+	}
+	return true, ""
+}
+
+// waitForProfile records why an Operation is waiting on its ProvisioningProfile and checks again later
+func waitForProfile(ctx context.Context, c client.Client, operation *api.Operation, message string) (ctrl.Result, error) {
+	if operation.Status.Message != message {
+		log.FromContext(ctx).Info("Operation waiting for ProvisioningProfile", "provisioningProfile", operation.Spec.ProvisioningProfileRef.Name)
+		operation.Status.Message = message
+		if err := c.Status().Update(ctx, operation); err != nil {
+			return ctrl.Result{}, fmt.Errorf("update Operation status: %w", err)
+		}
+	}
+	return ctrl.Result{RequeueAfter: profileWaitInterval}, nil
+}
+
+// indexProfileSecretRefs is the indexer function for profileSecretRefsField
+func indexProfileSecretRefs(obj client.Object) []string {
+	profile, ok := obj.(*api.ProvisioningProfile)
+	if !ok {
+		return nil
+	}
+	var refs []string
+	for _, ref := range []string{profile.Spec.SSHCredentialsSecretRef, profile.Spec.TailscaleAuthKeySecretRef} {
+		if ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// profilesForSecret maps a Secret to the ProvisioningProfiles that reference it
+func (r *ProvisioningProfileReconciler) profilesForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	var profiles api.ProvisioningProfileList
+	if err := r.List(ctx, &profiles, client.InNamespace(obj.GetNamespace()), client.MatchingFields{profileSecretRefsField: obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list ProvisioningProfiles for secret", "secret", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(profiles.Items))
+	for _, profile := range profiles.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&profile)})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *ProvisioningProfileReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Index profiles by the Secrets they reference so Secret changes re-validate them
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &api.ProvisioningProfile{}, profileSecretRefsField, indexProfileSecretRefs); err != nil {
+		return fmt.Errorf("index provisioning profiles by secret: %w", err)
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&api.ProvisioningProfile{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.profilesForSecret)).
+		Complete(r)
+}
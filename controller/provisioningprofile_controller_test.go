@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+func TestValidateProfile(t *testing.T) {
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "ssh", Namespace: "dc"},
+			Data:       map[string][]byte{"privateKey": []byte("key")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "tailscale-empty", Namespace: "dc"},
+			Data:       map[string][]byte{"key": []byte("tskey")},
+		},
+	).Build()
+
+	tests := []struct {
+		name     string
+		spec     api.ProvisioningProfileSpec
+		problems []string
+	}{
+		{
+			name: "valid",
+			spec: api.ProvisioningProfileSpec{KubernetesVersion: "1.34", SSHCredentialsSecretRef: "ssh", CustomBootstrapScript: "#!/bin/bash\necho ok\n"},
+		},
+		{
+			name:     "unsupported version",
+			spec:     api.ProvisioningProfileSpec{KubernetesVersion: "1.20"},
+			problems: []string{"not supported"},
+		},
+		{
+			name:     "missing secret and key",
+			spec:     api.ProvisioningProfileSpec{KubernetesVersion: "1.34", SSHCredentialsSecretRef: "missing", TailscaleAuthKeySecretRef: "tailscale-empty"},
+			problems: []string{"secret missing not found", `has no "authKey" key`},
+		},
+		{
+			name:     "bad script",
+			spec:     api.ProvisioningProfileSpec{KubernetesVersion: "1.34", CustomBootstrapScript: "if true; then echo\n"},
+			problems: []string{"customBootstrapScript does not parse"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := &api.ProvisioningProfile{ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: "dc"}, Spec: tt.spec}
+			validateErr, err := validateProfile(context.Background(), c, profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tt.problems) == 0 {
+				if validateErr != nil {
+					t.Errorf("expected profile to be valid, got %v", validateErr)
+				}
+				return
+			}
+			if validateErr == nil {
+				t.Fatalf("expected problems %v, got none", tt.problems)
+			}
+			for _, problem := range tt.problems {
+				if !strings.Contains(validateErr.Error(), problem) {
+					t.Errorf("expected %q in %q", problem, validateErr.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestProfileReadiness(t *testing.T) {
+	profile := &api.ProvisioningProfile{ObjectMeta: metav1.ObjectMeta{Name: "p", Generation: 2}}
+	if ready, _ := profileReadiness(profile); ready {
+		t.Error("expected an unvalidated profile not to be ready")
+	}
+
+	setCondition(&profile.Status.Conditions, 1, api.ConditionProfileValid, metav1.ConditionTrue, api.ReasonValid, "Profile is valid")
+	if ready, _ := profileReadiness(profile); ready {
+		t.Error("expected a profile validated at an older generation not to be ready")
+	}
+
+	setCondition(&profile.Status.Conditions, 2, api.ConditionProfileValid, metav1.ConditionFalse, api.ReasonInvalid, "secret ssh not found")
+	if ready, message := profileReadiness(profile); ready || !strings.Contains(message, "secret ssh not found") {
+		t.Errorf("expected an invalid profile not to be ready, got %v %q", ready, message)
+	}
+
+	setCondition(&profile.Status.Conditions, 2, api.ConditionProfileValid, metav1.ConditionTrue, api.ReasonValid, "Profile is valid")
+	if ready, _ := profileReadiness(profile); !ready {
+		t.Error("expected a valid profile to be ready")
+	}
+}
@@ -15,8 +15,10 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	api "github.com/vpatelsj/stargate/api/v1alpha1"
@@ -25,7 +27,8 @@ import (
 // QemuOperationReconciler reconciles Operation resources for QEMU VMs
 type QemuOperationReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 
 	// Control plane configuration
 	KindContainerName       string // Name of the Kind control plane container
@@ -56,9 +59,13 @@ func (r *QemuOperationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	// Skip if operation is already completed
-	if operation.Status.Phase == api.OperationPhaseSucceeded || operation.Status.Phase == api.OperationPhaseFailed {
-		return ctrl.Result{}, nil
+	// Nothing to do once finished; just release the Server's lock and the finalizer so
+	// queued Operations can start and this one can be deleted
+	if isOperationFinished(&operation) {
+		if err := releaseServerLock(ctx, r.Client, &operation); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, removeOperationFinalizer(ctx, r.Client, &operation)
 	}
 
 	// Get the referenced Server
@@ -78,20 +85,54 @@ func (r *QemuOperationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, nil
 	}
 
-	// Get the referenced ProvisioningProfile
-	var profile api.ProvisioningProfile
-	profileKey := client.ObjectKey{
-		Namespace: operation.Namespace,
-		Name:      operation.Spec.ProvisioningProfileRef.Name,
+	// Make the Operation a dependent of its Server so deleting the Server cascades
+	if err := ensureServerOwner(ctx, r.Client, r.Scheme, &operation, &server); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Handle deletion, cancellation and deadlines before starting any more work
+	if aborted, err := handleOperationLifecycle(ctx, r.Client, r.Recorder, "qemu", &operation, &server); aborted || err != nil {
+		return ctrl.Result{}, err
 	}
-	if err := r.Get(ctx, profileKey, &profile); err != nil {
-		logger.Error(err, "Failed to get ProvisioningProfile", "profileRef", operation.Spec.ProvisioningProfileRef.Name)
-		return r.updateOperationStatus(ctx, &operation, api.OperationPhaseFailed, fmt.Sprintf("ProvisioningProfile not found: %s", operation.Spec.ProvisioningProfileRef.Name))
+
+	// Only repave and reboot are implemented for qemu servers
+	isReboot := operation.Spec.Operation == api.OperationTypeReboot
+	if operation.Spec.Operation != "" && operation.Spec.Operation != api.OperationTypeRepave && !isReboot {
+		return r.updateOperationStatus(ctx, &operation, api.OperationPhaseFailed, fmt.Sprintf("Operation %q is not supported for qemu servers", operation.Spec.Operation))
+	}
+
+	// Get the referenced ProvisioningProfile. A reboot falls back to the controller
+	// defaults when it is omitted.
+	var profile api.ProvisioningProfile
+	if operation.Spec.ProvisioningProfileRef.Name != "" || !isReboot {
+		profileKey := client.ObjectKey{
+			Namespace: operation.Namespace,
+			Name:      operation.Spec.ProvisioningProfileRef.Name,
+		}
+		if err := r.Get(ctx, profileKey, &profile); err != nil {
+			logger.Error(err, "Failed to get ProvisioningProfile", "profileRef", operation.Spec.ProvisioningProfileRef.Name)
+			return r.updateOperationStatus(ctx, &operation, api.OperationPhaseFailed, fmt.Sprintf("ProvisioningProfile not found: %s", operation.Spec.ProvisioningProfileRef.Name))
+		}
 	}
 
 	// Handle based on current phase
 	switch operation.Status.Phase {
 	case api.OperationPhasePending, "":
+		// Wait for the ProvisioningProfile to be validated rather than failing mid-bootstrap.
+		// Operations already running carry on if it turns not ready.
+		if profile.Name != "" {
+			if ready, message := profileReadiness(&profile); !ready {
+				return waitForProfile(ctx, r.Client, &operation, message)
+			}
+		}
+		// Only one Operation runs on a Server at a time; the rest queue in priority order
+		locked, message, err := acquireServerLock(ctx, r.Client, &operation, &server)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !locked {
+			return waitForServerLock(ctx, r.Client, r.Recorder, &operation, message)
+		}
 		return r.handlePending(ctx, &operation, &server, &profile)
 	case api.OperationPhaseRunning:
 		return r.handleRunning(ctx, &operation, &server, &profile)
@@ -100,18 +141,19 @@ func (r *QemuOperationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	}
 }
 
-// handlePending initiates the repave operation for QEMU VM
+// handlePending initiates the repave or reboot operation for QEMU VM
 func (r *QemuOperationReconciler) handlePending(ctx context.Context, operation *api.Operation, server *api.Server, profile *api.ProvisioningProfile) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
-	logger.Info("Initiating QEMU VM bootstrap via SSH", "server", server.Name, "ipv4", server.Spec.IPv4, "k8sVersion", profile.Spec.KubernetesVersion)
 
-	// Resolve bootstrap configuration
-	cfg, cleanup, err := r.resolveBootstrapConfig(ctx, operation.Namespace, profile)
-	if err != nil {
-		logger.Error(err, "Failed to resolve bootstrap config")
-		return r.updateOperationStatus(ctx, operation, api.OperationPhaseFailed, fmt.Sprintf("Failed to resolve config: %v", err))
+	name, state, firstStep := "Repave", "provisioning", api.OperationStepPreflight
+	serverMessage := fmt.Sprintf("Bootstrap initiated by operation %s", operation.Name)
+	if operation.Spec.Operation == api.OperationTypeReboot {
+		name, state, firstStep = "Reboot", "rebooting", api.OperationStepCordon
+		serverMessage = fmt.Sprintf("Reboot initiated by operation %s", operation.Name)
+		logger.Info("Initiating QEMU VM reboot", "server", server.Name, "ipv4", server.Spec.IPv4)
+	} else {
+		logger.Info("Initiating QEMU VM bootstrap via SSH", "server", server.Name, "ipv4", server.Spec.IPv4, "k8sVersion", profile.Spec.KubernetesVersion)
 	}
-	defer cleanup()
 
 	// Re-fetch server to avoid conflicts
 	var freshServer api.Server
@@ -119,14 +161,17 @@ func (r *QemuOperationReconciler) handlePending(ctx context.Context, operation *
 		return ctrl.Result{RequeueAfter: 2 * time.Second}, err
 	}
 
-	// Update server status to provisioning
-	freshServer.Status.State = "provisioning"
-	freshServer.Status.Message = fmt.Sprintf("Bootstrap initiated by operation %s", operation.Name)
+	// Update server status to provisioning (or rebooting)
+	previous := freshServer.Status.State
+	freshServer.Status.State = state
+	freshServer.Status.Message = serverMessage
 	freshServer.Status.LastUpdated = metav1.Now()
+	setServerReadyCondition(&freshServer)
 	if err := r.Status().Update(ctx, &freshServer); err != nil {
 		logger.Error(err, "Failed to update Server status")
 		return ctrl.Result{RequeueAfter: 2 * time.Second}, err
 	}
+	recordServerState(r.Recorder, &freshServer, previous)
 
 	// Re-fetch operation to avoid conflicts
 	var freshOperation api.Operation
@@ -134,57 +179,23 @@ func (r *QemuOperationReconciler) handlePending(ctx context.Context, operation *
 		return ctrl.Result{RequeueAfter: 2 * time.Second}, err
 	}
 
-	// Update operation status to running
-	freshOperation.Status.Phase = api.OperationPhaseRunning
+	// Update operation status to running at the first step
 	now := metav1.Now()
+	freshOperation.Status.Phase = api.OperationPhaseRunning
 	freshOperation.Status.StartTime = &now
-	freshOperation.Status.Message = "Bootstrap in progress"
+	freshOperation.Status.Attempts = 1
+	freshOperation.Status.Step = firstStep
+	freshOperation.Status.StepStartTime = &now
+	freshOperation.Status.Message = fmt.Sprintf("Step %s in progress", freshOperation.Status.Step)
+	setOperationPhaseCondition(&freshOperation)
 
 	if err := r.Status().Update(ctx, &freshOperation); err != nil {
 		logger.Error(err, "Failed to update Operation status")
 		return ctrl.Result{RequeueAfter: 2 * time.Second}, err
 	}
+	recordEvent(r.Recorder, &freshOperation, corev1.EventTypeNormal, api.EventReasonStarted, "%s of server %s started", name, server.Name)
 
-	// Perform SSH bootstrap
-	if err := r.bootstrapServer(ctx, server, profile, cfg); err != nil {
-		logger.Error(err, "Bootstrap failed", "server", server.Name)
-
-		// Re-fetch and update server status to error
-		if getErr := r.Get(ctx, client.ObjectKeyFromObject(server), &freshServer); getErr == nil {
-			freshServer.Status.State = "error"
-			freshServer.Status.Message = fmt.Sprintf("Bootstrap failed: %v", err)
-			freshServer.Status.LastUpdated = metav1.Now()
-			if updateErr := r.Status().Update(ctx, &freshServer); updateErr != nil {
-				logger.Error(updateErr, "Failed to update Server status to error")
-			}
-		}
-
-		// Re-fetch operation for final status update
-		if getErr := r.Get(ctx, client.ObjectKeyFromObject(operation), &freshOperation); getErr == nil {
-			return r.updateOperationStatus(ctx, &freshOperation, api.OperationPhaseFailed, fmt.Sprintf("Bootstrap failed: %v", err))
-		}
-		return ctrl.Result{RequeueAfter: 5 * time.Second}, err
-	}
-
-	// Bootstrap succeeded - re-fetch objects for final update
-	if err := r.Get(ctx, client.ObjectKeyFromObject(server), &freshServer); err != nil {
-		return ctrl.Result{RequeueAfter: 2 * time.Second}, err
-	}
-	logger.Info("Bootstrap succeeded", "server", server.Name)
-	freshServer.Status.State = "ready"
-	freshServer.Status.CurrentOS = fmt.Sprintf("k8s-%s", profile.Spec.KubernetesVersion)
-	freshServer.Status.AppliedProvisioningProfile = profile.Name
-	freshServer.Status.Message = fmt.Sprintf("Joined cluster successfully via operation %s", operation.Name)
-	freshServer.Status.LastUpdated = metav1.Now()
-	if err := r.Status().Update(ctx, &freshServer); err != nil {
-		logger.Error(err, "Failed to update Server status to ready")
-	}
-
-	// Re-fetch operation for final status update
-	if err := r.Get(ctx, client.ObjectKeyFromObject(operation), &freshOperation); err != nil {
-		return ctrl.Result{RequeueAfter: 2 * time.Second}, err
-	}
-	return r.updateOperationStatus(ctx, &freshOperation, api.OperationPhaseSucceeded, "Bootstrap completed successfully - node joined cluster")
+	return ctrl.Result{Requeue: true}, nil
 }
 
 // resolveBootstrapConfig resolves configuration from profile and secrets
@@ -256,9 +267,147 @@ func (r *QemuOperationReconciler) resolveBootstrapConfig(ctx context.Context, na
 	return cfg, cleanup, nil
 }
 
-// handleRunning handles already-running operations
+// handleRunning resumes a running operation at its persisted step. Each reconcile runs
+// a single step, so a restarted controller (or a new leader) picks up where the last one stopped.
 func (r *QemuOperationReconciler) handleRunning(ctx context.Context, operation *api.Operation, server *api.Server, profile *api.ProvisioningProfile) (ctrl.Result, error) {
-	return r.updateOperationStatus(ctx, operation, api.OperationPhaseFailed, "Operation was interrupted (controller restart?). Please create a new operation to retry.")
+	logger := log.FromContext(ctx)
+
+	// Wait out the backoff of a scheduled retry
+	if wait := retryWait(operation); wait > 0 {
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	// Resolve bootstrap configuration
+	cfg, cleanup, err := r.resolveBootstrapConfig(ctx, operation.Namespace, profile)
+	if err != nil {
+		logger.Error(err, "Failed to resolve bootstrap config")
+		return r.updateOperationStatus(ctx, operation, api.OperationPhaseFailed, fmt.Sprintf("Failed to resolve config: %v", err))
+	}
+	defer cleanup()
+
+	name, steps := "Bootstrap", r.repaveSteps(operation, server, profile, cfg)
+	isReboot := operation.Spec.Operation == api.OperationTypeReboot
+	if isReboot {
+		name, steps = "Reboot", rebootSteps(r.Client, r.Recorder, operation, server, r.sshRunner(server, cfg))
+	}
+
+	// Run the step under a context that is cancelled if the operation is cancelled,
+	// deleted or runs past its deadline
+	stepCtx, stop := stepContext(ctx, r.Client, operation)
+	finished, requeueAfter, err := advanceOperation(stepCtx, r.Client, r.Recorder, operation, steps)
+	stop()
+	if err != nil {
+		if reason := stepAbortCause(stepCtx); reason != nil {
+			return ctrl.Result{}, abortOperation(ctx, r.Client, r.Recorder, "qemu", operation, server, reason)
+		}
+		observeBootstrapFailure(operation, "qemu")
+		if delay, ok := scheduleRetry(operation, err); ok {
+			logger.Error(err, name+" step failed, will retry", "server", server.Name,
+				"class", operation.Status.LastFailure.Class, "attempt", operation.Status.Attempts, "retryIn", delay)
+			if err := r.Status().Update(ctx, operation); err != nil {
+				return ctrl.Result{RequeueAfter: 2 * time.Second}, err
+			}
+			recordRetry(r.Recorder, operation, delay)
+			return ctrl.Result{RequeueAfter: delay}, nil
+		}
+
+		logger.Error(err, name+" failed", "server", server.Name)
+
+		message := fmt.Sprintf("%s failed: %v", name, err)
+		if err := recordServerOutcome(ctx, r.Client, r.Recorder, operation, server, "error", message, nil); err != nil {
+			return ctrl.Result{}, err
+		}
+		return r.updateOperationStatus(ctx, operation, api.OperationPhaseFailed, fmt.Sprintf("%s failed: %v", name, err))
+	}
+	if !finished {
+		if requeueAfter > 0 {
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	logger.Info(name+" succeeded", "server", server.Name)
+	message := fmt.Sprintf("Reboot completed by operation %s", operation.Name)
+	var onSuccess func(server *api.Server)
+	if !isReboot {
+		message = fmt.Sprintf("Joined cluster successfully via operation %s", operation.Name)
+		onSuccess = func(server *api.Server) {
+			server.Status.CurrentOS = fmt.Sprintf("k8s-%s", profile.Spec.KubernetesVersion)
+			server.Status.AppliedProvisioningProfile = profile.Name
+		}
+	}
+	if err := recordServerOutcome(ctx, r.Client, r.Recorder, operation, server, "ready", message, onSuccess); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if isReboot {
+		return r.updateOperationStatus(ctx, operation, api.OperationPhaseSucceeded, "Reboot completed successfully")
+	}
+	return r.updateOperationStatus(ctx, operation, api.OperationPhaseSucceeded, "Bootstrap completed successfully - node joined cluster")
+}
+
+// repaveSteps returns the QEMU repave workflow. QEMU VMs sit on a local bridge, so unlike
+// the azure flow there is no routing step.
+func (r *QemuOperationReconciler) repaveSteps(operation *api.Operation, server *api.Server, profile *api.ProvisioningProfile, cfg *qemuBootstrapConfig) []operationStep {
+	return []operationStep{
+		{
+			name:      api.OperationStepPreflight,
+			condition: api.ConditionSSHReachable,
+			run: func(ctx context.Context) (bool, error) {
+				if server.Spec.IPv4 == "" {
+					return false, fmt.Errorf("server %s has no IPv4 address", server.Name)
+				}
+				if err := r.runRemoteBootstrap(ctx, server.Spec.IPv4, server.Spec.RouterIP, "true", cfg); err != nil {
+					return false, err
+				}
+				return true, nil
+			},
+		},
+		cordonStep(r.Client, r.Recorder, operation, server.Name),
+		drainStep(r.Client, r.Recorder, operation, server.Name),
+		{
+			name: api.OperationStepNodeCleanup,
+			run: func(ctx context.Context) (bool, error) {
+				done, err := r.deleteNodeIfExists(ctx, operation, server.Name)
+				if err != nil {
+					// Bootstrapping while the old Node is still Terminating would collide with it
+					if failureClassOf(err) == api.FailureClassTimeout {
+						return false, err
+					}
+					log.FromContext(ctx).Error(err, "Failed to delete existing node (continuing anyway)", "node", server.Name)
+					return true, nil
+				}
+				return done, nil
+			},
+		},
+		{
+			name:      api.OperationStepBootstrap,
+			condition: api.ConditionBootstrapped,
+			run: func(ctx context.Context) (bool, error) {
+				recordBootstrapStarted(r.Recorder, operation, server)
+				err := r.bootstrapServer(ctx, server, profile, cfg)
+				recordBootstrapResult(r.Recorder, operation, server, err)
+				return err == nil, err
+			},
+		},
+		{
+			name:      api.OperationStepVerify,
+			condition: api.ConditionNodeRegistered,
+			run: func(ctx context.Context) (bool, error) {
+				node, err := getNode(ctx, r.Client, server.Name)
+				if err != nil {
+					return false, err
+				}
+				if node != nil && isNodeReady(node) {
+					return true, nil
+				}
+				if stepElapsed(operation) > nodeJoinTimeout {
+					return false, timeoutError("node %s did not become Ready within %s", server.Name, nodeJoinTimeout)
+				}
+				return false, nil
+			},
+		},
+	}
 }
 
 // bootstrapServer runs the bootstrap script on the QEMU VM via SSH
@@ -272,11 +421,6 @@ func (r *QemuOperationReconciler) bootstrapServer(ctx context.Context, server *a
 	// Get router IP for SSH proxy (empty for router itself)
 	routerIP := server.Spec.RouterIP
 
-	// Delete existing K8s Node object if it exists (for repave)
-	if err := r.deleteNodeIfExists(ctx, server.Name); err != nil {
-		log.FromContext(ctx).Error(err, "Failed to delete existing node (continuing anyway)", "node", server.Name)
-	}
-
 	// Get control plane Tailscale IP (via Kind control-plane container, same as azure flow)
 	controlPlaneIP := r.ControlPlaneTailscaleIP
 	if controlPlaneIP == "" {
@@ -294,7 +438,7 @@ func (r *QemuOperationReconciler) bootstrapServer(ctx context.Context, server *a
 	}
 
 	// Build the bootstrap script with the node's actual IP
-	script := r.buildBootstrapScript(controlPlaneIP, joinCmd, cfg.kubernetesVersion, target)
+	script := r.buildBootstrapScript(controlPlaneIP, joinCmd, cfg.kubernetesVersion, target, stargateNodeLabels(server))
 
 	// Run the script via SSH (via router proxy if routerIP is set)
 	return r.runRemoteBootstrap(ctx, target, routerIP, script, cfg)
@@ -359,7 +503,7 @@ func (r *QemuOperationReconciler) generateKubeadmJoinCommand(controlPlaneTailsca
 
 // buildBootstrapScript creates the bash script for QEMU VM bootstrap
 // Workers behind a router don't have Tailscale - they use their local IP for node registration
-func (r *QemuOperationReconciler) buildBootstrapScript(controlPlaneTailscaleIP, joinCmd, kubernetesVersion, nodeIP string) string {
+func (r *QemuOperationReconciler) buildBootstrapScript(controlPlaneTailscaleIP, joinCmd, kubernetesVersion, nodeIP, nodeLabels string) string {
 	controlPlaneHostname := r.ControlPlaneHostname
 	if controlPlaneHostname == "" {
 		// Try to get hostname from Kind container
@@ -390,6 +534,7 @@ set -ex
 
 KUBERNETES_VERSION="%s"
 NODE_IP="%s"
+NODE_LABELS="%s"
 
 # Add control plane hostname to /etc/hosts
 echo '%s %s' >> /etc/hosts
@@ -417,6 +562,8 @@ nodeRegistration:
       value: /
     - name: node-ip
       value: "$NODE_IP"
+    - name: node-labels
+      value: "$NODE_LABELS"
 ---
 apiVersion: kubelet.config.k8s.io/v1beta1
 kind: KubeletConfiguration
@@ -488,6 +635,7 @@ echo "Bootstrap complete! Node joined cluster."
 `,
 		k8sRepoVersion,
 		nodeIP,
+		nodeLabels,
 		controlPlaneTailscaleIP,
 		controlPlaneHostname,
 		joinCmd,
@@ -497,6 +645,14 @@ echo "Bootstrap complete! Node joined cluster."
 
 // runRemoteBootstrap executes the bootstrap script on the QEMU VM via SSH
 func (r *QemuOperationReconciler) runRemoteBootstrap(ctx context.Context, host, routerIP, script string, cfg *qemuBootstrapConfig) error {
+	if output, err := r.runRemoteScript(ctx, host, routerIP, script, cfg); err != nil {
+		return fmt.Errorf("ssh bootstrap failed: %w\nOutput: %s", err, output)
+	}
+	return nil
+}
+
+// runRemoteScript executes a script as root on the QEMU VM via SSH and returns its combined output
+func (r *QemuOperationReconciler) runRemoteScript(ctx context.Context, host, routerIP, script string, cfg *qemuBootstrapConfig) (string, error) {
 	sshArgs := []string{
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
@@ -524,11 +680,15 @@ func (r *QemuOperationReconciler) runRemoteBootstrap(ctx context.Context, host,
 	cmd.Stdout = &buf
 	cmd.Stderr = &buf
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("ssh bootstrap failed: %w\nOutput: %s", err, buf.String())
-	}
+	err := cmd.Run()
+	return buf.String(), err
+}
 
-	return nil
+// sshRunner returns a remoteRunner that reaches the QEMU VM over SSH with cfg
+func (r *QemuOperationReconciler) sshRunner(server *api.Server, cfg *qemuBootstrapConfig) remoteRunner {
+	return func(ctx context.Context, script string) (string, error) {
+		return r.runRemoteScript(ctx, server.Spec.IPv4, server.Spec.RouterIP, script, cfg)
+	}
 }
 
 // updateOperationStatus updates the operation status
@@ -538,45 +698,62 @@ func (r *QemuOperationReconciler) updateOperationStatus(ctx context.Context, ope
 	operation.Status.Phase = phase
 	operation.Status.Message = message
 
-	if phase == api.OperationPhaseSucceeded || phase == api.OperationPhaseFailed {
+	if phase == api.OperationPhaseSucceeded || phase == api.OperationPhaseFailed || phase == api.OperationPhaseCancelled {
 		now := metav1.Now()
 		operation.Status.CompletionTime = &now
 	}
+	setOperationPhaseCondition(operation)
 
 	if err := r.Status().Update(ctx, operation); err != nil {
 		logger.Error(err, "Failed to update Operation status")
 		return ctrl.Result{RequeueAfter: 5 * time.Second}, err
 	}
+	recordOperationResult(r.Recorder, operation)
+	if isOperationFinished(operation) {
+		observeOperationDuration(operation, "qemu")
+	}
 
 	return ctrl.Result{}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager
 func (r *QemuOperationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Index pods by node so drains can list the pods on a node from the cache
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.Pod{}, podNodeNameField, indexPodNodeName); err != nil {
+		return fmt.Errorf("index pods by node: %w", err)
+	}
+
+	// Index operations by server so queued Operations can be found when a Server's lock is released
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &api.Operation{}, operationServerRefField, indexOperationServerRef); err != nil {
+		return fmt.Errorf("index operations by server: %w", err)
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&api.Operation{}).
+		Watches(&api.Server{}, handler.EnqueueRequestsFromMapFunc(queuedOperationsForServer(r.Client))).
 		Complete(r)
 }
 
-// deleteNodeIfExists removes a Kubernetes Node object if it exists (for repave operations)
-func (r *QemuOperationReconciler) deleteNodeIfExists(ctx context.Context, nodeName string) error {
+// deleteNodeIfExists removes a Kubernetes Node object if it exists (for repave operations).
+// It returns done=false while the Node is still Terminating so the step is polled until it is gone.
+func (r *QemuOperationReconciler) deleteNodeIfExists(ctx context.Context, operation *api.Operation, nodeName string) (done bool, err error) {
 	logger := log.FromContext(ctx)
 
-	node := &corev1.Node{}
-	if err := r.Get(ctx, client.ObjectKey{Name: nodeName}, node); err != nil {
-		if client.IgnoreNotFound(err) != nil {
-			return err
-		}
+	node, err := getNode(ctx, r.Client, nodeName)
+	if err != nil || node == nil {
 		// Node doesn't exist, nothing to delete
-		return nil
+		return err == nil, err
 	}
 
-	logger.Info("Deleting existing node for repave", "node", nodeName)
-	if err := r.Delete(ctx, node); err != nil {
-		return fmt.Errorf("delete node %s: %w", nodeName, err)
+	if node.DeletionTimestamp.IsZero() {
+		logger.Info("Deleting existing node for repave", "node", nodeName)
+		if err := r.Delete(ctx, node); err != nil {
+			return false, fmt.Errorf("delete node %s: %w", nodeName, err)
+		}
+		recordEvent(r.Recorder, node, corev1.EventTypeNormal, api.EventReasonNodeDeleted, "Deleted stale Node before repave")
 	}
 
-	// Wait briefly for node deletion to propagate
-	time.Sleep(2 * time.Second)
-	return nil
+	// Finalizers such as route cleanup keep the Node Terminating for a while
+	pending, err := nodeDeletionPending(operation, node)
+	return !pending && err == nil, err
 }
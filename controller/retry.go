@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// Defaults for fields a RetryPolicy leaves unset
+const (
+	defaultMaxAttempts        = 3
+	defaultBackoffBaseSeconds = 30
+	defaultBackoffCapSeconds  = 600
+)
+
+// defaultRetryOn are the failure classes retried when a RetryPolicy doesn't list any.
+// Bootstrap failures are excluded since a script that fails usually fails the same way again.
+var defaultRetryOn = []api.FailureClass{
+	api.FailureClassSSH,
+	api.FailureClassTailscale,
+	api.FailureClassBMC,
+	api.FailureClassTimeout,
+}
+
+// proxyFailureMarkers are ssh error messages that indicate the ProxyCommand hop through
+// the router's Tailscale IP failed, rather than the connection to the server itself
+var proxyFailureMarkers = []string{
+	"kex_exchange_identification",
+	"Connection closed by UNKNOWN port 65535",
+}
+
+// stepError is a step failure tagged with the class used for retry decisions
+type stepError struct {
+	class api.FailureClass
+	err   error
+}
+
+func (e *stepError) Error() string { return e.err.Error() }
+func (e *stepError) Unwrap() error { return e.err }
+
+// classify tags err with a failure class. It returns nil if err is nil.
+func classify(class api.FailureClass, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &stepError{class: class, err: err}
+}
+
+// timeoutError returns a Timeout-class error for a step that ran out of time
+func timeoutError(format string, args ...interface{}) error {
+	return classify(api.FailureClassTimeout, fmt.Errorf(format, args...))
+}
+
+// failureClassOf determines the failure class of an error returned by a step.
+// Errors not explicitly classified are inferred from how the ssh binary exited.
+func failureClassOf(err error) api.FailureClass {
+	var se *stepError
+	if errors.As(err, &se) {
+		return se.class
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return api.FailureClassTimeout
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// ssh exits 255 when it couldn't connect; any other status is the remote script's
+		if exitErr.ExitCode() != 255 {
+			return api.FailureClassBootstrap
+		}
+		msg := err.Error()
+		for _, marker := range proxyFailureMarkers {
+			if strings.Contains(msg, marker) {
+				return api.FailureClassTailscale
+			}
+		}
+		return api.FailureClassSSH
+	}
+
+	return api.FailureClassUnknown
+}
+
+// retryBackoff returns the delay before the retry following the given number of failed
+// attempts: the base delay doubled for each earlier failure, capped
+func retryBackoff(policy *api.RetryPolicy, failures int32) time.Duration {
+	base := time.Duration(defaultBackoffBaseSeconds) * time.Second
+	if policy.BackoffBaseSeconds > 0 {
+		base = time.Duration(policy.BackoffBaseSeconds) * time.Second
+	}
+	limit := time.Duration(defaultBackoffCapSeconds) * time.Second
+	if policy.BackoffCapSeconds > 0 {
+		limit = time.Duration(policy.BackoffCapSeconds) * time.Second
+	}
+
+	delay := base
+	for i := int32(1); i < failures && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	return delay
+}
+
+// isRetryable returns true if the policy retries failures of the given class
+func isRetryable(policy *api.RetryPolicy, class api.FailureClass) bool {
+	retryOn := policy.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = defaultRetryOn
+	}
+	for _, c := range retryOn {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleRetry records a failed attempt on the operation and, if its retry policy allows
+// another attempt, schedules a retry of the current step. It returns the delay until the
+// retry, or false if the failure is final. The caller must persist the status.
+func scheduleRetry(operation *api.Operation, err error) (time.Duration, bool) {
+	class := failureClassOf(err)
+	now := metav1.Now()
+	operation.Status.LastFailure = &api.OperationFailure{
+		Class:   class,
+		Step:    operation.Status.Step,
+		Message: err.Error(),
+		Time:    now,
+	}
+
+	policy := operation.Spec.RetryPolicy
+	if policy == nil || !isRetryable(policy, class) {
+		return 0, false
+	}
+
+	attempts := operation.Status.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	if attempts >= maxAttempts {
+		return 0, false
+	}
+
+	delay := retryBackoff(policy, attempts)
+	next := metav1.NewTime(now.Add(delay))
+	operation.Status.Attempts = attempts + 1
+	operation.Status.NextRetryTime = &next
+	// Restart the step's clock at the retry so step timeouts apply per attempt
+	operation.Status.StepStartTime = &next
+	operation.Status.Message = fmt.Sprintf("Attempt %d/%d failed (%s), retrying step %s in %s: %v",
+		attempts, maxAttempts, class, operation.Status.Step, delay, err)
+
+	return delay, true
+}
+
+// retryWait returns how long until a scheduled retry is due, clearing the schedule once it is
+func retryWait(operation *api.Operation) time.Duration {
+	if operation.Status.NextRetryTime == nil {
+		return 0
+	}
+	if wait := time.Until(operation.Status.NextRetryTime.Time); wait > 0 {
+		return wait
+	}
+	operation.Status.NextRetryTime = nil
+	return 0
+}
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+	"time"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	policy := &api.RetryPolicy{BackoffBaseSeconds: 10, BackoffCapSeconds: 60}
+
+	tests := []struct {
+		failures int32
+		expected time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, 60 * time.Second},
+		{10, 60 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := retryBackoff(policy, tt.failures); got != tt.expected {
+			t.Errorf("retryBackoff(%d) = %s, want %s", tt.failures, got, tt.expected)
+		}
+	}
+
+	if got := retryBackoff(&api.RetryPolicy{}, 1); got != defaultBackoffBaseSeconds*time.Second {
+		t.Errorf("expected default base delay, got %s", got)
+	}
+}
+
+func TestFailureClassOf(t *testing.T) {
+	exitErr := func(code int) error {
+		err := exec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+		if err == nil {
+			t.Fatalf("expected exit status %d", code)
+		}
+		return err
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected api.FailureClass
+	}{
+		{"classified", classify(api.FailureClassBMC, errors.New("redfish error 500")), api.FailureClassBMC},
+		{"wrapped classified", fmt.Errorf("step Reboot: %w", timeoutError("host did not come back")), api.FailureClassTimeout},
+		{"context deadline", fmt.Errorf("ssh: %w", context.DeadlineExceeded), api.FailureClassTimeout},
+		{"ssh connect failure", fmt.Errorf("ssh bootstrap failed: %w\nOutput: connection refused", exitErr(255)), api.FailureClassSSH},
+		{"router hop failure", fmt.Errorf("ssh bootstrap failed: %w\nOutput: kex_exchange_identification: Connection closed by remote host", exitErr(255)), api.FailureClassTailscale},
+		{"script failure", fmt.Errorf("ssh bootstrap failed: %w", exitErr(1)), api.FailureClassBootstrap},
+		{"other", errors.New("boom"), api.FailureClassUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failureClassOf(tt.err); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestScheduleRetry(t *testing.T) {
+	sshErr := classify(api.FailureClassSSH, errors.New("connection refused"))
+
+	t.Run("no policy", func(t *testing.T) {
+		op := &api.Operation{}
+		if _, ok := scheduleRetry(op, sshErr); ok {
+			t.Fatal("expected no retry without a policy")
+		}
+		if op.Status.LastFailure == nil || op.Status.LastFailure.Class != api.FailureClassSSH {
+			t.Errorf("expected last failure to be recorded, got %+v", op.Status.LastFailure)
+		}
+	})
+
+	t.Run("retries until max attempts", func(t *testing.T) {
+		op := &api.Operation{}
+		op.Spec.RetryPolicy = &api.RetryPolicy{MaxAttempts: 2}
+		op.Status.Attempts = 1
+
+		if _, ok := scheduleRetry(op, sshErr); !ok {
+			t.Fatal("expected first failure to be retried")
+		}
+		if op.Status.Attempts != 2 || op.Status.NextRetryTime == nil {
+			t.Errorf("expected attempt 2 with a retry time, got %d / %v", op.Status.Attempts, op.Status.NextRetryTime)
+		}
+		if _, ok := scheduleRetry(op, sshErr); ok {
+			t.Fatal("expected failure at max attempts to be final")
+		}
+	})
+
+	t.Run("non-retryable class", func(t *testing.T) {
+		op := &api.Operation{}
+		op.Spec.RetryPolicy = &api.RetryPolicy{MaxAttempts: 5}
+		if _, ok := scheduleRetry(op, classify(api.FailureClassBootstrap, errors.New("apt failed"))); ok {
+			t.Fatal("expected bootstrap failure not to be retried by default")
+		}
+	})
+}
@@ -0,0 +1,347 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+	"github.com/vpatelsj/stargate/pkg/tailscale"
+)
+
+// DefaultAKSNodeSubnetCIDR is the AKS node subnet the AKS router advertises by default
+const DefaultAKSNodeSubnetCIDR = "10.224.0.0/16"
+
+// aksRouterKey identifies the AKS router in the record of applied advertisements, the plan
+// and the route sync metrics
+const aksRouterKey = "aks"
+
+// RouteSyncAdvertisementsName is the name of the ConfigMap that records the last advertisement
+// applied to each router. It lives in the route sync plan namespace.
+const RouteSyncAdvertisementsName = "route-sync-advertisements"
+
+// advertisementsKey is the ConfigMap key holding the JSON record, keyed by router
+const advertisementsKey = "advertisements"
+
+// routerAdvertisement is the set of routes a router advertises over Tailscale
+type routerAdvertisement struct {
+	routes []string // sorted
+	hash   string
+}
+
+// newRouterAdvertisement returns the advertisement of a router's configured subnets and the
+// pod CIDRs of its Nodes. Broad pod CIDRs like 10.244.0.0/16 are left out: on the DC router
+// they would route DC worker pods back through Tailscale instead of locally.
+func newRouterAdvertisement(subnets, podCIDRs []string) routerAdvertisement {
+	set := make(map[string]bool)
+	for _, subnet := range subnets {
+		if subnet != "" {
+			set[subnet] = true
+		}
+	}
+	for _, cidr := range podCIDRs {
+		if cidr != "" && !isBroadPodCIDR(cidr) {
+			set[cidr] = true
+		}
+	}
+
+	routes := make([]string, 0, len(set))
+	for route := range set {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	sum := sha256.Sum256([]byte(strings.Join(routes, ",")))
+	return routerAdvertisement{routes: routes, hash: hex.EncodeToString(sum[:8])}
+}
+
+// matches returns true if routes is exactly the advertised set, in any order
+func (a routerAdvertisement) matches(routes []string) bool {
+	if len(routes) != len(a.routes) {
+		return false
+	}
+	sorted := append([]string(nil), routes...)
+	sort.Strings(sorted)
+	for i := range sorted {
+		if sorted[i] != a.routes[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// advertisedPodCIDRs returns the pod CIDRs of the Nodes routed through one router: the AKS
+// nodes for the AKS router, or the DC workers of datacenter ("namespace/name", "" for the
+// router set by flags) for a DC router
+func advertisedPodCIDRs(nodes []nodeRoutes, class nodeClass, datacenter string) []string {
+	var cidrs []string
+	for _, routes := range nodes {
+		if routes.class != class || (class == nodeClassWorker && routes.datacenter != datacenter) {
+			continue
+		}
+		cidrs = append(cidrs, routes.podCIDR)
+	}
+	return cidrs
+}
+
+// appliedAdvertisement is the recorded last advertisement applied to a router
+type appliedAdvertisement struct {
+	Routes  []string    `json:"routes"`
+	Hash    string      `json:"hash"`
+	Applied metav1.Time `json:"applied"`
+}
+
+// appliedAdvertisements records the last advertisement applied to each router. The record is
+// persisted to the RouteSyncAdvertisementsName ConfigMap, so it survives restarts and leader
+// failover and can be audited.
+type appliedAdvertisements struct {
+	mu      sync.Mutex
+	loaded  bool
+	dirty   bool // Recorded changes not persisted yet
+	applied map[string]appliedAdvertisement
+}
+
+// advertisementsObjectKey returns the key of the ConfigMap the applied advertisements are persisted to
+func (r *RouteSyncReconciler) advertisementsObjectKey() client.ObjectKey {
+	return client.ObjectKey{Namespace: r.planNamespace(), Name: RouteSyncAdvertisementsName}
+}
+
+// loadAdvertisements reads the persisted record of applied advertisements the first time it is
+// needed. The caller holds r.advertisements.mu.
+func (r *RouteSyncReconciler) loadAdvertisements(ctx context.Context) error {
+	a := &r.advertisements
+	if a.loaded {
+		return nil
+	}
+	a.applied = map[string]appliedAdvertisement{}
+	var configMap corev1.ConfigMap
+	if err := r.Get(ctx, r.advertisementsObjectKey(), &configMap); apierrors.IsNotFound(err) {
+		a.loaded = true
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("get applied advertisements: %w", err)
+	}
+	if data := configMap.Data[advertisementsKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &a.applied); err != nil {
+			return fmt.Errorf("parse applied advertisements: %w", err)
+		}
+	}
+	a.loaded = true
+	return nil
+}
+
+// lastAdvertisementHash returns the hash of the last advertisement applied to router, and
+// whether one was recorded. Unpersisted changes are written out again.
+func (r *RouteSyncReconciler) lastAdvertisementHash(ctx context.Context, router string) (string, bool, error) {
+	a := &r.advertisements
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := r.loadAdvertisements(ctx); err != nil {
+		return "", false, err
+	}
+	if a.dirty {
+		r.persistAdvertisements(ctx)
+	}
+	last, ok := a.applied[router]
+	return last.Hash, ok, nil
+}
+
+// recordAdvertisement records adv as applied to router and persists the record. A failure to
+// persist it is logged and retried on the next lookup.
+func (r *RouteSyncReconciler) recordAdvertisement(ctx context.Context, router string, adv routerAdvertisement) {
+	a := &r.advertisements
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := r.loadAdvertisements(ctx); err != nil {
+		logger := r.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Warn("Failed to record applied advertisement", "router", router, "hash", adv.hash, "error", err)
+		return
+	}
+	if last, ok := a.applied[router]; ok && last.Hash == adv.hash && !a.dirty {
+		return
+	}
+	a.applied[router] = appliedAdvertisement{Routes: adv.routes, Hash: adv.hash, Applied: metav1.Now()}
+	a.dirty = true
+	r.persistAdvertisements(ctx)
+}
+
+// persistAdvertisements writes the applied advertisements to their ConfigMap. The caller
+// holds r.advertisements.mu.
+func (r *RouteSyncReconciler) persistAdvertisements(ctx context.Context) {
+	a := &r.advertisements
+	data, err := json.Marshal(a.applied)
+	if err == nil {
+		key := r.advertisementsObjectKey()
+		configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+		_, err = controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+			configMap.Data = map[string]string{advertisementsKey: string(data)}
+			return nil
+		})
+	}
+	if err != nil {
+		logger := r.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Warn("Failed to persist applied advertisements", "error", err)
+		return
+	}
+	a.dirty = false
+}
+
+// liveNodeRoutes returns what the routes of the live Nodes were programmed for. The cache may
+// not reflect the Node being reconciled yet, so its routes are replaced by current; a zero
+// current leaves it out, e.g. while its routes are removed.
+func (r *RouteSyncReconciler) liveNodeRoutes(ctx context.Context, nodeName string, current nodeRoutes) ([]nodeRoutes, error) {
+	var nodes corev1.NodeList
+	if err := r.List(ctx, &nodes); err != nil {
+		return nil, fmt.Errorf("list nodes: %w", err)
+	}
+
+	var live []nodeRoutes
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		podCIDR := node.Annotations[podCIDRAnnotation]
+		if node.DeletionTimestamp != nil || node.Name == nodeName || podCIDR == "" {
+			continue
+		}
+		live = append(live, nodeRoutes{
+			class:      r.recordedClass(node),
+			podCIDR:    podCIDR,
+			nodeIP:     node.Annotations[nodeIPAnnotation],
+			datacenter: node.Annotations[datacenterAnnotation],
+		})
+	}
+	if current.podCIDR != "" {
+		live = append(live, current)
+	}
+	return live, nil
+}
+
+// syncDCRouterAdvertisement makes a DC router advertise its worker subnet and the pod CIDRs of
+// its datacenter's live workers. It returns true if the router's routes changed.
+func (r *RouteSyncReconciler) syncDCRouterAdvertisement(ctx context.Context, router *dcRouter, nodeName string, current nodeRoutes, force bool) (bool, error) {
+	live, err := r.liveNodeRoutes(ctx, nodeName, current)
+	if err != nil {
+		return false, err
+	}
+	adv := newRouterAdvertisement([]string{router.workerSubnet}, advertisedPodCIDRs(live, nodeClassWorker, router.datacenter))
+	findDevice := func(ctx context.Context) (*tailscale.Device, error) { return r.findDCRouterDevice(ctx, router) }
+	return r.applyAdvertisement(ctx, router.key(), nodeName, findDevice, adv, force)
+}
+
+// syncAKSRouterAdvertisement makes the AKS router advertise the AKS node subnet, so DC workers
+// can reach AKS nodes, and the pod CIDRs of the live AKS nodes for return traffic. It returns
+// true if the router's routes changed.
+func (r *RouteSyncReconciler) syncAKSRouterAdvertisement(ctx context.Context, nodeName string, current nodeRoutes, force bool) (bool, error) {
+	live, err := r.liveNodeRoutes(ctx, nodeName, current)
+	if err != nil {
+		return false, err
+	}
+	adv := newRouterAdvertisement([]string{r.aksNodeSubnetCIDR()}, advertisedPodCIDRs(live, nodeClassAKS, ""))
+	return r.applyAdvertisement(ctx, aksRouterKey, nodeName, r.findAKSRouterDevice, adv, force)
+}
+
+// applyAdvertisement makes a router advertise and enable exactly the routes of adv, replacing
+// the whole set in one update of each. Nothing is done if adv was the last advertisement
+// applied to the router, unless force is set, or if the router's device already has it.
+// Outside auto mode the advertisement is only planned, for nodeName if it is set.
+func (r *RouteSyncReconciler) applyAdvertisement(ctx context.Context, key, nodeName string, findDevice func(context.Context) (*tailscale.Device, error), adv routerAdvertisement, force bool) (bool, error) {
+	if r.tsClient == nil {
+		return false, fmt.Errorf("Tailscale client not configured")
+	}
+	if last, ok, err := r.lastAdvertisementHash(ctx, key); err != nil {
+		return false, err
+	} else if ok && last == adv.hash && !force {
+		return false, nil
+	}
+
+	device, err := findDevice(ctx)
+	if err != nil {
+		return false, err
+	}
+	current, err := r.tsClient.GetDeviceRoutes(ctx, device.ID)
+	if err != nil {
+		return false, fmt.Errorf("get device routes: %w", err)
+	}
+	mutation := api.RouteMutation{Layer: api.RouteLayerTailscale, Action: api.RouteActionAdvertise, Router: key, Routes: adv.routes, Node: nodeName}
+	if adv.matches(current.AdvertisedRoutes) && adv.matches(current.EnabledRoutes) {
+		r.recordAdvertisement(ctx, key, adv)
+		if r.planning() {
+			r.plan.settle(mutation)
+		}
+		return false, nil
+	}
+
+	if applied, err := r.mutate(ctx, mutation); err != nil || !applied {
+		return false, err
+	}
+
+	logger := r.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.Info("Applied Tailscale route advertisement", "router", key, "routes", adv.routes, "hash", adv.hash)
+	return true, nil
+}
+
+// resyncAdvertisements checks every router's Tailscale advertisement against the live Nodes
+// and repairs it
+func (r *RouteSyncReconciler) resyncAdvertisements(ctx context.Context) error {
+	if r.tsClient == nil {
+		return nil
+	}
+
+	var errs []error
+	if r.AKSRouterTSIP != "" {
+		_, err := r.syncAKSRouterAdvertisement(ctx, "", nodeRoutes{}, true)
+		r.observeSync(routeLayerTailscale, aksRouterKey, err)
+		errs = append(errs, err)
+	}
+
+	datacenters := []string{""}
+	var list api.DatacenterList
+	if err := r.List(ctx, &list); err != nil {
+		return errors.Join(append(errs, fmt.Errorf("list datacenters: %w", err))...)
+	}
+	for i := range list.Items {
+		datacenters = append(datacenters, list.Items[i].Namespace+"/"+list.Items[i].Name)
+	}
+	for _, datacenter := range datacenters {
+		router, err := r.dcRouterFor(ctx, datacenter)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if router.tailscaleIP == "" {
+			continue
+		}
+		_, err = r.syncDCRouterAdvertisement(ctx, router, "", nodeRoutes{}, true)
+		r.observeSync(routeLayerTailscale, router.key(), err)
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// aksNodeSubnetCIDR returns the AKS node subnet the AKS router advertises
+func (r *RouteSyncReconciler) aksNodeSubnetCIDR() string {
+	if r.AKSNodeSubnetCIDR != "" {
+		return r.AKSNodeSubnetCIDR
+	}
+	return DefaultAKSNodeSubnetCIDR
+}
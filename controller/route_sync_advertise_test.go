@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNewRouterAdvertisement(t *testing.T) {
+	adv := newRouterAdvertisement(
+		[]string{"10.50.0.0/16", ""},
+		[]string{"10.244.61.0/24", "10.244.60.0/24", "10.244.61.0/24", "10.244.0.0/16"},
+	)
+	// Sorted and deduplicated, without the broad pod CIDR
+	if want := []string{"10.244.60.0/24", "10.244.61.0/24", "10.50.0.0/16"}; !reflect.DeepEqual(adv.routes, want) {
+		t.Errorf("routes = %v, want %v", adv.routes, want)
+	}
+
+	// The hash depends on the set only
+	same := newRouterAdvertisement([]string{"10.50.0.0/16"}, []string{"10.244.60.0/24", "10.244.61.0/24"})
+	if same.hash != adv.hash {
+		t.Errorf("hash = %s, want %s for the same set", same.hash, adv.hash)
+	}
+	if other := newRouterAdvertisement([]string{"10.50.0.0/16"}, []string{"10.244.60.0/24"}); other.hash == adv.hash {
+		t.Errorf("hash = %s for a different set, want it to differ", other.hash)
+	}
+
+	if !adv.matches([]string{"10.50.0.0/16", "10.244.61.0/24", "10.244.60.0/24"}) {
+		t.Error("matches() = false for the same routes in another order")
+	}
+	if adv.matches([]string{"10.50.0.0/16", "10.244.60.0/24"}) {
+		t.Error("matches() = true with a route missing")
+	}
+	if adv.matches([]string{"10.50.0.0/16", "10.244.60.0/24", "10.244.62.0/24"}) {
+		t.Error("matches() = true with a different route")
+	}
+}
+
+func TestAdvertisedPodCIDRs(t *testing.T) {
+	nodes := []nodeRoutes{
+		{class: nodeClassWorker, podCIDR: "10.244.60.0/24", datacenter: "azure-dc/dc1"},
+		{class: nodeClassWorker, podCIDR: "10.244.61.0/24"},
+		{class: nodeClassWorker, podCIDR: "10.244.62.0/24", datacenter: "azure-dc/dc1"},
+		{class: nodeClassAKS, podCIDR: "10.244.1.0/24"},
+	}
+
+	tests := []struct {
+		name       string
+		class      nodeClass
+		datacenter string
+		want       []string
+	}{
+		{"datacenter router", nodeClassWorker, "azure-dc/dc1", []string{"10.244.60.0/24", "10.244.62.0/24"}},
+		{"router set by flags", nodeClassWorker, "", []string{"10.244.61.0/24"}},
+		{"AKS router", nodeClassAKS, "", []string{"10.244.1.0/24"}},
+		{"datacenter without workers", nodeClassWorker, "site-3/dc3", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := advertisedPodCIDRs(nodes, tt.class, tt.datacenter); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("advertisedPodCIDRs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppliedAdvertisements(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewClientBuilder().Build()
+	adv := newRouterAdvertisement([]string{"10.50.0.0/16"}, []string{"10.244.60.0/24"})
+
+	r := &RouteSyncReconciler{Client: c}
+	if _, ok, err := r.lastAdvertisementHash(ctx, "dc"); err != nil || ok {
+		t.Fatalf("lastAdvertisementHash() = %v, %v before anything was applied", ok, err)
+	}
+	r.recordAdvertisement(ctx, "dc", adv)
+
+	var configMap corev1.ConfigMap
+	if err := c.Get(ctx, r.advertisementsObjectKey(), &configMap); err != nil {
+		t.Fatalf("applied advertisements not persisted: %v", err)
+	}
+
+	// A restarted controller reads the record back
+	restarted := &RouteSyncReconciler{Client: c}
+	hash, ok, err := restarted.lastAdvertisementHash(ctx, "dc")
+	if err != nil || !ok || hash != adv.hash {
+		t.Errorf("lastAdvertisementHash() = %q, %v, %v after restart, want %q", hash, ok, err, adv.hash)
+	}
+	if got := restarted.advertisements.applied["dc"].Routes; !reflect.DeepEqual(got, adv.routes) {
+		t.Errorf("recorded routes = %v, want %v", got, adv.routes)
+	}
+}
@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// Labels the bootstrap scripts put on a Server's Node, so route sync can classify it without
+// guessing from its name
+const (
+	nodeRoleLabel       = "stargate.io/role"
+	nodeServerLabel     = "stargate.io/server"
+	nodeDatacenterLabel = "stargate.io/datacenter" // the Datacenter's name
+
+	nodeRoleWorker = "worker"
+)
+
+const (
+	// DefaultWorkerNodeSelector selects the Nodes of stargate DC workers
+	DefaultWorkerNodeSelector = nodeRoleLabel + "=" + nodeRoleWorker
+
+	// DefaultAKSNodeSelector selects the Nodes of AKS node pools. Stargate workers joined to AKS
+	// carry the agentpool label too, with the value "stargate".
+	DefaultAKSNodeSelector = "kubernetes.azure.com/agentpool,kubernetes.azure.com/agentpool!=stargate,!" + nodeRoleLabel
+)
+
+var (
+	defaultWorkerNodeSelector = mustParseSelector(DefaultWorkerNodeSelector)
+	defaultAKSNodeSelector    = mustParseSelector(DefaultAKSNodeSelector)
+)
+
+func mustParseSelector(selector string) labels.Selector {
+	s, err := labels.Parse(selector)
+	if err != nil {
+		panic(fmt.Sprintf("invalid label selector %q: %v", selector, err))
+	}
+	return s
+}
+
+// nodeClass is what route sync programs a Node's routes as
+type nodeClass string
+
+const (
+	nodeClassNone   nodeClass = ""
+	nodeClassWorker nodeClass = "worker"
+	nodeClassAKS    nodeClass = "aks"
+)
+
+// stargateNodeLabels returns the kubelet --node-labels value for a Server's Node. Names that
+// aren't valid label values are left out.
+func stargateNodeLabels(server *api.Server) string {
+	nodeLabels := []string{nodeRoleLabel + "=" + nodeRoleWorker}
+	if len(validation.IsValidLabelValue(server.Name)) == 0 {
+		nodeLabels = append(nodeLabels, nodeServerLabel+"="+server.Name)
+	}
+	if ref := server.Spec.DatacenterRef; ref != nil && ref.Name != "" && len(validation.IsValidLabelValue(ref.Name)) == 0 {
+		nodeLabels = append(nodeLabels, nodeDatacenterLabel+"="+ref.Name)
+	}
+	return strings.Join(nodeLabels, ",")
+}
+
+// classifyNode returns what route sync treats a Node as: its labels are matched against the
+// worker selector, then the AKS selector. With nameHeuristics, a Node neither selects is
+// classified by its name instead.
+func classifyNode(node *corev1.Node, workerSelector, aksSelector labels.Selector, nameHeuristics bool) nodeClass {
+	set := labels.Set(node.Labels)
+	switch {
+	case workerSelector.Matches(set):
+		return nodeClassWorker
+	case aksSelector.Matches(set):
+		return nodeClassAKS
+	case !nameHeuristics:
+		return nodeClassNone
+	case looksLikeStargateWorker(node):
+		return nodeClassWorker
+	case looksLikeAKSNode(node):
+		return nodeClassAKS
+	}
+	return nodeClassNone
+}
+
+// classifyNode returns what route sync treats a Node as, using the configured selectors
+func (r *RouteSyncReconciler) classifyNode(node *corev1.Node) nodeClass {
+	return classifyNode(node, r.workerNodeSelector(), r.aksNodeSelector(), r.NameHeuristics)
+}
+
+func (r *RouteSyncReconciler) workerNodeSelector() labels.Selector {
+	if r.WorkerNodeSelector != nil {
+		return r.WorkerNodeSelector
+	}
+	return defaultWorkerNodeSelector
+}
+
+func (r *RouteSyncReconciler) aksNodeSelector() labels.Selector {
+	if r.AKSNodeSelector != nil {
+		return r.AKSNodeSelector
+	}
+	return defaultAKSNodeSelector
+}
+
+// looksLikeStargateWorker guesses from its name whether a Node is a stargate DC worker
+func looksLikeStargateWorker(node *corev1.Node) bool {
+	// Check for stargate-specific labels
+	if _, ok := node.Labels[nodeRoleLabel]; ok {
+		return true
+	}
+	// Check for DC worker naming convention
+	if strings.Contains(node.Name, "dc-") || strings.Contains(node.Name, "worker") {
+		return true
+	}
+	return false
+}
+
+// looksLikeAKSNode guesses from its name whether a Node is an AKS node (from VMSS)
+func looksLikeAKSNode(node *corev1.Node) bool {
+	// Check for AKS node naming convention (aks-nodepool...)
+	if strings.HasPrefix(node.Name, "aks-") {
+		return true
+	}
+	// Check for VMSS naming pattern
+	if strings.Contains(node.Name, "vmss") {
+		return true
+	}
+	return false
+}
+
+// unclassifiedNodes tracks the Nodes route sync ignores because neither selector matches them
+type unclassifiedNodes struct {
+	mu    sync.Mutex
+	names map[string]bool
+}
+
+// set records whether a Node is unclassified and returns true if it just became so
+func (u *unclassifiedNodes) set(name string, unclassified bool) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.names == nil {
+		u.names = map[string]bool{}
+	}
+	was := u.names[name]
+	if unclassified {
+		u.names[name] = true
+	} else {
+		delete(u.names, name)
+	}
+	routeSyncUnclassifiedNodes.Set(float64(len(u.names)))
+	return unclassified && !was
+}
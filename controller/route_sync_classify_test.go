@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+func TestClassifyNode(t *testing.T) {
+	node := func(name string, nodeLabels map[string]string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nodeLabels}}
+	}
+	aksPool := func(pool string) map[string]string {
+		return map[string]string{"kubernetes.azure.com/agentpool": pool}
+	}
+	stargateWorker := map[string]string{
+		"kubernetes.azure.com/agentpool": "stargate",
+		nodeRoleLabel:                    nodeRoleWorker,
+	}
+
+	tests := []struct {
+		name           string
+		node           *corev1.Node
+		nameHeuristics bool
+		want           nodeClass
+	}{
+		{"labelled worker", node("bm-17", stargateWorker), false, nodeClassWorker},
+		{"AKS pool", node("aks-nodepool1-123-vmss000000", aksPool("nodepool1")), false, nodeClassAKS},
+		{"AKS pool named like a worker", node("aks-workerpool-123-vmss000000", aksPool("workerpool")), true, nodeClassAKS},
+		{"stargate pool without role", node("bm-17", aksPool("stargate")), false, nodeClassNone},
+		{"unlabelled worker", node("dc-worker-1", nil), false, nodeClassNone},
+		{"unlabelled worker with heuristics", node("dc-worker-1", nil), true, nodeClassWorker},
+		{"unlabelled VMSS node with heuristics", node("aks-nodepool1-123-vmss000000", nil), true, nodeClassAKS},
+		{"unknown with heuristics", node("gpu-1", nil), true, nodeClassNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyNode(tt.node, defaultWorkerNodeSelector, defaultAKSNodeSelector, tt.nameHeuristics)
+			if got != tt.want {
+				t.Errorf("classifyNode(%s) = %q, want %q", tt.node.Name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStargateNodeLabels(t *testing.T) {
+	server := &api.Server{ObjectMeta: metav1.ObjectMeta{Name: "dc-worker-1"}}
+	if got, want := stargateNodeLabels(server), "stargate.io/role=worker,stargate.io/server=dc-worker-1"; got != want {
+		t.Errorf("stargateNodeLabels() = %q, want %q", got, want)
+	}
+
+	server.Spec.DatacenterRef = &api.LocalObjectReference{Name: "dc1"}
+	if got, want := stargateNodeLabels(server), "stargate.io/role=worker,stargate.io/server=dc-worker-1,stargate.io/datacenter=dc1"; got != want {
+		t.Errorf("stargateNodeLabels() = %q, want %q", got, want)
+	}
+}
+
+func TestUnclassifiedNodes(t *testing.T) {
+	var u unclassifiedNodes
+	if !u.set("gpu-1", true) {
+		t.Error("set(gpu-1, true) = false, want true for a newly unclassified node")
+	}
+	if u.set("gpu-1", true) {
+		t.Error("set(gpu-1, true) = true, want false for an already unclassified node")
+	}
+	u.set("gpu-1", false)
+	if !u.set("gpu-1", true) {
+		t.Error("set(gpu-1, true) = false, want true after the node was classified")
+	}
+}
@@ -0,0 +1,275 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+const (
+	// routeCleanupFinalizer keeps a Node around until the routes programmed for it are removed
+	routeCleanupFinalizer = "stargate.io/route-cleanup"
+
+	// Annotations recording what a Node's routes were programmed for, so they can be removed
+	// after the Node is gone or they change
+	podCIDRAnnotation    = "stargate.io/pod-cidr"
+	nodeIPAnnotation     = "stargate.io/node-ip"
+	datacenterAnnotation = "stargate.io/datacenter" // "namespace/name" of the Datacenter
+	nodeClassAnnotation  = "stargate.io/route-class"
+)
+
+// errRouteRemovalsPlanned is returned by finalizeNode in apply mode while a Node's route removals
+// wait in the plan. The plan is only in memory, so the finalizer stays until they are applied:
+// after a restart the next pass plans them again.
+var errRouteRemovalsPlanned = errors.New("route removals are planned but not applied yet")
+
+// nodeRoutes is what a Node's routes are programmed for
+type nodeRoutes struct {
+	class   nodeClass
+	podCIDR string
+	nodeIP  string
+	// datacenter is the "namespace/name" of the Datacenter whose router a worker's routes are
+	// programmed on, or "" for the router set by flags
+	datacenter string
+}
+
+// trackNodeRoutes records what routes are programmed for on the Node and adds the cleanup
+// finalizer. The Node is updated in place. Plan mode never programs routes, so it only records
+// them: the resync and the Tailscale advertisements are planned from the annotations.
+func (r *RouteSyncReconciler) trackNodeRoutes(ctx context.Context, node *corev1.Node, routes nodeRoutes) error {
+	addFinalizer := r.Mode != RouteSyncModePlan
+	if (controllerutil.ContainsFinalizer(node, routeCleanupFinalizer) || !addFinalizer) &&
+		node.Annotations[podCIDRAnnotation] == routes.podCIDR && node.Annotations[nodeIPAnnotation] == routes.nodeIP &&
+		node.Annotations[datacenterAnnotation] == routes.datacenter && node.Annotations[nodeClassAnnotation] == string(routes.class) {
+		return nil
+	}
+
+	patch := client.MergeFrom(node.DeepCopy())
+	if addFinalizer {
+		controllerutil.AddFinalizer(node, routeCleanupFinalizer)
+	}
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	node.Annotations[podCIDRAnnotation] = routes.podCIDR
+	node.Annotations[nodeIPAnnotation] = routes.nodeIP
+	node.Annotations[nodeClassAnnotation] = string(routes.class)
+	if routes.datacenter != "" {
+		node.Annotations[datacenterAnnotation] = routes.datacenter
+	} else {
+		delete(node.Annotations, datacenterAnnotation)
+	}
+	if err := r.Patch(ctx, node, patch); err != nil {
+		return fmt.Errorf("track routes on node %s: %w", node.Name, err)
+	}
+	return nil
+}
+
+// finalizeNode removes the routes of a deleted Node, then its finalizer. In apply mode the
+// finalizer is kept, with errRouteRemovalsPlanned, until the planned removals are applied. Plan
+// mode never applies them, so the finalizer goes once they are published.
+func (r *RouteSyncReconciler) finalizeNode(ctx context.Context, node *corev1.Node) error {
+	if !controllerutil.ContainsFinalizer(node, routeCleanupFinalizer) {
+		return nil
+	}
+
+	routes, err := r.recordedRoutes(ctx, node)
+	if err != nil {
+		return err
+	}
+	if routes.podCIDR != "" {
+		if err := r.removeRoutesForNode(ctx, node, routes); err != nil {
+			return err
+		}
+	}
+	if r.planning() && r.plan.pendingFor(node.Name) {
+		if r.Mode == RouteSyncModeApply {
+			return errRouteRemovalsPlanned
+		}
+		if err := r.publishPlan(ctx); err != nil {
+			return err
+		}
+	}
+
+	patch := client.MergeFrom(node.DeepCopy())
+	controllerutil.RemoveFinalizer(node, routeCleanupFinalizer)
+	if err := r.Patch(ctx, node, patch); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	return nil
+}
+
+// recordedNodeRoutes returns the pod CIDR and IP a Node's routes were programmed for, falling
+// back to its current spec and addresses for Nodes tracked before the annotations existed
+func recordedNodeRoutes(node *corev1.Node) (podCIDR, nodeIP string) {
+	podCIDR, nodeIP = node.Annotations[podCIDRAnnotation], node.Annotations[nodeIPAnnotation]
+	if podCIDR == "" {
+		podCIDR = node.Spec.PodCIDR
+	}
+	if nodeIP == "" {
+		nodeIP = getNodeInternalIP(node)
+	}
+	return podCIDR, nodeIP
+}
+
+// recordedClass returns the class a Node's routes were programmed as. Nodes tracked before the
+// class was recorded were classified by name.
+func (r *RouteSyncReconciler) recordedClass(node *corev1.Node) nodeClass {
+	if class, recorded := node.Annotations[nodeClassAnnotation]; recorded {
+		return nodeClass(class)
+	}
+	return classifyNode(node, r.workerNodeSelector(), r.aksNodeSelector(), true)
+}
+
+// recordedRoutes returns what a Node's routes were programmed for. Nodes tracked before the
+// class was recorded were classified by name, and Nodes not tracked at all were routed
+// through the router of their Server's Datacenter.
+func (r *RouteSyncReconciler) recordedRoutes(ctx context.Context, node *corev1.Node) (nodeRoutes, error) {
+	routes := nodeRoutes{
+		class:      r.recordedClass(node),
+		datacenter: node.Annotations[datacenterAnnotation],
+	}
+	routes.podCIDR, routes.nodeIP = recordedNodeRoutes(node)
+	if _, tracked := node.Annotations[podCIDRAnnotation]; !tracked && routes.class == nodeClassWorker {
+		var err error
+		if routes.datacenter, err = r.nodeDatacenter(ctx, node); err != nil {
+			return routes, err
+		}
+	}
+	return routes, nil
+}
+
+// removeRoutesForNode removes the routes programmed for a Node's pod CIDR. This is the reverse
+// of ensureRouteForNode for DC workers, and of the router route and Tailscale advertisement
+// added for AKS nodes. Every layer is attempted; the first error is returned.
+func (r *RouteSyncReconciler) removeRoutesForNode(ctx context.Context, node *corev1.Node, routes nodeRoutes) error {
+	logger := r.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	podCIDR, nodeIP := routes.podCIDR, routes.nodeIP
+
+	// The AKS router's kernel route is shared by every Node with the CIDR, so after a CIDR has
+	// been reused it must stay. The Tailscale advertisements are recomputed from the live
+	// Nodes, which keeps a reused CIDR too.
+	var nodes corev1.NodeList
+	if err := r.List(ctx, &nodes); err != nil {
+		return fmt.Errorf("list nodes: %w", err)
+	}
+	shared := podCIDRInUse(nodes.Items, podCIDR, node.Name)
+
+	var errs []error
+	switch routes.class {
+	case nodeClassWorker:
+		router, err := r.dcRouterFor(ctx, routes.datacenter)
+		if err != nil {
+			return err
+		}
+
+		// 1. Azure route table entry (stargate-workers-rt)
+		_, err = r.mutate(ctx, azureRouteMutation(api.RouteActionDelete, r.RouteTableName, azureRoute{name: workerRouteName(node.Name)}, node.Name))
+		r.observeSync(routeLayerAzureRouteTable, aksRouterKey, err)
+		errs = append(errs, err)
+
+		// 2. Kernel route on the AKS router, unless another Node still uses the CIDR
+		if r.AKSRouterTSIP != "" && r.sshClientConfig != nil && !shared {
+			_, err := r.mutate(ctx, kernelRouteMutation(api.RouteActionDelete, aksRouterKey, podCIDR, "", tailscaleDevice, node.Name))
+			r.observeSync(routeLayerKernelRoute, aksRouterKey, err)
+			errs = append(errs, err)
+		}
+
+		// 3. Kernel route on the DC router. Matching the next hop leaves a route to another
+		// worker that reused the CIDR alone.
+		if router.tailscaleIP != "" && router.sshConfig != nil && nodeIP != "" {
+			_, err := r.mutate(ctx, kernelRouteMutation(api.RouteActionDelete, router.key(), podCIDR, nodeIP, "", node.Name))
+			r.observeSync(routeLayerKernelRoute, router.key(), err)
+			errs = append(errs, err)
+		}
+
+		// 4. DC router Tailscale advertisement, recomputed without the Node
+		if r.tsClient != nil && router.tailscaleIP != "" {
+			_, err := r.syncDCRouterAdvertisement(ctx, router, node.Name, nodeRoutes{}, false)
+			r.observeSync(routeLayerTailscale, router.key(), err)
+			errs = append(errs, err)
+		}
+	case nodeClassAKS:
+		// 1. Router route table entry (stargate-router-rt) for return traffic
+		if r.RouterSubnetName != "" && r.VNetName != "" {
+			route := azureRoute{name: aksNodeRouteName(node.Name)}
+			_, err := r.mutate(ctx, azureRouteMutation(api.RouteActionDelete, r.routerRouteTableName(), route, node.Name))
+			r.observeSync(routeLayerAzureRouteTable, aksRouterKey, err)
+			errs = append(errs, err)
+		}
+
+		// 2. AKS router Tailscale advertisement, recomputed without the Node
+		if r.tsClient != nil && r.AKSRouterTSIP != "" {
+			_, err := r.syncAKSRouterAdvertisement(ctx, node.Name, nodeRoutes{}, false)
+			r.observeSync(routeLayerTailscale, aksRouterKey, err)
+			errs = append(errs, err)
+		}
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	if r.planning() {
+		logger.Info("Route removals planned for node", "node", node.Name, "podCIDR", podCIDR, "nodeIP", nodeIP, "sharedCIDR", shared)
+		return nil
+	}
+	logger.Info("Routes removed for node", "node", node.Name, "podCIDR", podCIDR, "nodeIP", nodeIP, "sharedCIDR", shared)
+	recordEvent(r.Recorder, node, corev1.EventTypeNormal, api.EventReasonRouteRemoved, "Removed routes for pod CIDR %s", podCIDR)
+	return nil
+}
+
+// podCIDRInUse returns true if a Node other than except, and not being deleted, has routes
+// programmed for cidr
+func podCIDRInUse(nodes []corev1.Node, cidr, except string) bool {
+	for i := range nodes {
+		node := &nodes[i]
+		if node.Name == except || node.DeletionTimestamp != nil {
+			continue
+		}
+		if podCIDR, _ := recordedNodeRoutes(node); podCIDR == cidr {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteAzureRoute deletes a route from a route table. A route that doesn't exist is not an error.
+func (r *RouteSyncReconciler) deleteAzureRoute(ctx context.Context, routeTableName, routeName string) error {
+	poller, err := r.routesClient.BeginDelete(ctx, r.AKSResourceGroup, routeTableName, routeName, nil)
+	if err == nil {
+		_, err = poller.PollUntilDone(ctx, nil)
+	}
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("delete route %s from %s: %w", routeName, routeTableName, err)
+	}
+	return nil
+}
+
+// deleteKernelRoute deletes a kernel route on a router. A route that doesn't exist is not an error.
+// route must come from parseKernelRoute.
+func deleteKernelRoute(sshConfig *ssh.ClientConfig, routerTSIP, route string) error {
+	cmd := fmt.Sprintf("sudo ip route del %s 2>/dev/null || true", route)
+	if _, err := runSSHCommandWith(sshConfig, routerTSIP, cmd); err != nil {
+		return fmt.Errorf("delete kernel route %s on %s: %w", route, routerTSIP, err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRecordedNodeRoutes(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "dc-worker-1"},
+		Spec:       corev1.NodeSpec{PodCIDR: "10.244.60.0/24"},
+		Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeInternalIP, Address: "10.50.1.10"},
+		}},
+	}
+
+	// Falls back to the Node's spec and addresses
+	if cidr, ip := recordedNodeRoutes(node); cidr != "10.244.60.0/24" || ip != "10.50.1.10" {
+		t.Errorf("recordedNodeRoutes() = %q, %q; want spec values", cidr, ip)
+	}
+
+	// Prefers what was recorded when the routes were programmed
+	node.Annotations = map[string]string{podCIDRAnnotation: "10.244.70.0/24", nodeIPAnnotation: "10.50.1.20"}
+	if cidr, ip := recordedNodeRoutes(node); cidr != "10.244.70.0/24" || ip != "10.50.1.20" {
+		t.Errorf("recordedNodeRoutes() = %q, %q; want annotation values", cidr, ip)
+	}
+}
+
+func TestPodCIDRInUse(t *testing.T) {
+	now := metav1.Now()
+	node := func(name, cidr string, deleting bool) corev1.Node {
+		n := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{podCIDRAnnotation: cidr}}}
+		if deleting {
+			n.DeletionTimestamp = &now
+		}
+		return n
+	}
+	nodes := []corev1.Node{
+		node("dc-worker-1", "10.244.60.0/24", true),
+		node("dc-worker-2", "10.244.60.0/24", false),
+		node("dc-worker-3", "10.244.70.0/24", true),
+	}
+
+	tests := []struct {
+		name   string
+		cidr   string
+		except string
+		want   bool
+	}{
+		{"reused by a live node", "10.244.60.0/24", "dc-worker-1", true},
+		{"only the node itself", "10.244.60.0/24", "dc-worker-2", false},
+		{"only deleting nodes", "10.244.70.0/24", "dc-worker-1", false},
+		{"unused", "10.244.80.0/24", "dc-worker-1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := podCIDRInUse(nodes, tt.cidr, tt.except); got != tt.want {
+				t.Errorf("podCIDRInUse(%q, %q) = %v, want %v", tt.cidr, tt.except, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,26 +3,31 @@ package controller
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
-	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
 	"golang.org/x/crypto/ssh"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
 	"github.com/vpatelsj/stargate/pkg/tailscale"
 )
 
@@ -30,8 +35,9 @@ import (
 // when Kubernetes nodes join or leave the cluster.
 type RouteSyncReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
-	Logger *slog.Logger
+	Scheme   *runtime.Scheme
+	Logger   *slog.Logger
+	Recorder record.EventRecorder
 
 	// Azure configuration
 	SubscriptionID       string
@@ -42,9 +48,10 @@ type RouteSyncReconciler struct {
 	RouterRouteTableName string // Route table name for router subnet return traffic (stargate-router-rt)
 	RouterSubnetName     string // Subnet where the Tailscale router lives
 	AKSSubnetName        string // AKS node subnet name
+	AKSNodeSubnetCIDR    string // AKS node subnet the AKS router advertises (default: DefaultAKSNodeSubnetCIDR)
 	VNetName             string // AKS VNet name (auto-discovered if empty)
 
-	// DC Configuration
+	// DC Configuration, for workers whose Server has no Datacenter
 	DCSubnetCIDR     string // DC subnet CIDR to route (e.g., 10.50.0.0/16)
 	DCPodCIDR        string // DC pod CIDR range (e.g., 10.244.50.0/20)
 	DCResourceGroup  string // DC resource group name
@@ -53,9 +60,24 @@ type RouteSyncReconciler struct {
 	// Router IPs
 	AKSRouterIP       string // AKS router private IP (e.g., 10.237.0.4)
 	AKSRouterTSIP     string // AKS router Tailscale IP
-	DCRouterTSIP      string // DC router Tailscale IP
+	DCRouterTSIP      string // DC router Tailscale IP, for workers whose Server has no Datacenter
 	SSHPrivateKeyPath string // Path to SSH private key for router access
 
+	// Node classification. Nodes matched by neither selector get no routes.
+	WorkerNodeSelector labels.Selector // Selects stargate DC workers (default: DefaultWorkerNodeSelector)
+	AKSNodeSelector    labels.Selector // Selects AKS nodes (default: DefaultAKSNodeSelector)
+	NameHeuristics     bool            // Classify Nodes neither selector matches by name, e.g. Nodes bootstrapped before the labels existed
+
+	// ResyncInterval is how often the Azure route tables are diffed against the Nodes' routes
+	// and repaired (0 disables)
+	ResyncInterval time.Duration
+
+	// Mode is RouteSyncModeAuto (default), RouteSyncModePlan or RouteSyncModeApply. Outside
+	// auto mode, route mutations are published to the RouteSyncPlan in PlanNamespace instead of
+	// applied.
+	Mode          string
+	PlanNamespace string // Namespace of the RouteSyncPlan and the applied advertisements ConfigMap (default: DefaultRouteSyncPlanNamespace)
+
 	// Tailscale configuration
 	TailscaleAPIKey       string
 	TailscaleClientID     string
@@ -66,24 +88,35 @@ type RouteSyncReconciler struct {
 	routeTableClient *armnetwork.RouteTablesClient
 	routesClient     *armnetwork.RoutesClient
 	subnetsClient    *armnetwork.SubnetsClient
-	vmssClient       *armcompute.VirtualMachineScaleSetsClient
-	vmssVMsClient    *armcompute.VirtualMachineScaleSetVMsClient
 	nicClient        *armnetwork.InterfacesClient
 	tsClient         *tailscale.Client
 
 	// Runtime state
+	initMu          sync.Mutex
 	initialized     bool
-	lastSync        time.Time
-	aksRouterIP     string // Cached AKS router IP
-	discoveredVNet  string // Auto-discovered VNet name
+	syncMu          sync.Mutex
+	lastSync        time.Time // Last resync of the Azure route tables, guarded by syncMu
+	aksRouterIP     string    // Cached AKS router IP
+	discoveredVNet  string    // Auto-discovered VNet name
 	sshClientConfig *ssh.ClientConfig
+	unclassified    unclassifiedNodes
+	advertisements  appliedAdvertisements // Last Tailscale advertisement applied to each router, persisted to a ConfigMap
+	plan            routePlan             // Route mutations planned outside auto mode
 }
 
-// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups="",resources=nodes/status,verbs=get
+// +kubebuilder:rbac:groups=stargate.io,resources=servers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=stargate.io,resources=datacenters,verbs=get;list;watch
+// +kubebuilder:rbac:groups=stargate.io,resources=routesyncplans,verbs=get;list;watch;create;update
+// +kubebuilder:rbac:groups=stargate.io,resources=routesyncplans/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile handles Node events and syncs routes accordingly.
 // When a new node joins, it ensures the Azure route table has a route for its pod CIDR.
+// Nodes with routes carry a finalizer, so the routes are removed before the Node is deleted.
 func (r *RouteSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx).WithValues("node", req.Name)
 
@@ -97,22 +130,48 @@ func (r *RouteSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		logger.Error(err, "Failed to initialize Azure clients")
 		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
 	}
+	if r.planning() {
+		defer r.publishPlanOrLog(ctx)
+	}
 
 	// Fetch the Node
 	var node corev1.Node
 	if err := r.Get(ctx, req.NamespacedName, &node); err != nil {
-		// Node was deleted - we could clean up routes here
-		// For now, just log and ignore (routes to deleted nodes are harmless)
-		if client.IgnoreNotFound(err) != nil {
-			return ctrl.Result{}, err
+		// Routes of deleted Nodes were removed by the finalizer
+		if apierrors.IsNotFound(err) {
+			r.unclassified.set(req.Name, false)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if node.DeletionTimestamp != nil {
+		r.unclassified.set(node.Name, false)
+		err := r.finalizeNode(ctx, &node)
+		if errors.Is(err, errRouteRemovalsPlanned) {
+			logger.Info("Keeping the node's finalizer until its planned route removals are applied")
+			return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+		}
+		if err != nil {
+			logger.Error(err, "Failed to remove routes for deleted node")
+			recordEvent(r.Recorder, &node, corev1.EventTypeWarning, api.EventReasonRouteFailed, "Failed to remove routes: %v", err)
+			return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
 		}
-		logger.Info("Node deleted, route cleanup not implemented yet")
+		return ctrl.Result{}, nil
+	}
+
+	class := r.classifyNode(&node)
+	if r.unclassified.set(node.Name, class == nodeClassNone) {
+		logger.Info("Node matches neither the worker nor the AKS node selector, skipping")
+		recordEvent(r.Recorder, &node, corev1.EventTypeWarning, api.EventReasonNodeUnclassified,
+			"Node matches neither the worker nor the AKS node selector; no routes are programmed for it")
+	}
+	if class == nodeClassNone {
 		return ctrl.Result{}, nil
 	}
 
 	// Get the node's pod CIDR
 	podCIDR := node.Spec.PodCIDR
-	if podCIDR == "" && isAKSNode(&node) {
+	if podCIDR == "" && class == nodeClassAKS {
 		// AKS nodes with Azure CNI Overlay don't set spec.podCIDR
 		// The pod CIDR is in CiliumNode.spec.ipam.podCIDRs instead
 		logger.V(1).Info("AKS node has no spec.podCIDR (Azure CNI Overlay mode), fetching from CiliumNode")
@@ -142,30 +201,77 @@ func (r *RouteSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
 	}
 
+	routes := nodeRoutes{class: class, podCIDR: podCIDR, nodeIP: nodeIP}
+
+	// DC workers are routed through the router of their Server's Datacenter
+	if class == nodeClassWorker {
+		var err error
+		if routes.datacenter, err = r.nodeDatacenter(ctx, &node); err != nil {
+			logger.Error(err, "Failed to resolve the node's datacenter")
+			return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+		}
+	}
+
+	// A Node whose pod CIDR, datacenter or class changed still has routes for the old ones;
+	// stale routes black-hole traffic once the CIDR is handed to another Node
+	if _, tracked := node.Annotations[podCIDRAnnotation]; tracked {
+		previous, err := r.recordedRoutes(ctx, &node)
+		if err != nil {
+			logger.Error(err, "Failed to resolve the node's previous routes")
+			return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+		}
+		if previous.podCIDR != routes.podCIDR || previous.datacenter != routes.datacenter || previous.class != routes.class {
+			logger.Info("Node routes changed, removing old routes", "previousPodCIDR", previous.podCIDR, "podCIDR", podCIDR,
+				"previousDatacenter", previous.datacenter, "datacenter", routes.datacenter, "previousClass", previous.class, "class", class)
+			if err := r.removeRoutesForNode(ctx, &node, previous); err != nil {
+				logger.Error(err, "Failed to remove routes for previous pod CIDR")
+				recordEvent(r.Recorder, &node, corev1.EventTypeWarning, api.EventReasonRouteFailed, "Failed to remove routes for previous pod CIDR %s: %v", previous.podCIDR, err)
+				return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+			}
+		}
+	}
+
+	// Record what is about to be programmed before programming it, so it can be cleaned up
+	if err := r.trackNodeRoutes(ctx, &node, routes); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Check if this is a stargate DC worker node
-	if isStargateWorker(&node) {
+	if class == nodeClassWorker {
 		// Stargate DC worker - add routes for traffic TO this node
-		logger.Info("Ensuring routes for DC worker node", "podCIDR", podCIDR, "nodeIP", nodeIP)
-		if err := r.ensureRouteForNode(ctx, &node, podCIDR, nodeIP); err != nil {
+		logger.Info("Ensuring routes for DC worker node", "podCIDR", podCIDR, "nodeIP", nodeIP, "datacenter", routes.datacenter)
+		if err := r.ensureRouteForNode(ctx, &node, podCIDR, nodeIP, routes.datacenter); err != nil {
 			logger.Error(err, "Failed to ensure route for DC worker node")
+			recordEvent(r.Recorder, &node, corev1.EventTypeWarning, api.EventReasonRouteFailed, "Failed to program route for pod CIDR %s: %v", podCIDR, err)
 			return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
 		}
-	} else if isAKSNode(&node) {
+		if !r.planning() {
+			recordEvent(r.Recorder, &node, corev1.EventTypeNormal, api.EventReasonRouteProgrammed, "Programmed route for pod CIDR %s via %s", podCIDR, nodeIP)
+		}
+	} else if class == nodeClassAKS {
 		// AKS node - add route to router route table for return traffic FROM DC workers
 		logger.Info("Ensuring router route for AKS node", "podCIDR", podCIDR, "nodeIP", nodeIP)
 		if r.RouterSubnetName != "" && r.VNetName != "" {
-			if err := r.ensureRouterRouteForAKSNode(ctx, node.Name, podCIDR, nodeIP); err != nil {
+			err := r.ensureRouterRouteForAKSNode(ctx, node.Name, podCIDR, nodeIP)
+			r.observeSync(routeLayerAzureRouteTable, aksRouterKey, err)
+			if err != nil {
 				logger.Error(err, "Failed to ensure router route for AKS node")
+				recordEvent(r.Recorder, &node, corev1.EventTypeWarning, api.EventReasonRouteFailed, "Failed to program router route for pod CIDR %s: %v", podCIDR, err)
 				// Don't requeue - this is not critical for DC workers
+			} else if !r.planning() {
+				recordEvent(r.Recorder, &node, corev1.EventTypeNormal, api.EventReasonRouteProgrammed, "Programmed router route for pod CIDR %s via %s", podCIDR, nodeIP)
 			}
 		}
 
 		// Update AKS router Tailscale routes to include this node's pod CIDR
 		if r.tsClient != nil && r.AKSRouterTSIP != "" {
-			if err := r.updateAKSRouterTailscaleRoutes(ctx, podCIDR); err != nil {
+			changed, err := r.syncAKSRouterAdvertisement(ctx, node.Name, routes, false)
+			r.observeSync(routeLayerTailscale, aksRouterKey, err)
+			if err != nil {
 				logger.Error(err, "Failed to update AKS router Tailscale routes")
-			} else {
+			} else if changed {
 				logger.Info("AKS router Tailscale routes updated", "podCIDR", podCIDR)
+				recordEvent(r.Recorder, &node, corev1.EventTypeNormal, api.EventReasonTailscaleRoutesUpdated, "AKS router now advertises pod CIDR %s", podCIDR)
 			}
 		}
 	}
@@ -175,6 +281,12 @@ func (r *RouteSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 // SetupWithManager sets up the controller with the Manager
 func (r *RouteSyncReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := r.addRouteResync(mgr); err != nil {
+		return err
+	}
+	if err := r.addPlanApplier(mgr); err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.Node{}).
 		Complete(r)
@@ -182,6 +294,9 @@ func (r *RouteSyncReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 // ensureInitialized lazily initializes Azure and Tailscale clients
 func (r *RouteSyncReconciler) ensureInitialized(ctx context.Context) error {
+	// The route table resync initializes the clients too
+	r.initMu.Lock()
+	defer r.initMu.Unlock()
 	if r.initialized {
 		return nil
 	}
@@ -206,16 +321,6 @@ func (r *RouteSyncReconciler) ensureInitialized(ctx context.Context) error {
 		return fmt.Errorf("create subnets client: %w", err)
 	}
 
-	r.vmssClient, err = armcompute.NewVirtualMachineScaleSetsClient(r.SubscriptionID, cred, nil)
-	if err != nil {
-		return fmt.Errorf("create vmss client: %w", err)
-	}
-
-	r.vmssVMsClient, err = armcompute.NewVirtualMachineScaleSetVMsClient(r.SubscriptionID, cred, nil)
-	if err != nil {
-		return fmt.Errorf("create vmss vms client: %w", err)
-	}
-
 	r.nicClient, err = armnetwork.NewInterfacesClient(r.SubscriptionID, cred, nil)
 	if err != nil {
 		return fmt.Errorf("create nic client: %w", err)
@@ -274,8 +379,9 @@ func (r *RouteSyncReconciler) ensureInitialized(ctx context.Context) error {
 		r.aksRouterIP = r.AKSRouterIP
 	}
 
-	// Ensure the router route table exists and is associated
-	if r.RouterSubnetName != "" && r.VNetName != "" {
+	// Ensure the router route table exists and is associated. Outside auto mode nothing is
+	// changed before a plan is approved, so a missing table surfaces as a resync error instead.
+	if r.RouterSubnetName != "" && r.VNetName != "" && !r.planning() {
 		if err := r.ensureRouterRouteTable(ctx); err != nil {
 			if r.Logger != nil {
 				r.Logger.Warn("Failed to ensure router route table", "error", err)
@@ -293,66 +399,88 @@ func (r *RouteSyncReconciler) ensureInitialized(ctx context.Context) error {
 // 2. Kernel route on AKS router via tailscale0
 // 3. Kernel route on DC router to the worker's IP
 // 4. Tailscale route advertisement updates
-func (r *RouteSyncReconciler) ensureRouteForNode(ctx context.Context, node *corev1.Node, podCIDR, nodeIP string) error {
+// The DC router is the one of datacenter ("namespace/name"), or the one set by flags if it is "".
+func (r *RouteSyncReconciler) ensureRouteForNode(ctx context.Context, node *corev1.Node, podCIDR, nodeIP, datacenter string) error {
 	logger := r.Logger
 	if logger == nil {
 		logger = slog.Default()
 	}
 
+	router, err := r.dcRouterFor(ctx, datacenter)
+	if err != nil {
+		return err
+	}
+
 	// 1. Add Azure route table entry for DC worker pod CIDR -> AKS router
-	if err := r.ensureAzureRoute(ctx, node.Name, podCIDR); err != nil {
+	applied, err := r.ensureAzureRoute(ctx, node.Name, podCIDR)
+	r.observeSync(routeLayerAzureRouteTable, aksRouterKey, err)
+	if err != nil {
 		return fmt.Errorf("ensure Azure route: %w", err)
 	}
-	logger.Info("Azure route created/updated", "node", node.Name, "podCIDR", podCIDR, "nextHop", r.aksRouterIP)
+	if applied {
+		logger.Info("Azure route created/updated", "node", node.Name, "podCIDR", podCIDR, "nextHop", r.aksRouterIP)
+	}
 
 	// 2. Add kernel route on AKS router for pod CIDR via tailscale0
 	if r.AKSRouterTSIP != "" && r.sshClientConfig != nil {
-		if err := r.ensureAKSRouterKernelRoute(ctx, podCIDR); err != nil {
+		applied, err := r.ensureAKSRouterKernelRoute(ctx, node.Name, podCIDR)
+		r.observeSync(routeLayerKernelRoute, aksRouterKey, err)
+		if err != nil {
 			logger.Warn("Failed to add AKS router kernel route", "error", err, "podCIDR", podCIDR)
-		} else {
+		} else if applied {
 			logger.Info("AKS router kernel route added", "podCIDR", podCIDR)
 		}
 	}
 
 	// 3. Add kernel route on DC router for pod CIDR -> worker IP
-	if r.DCRouterTSIP != "" && r.sshClientConfig != nil {
-		if err := r.ensureDCRouterKernelRoute(ctx, podCIDR, nodeIP); err != nil {
+	if router.tailscaleIP != "" && router.sshConfig != nil {
+		applied, err := r.ensureDCRouterKernelRoute(ctx, router, node.Name, podCIDR, nodeIP)
+		r.observeSync(routeLayerKernelRoute, router.key(), err)
+		if err != nil {
 			logger.Warn("Failed to add DC router kernel route", "error", err, "podCIDR", podCIDR, "nodeIP", nodeIP)
-		} else {
+		} else if applied {
 			logger.Info("DC router kernel route added", "podCIDR", podCIDR, "nextHop", nodeIP)
 		}
 	}
 
 	// 4. Update Tailscale route advertisements on DC router
-	if r.tsClient != nil && r.DCRouterTSIP != "" {
-		if err := r.updateDCRouterTailscaleRoutes(ctx, podCIDR); err != nil {
+	if r.tsClient != nil && router.tailscaleIP != "" {
+		current := nodeRoutes{class: nodeClassWorker, podCIDR: podCIDR, nodeIP: nodeIP, datacenter: datacenter}
+		changed, err := r.syncDCRouterAdvertisement(ctx, router, node.Name, current, false)
+		r.observeSync(routeLayerTailscale, router.key(), err)
+		if err != nil {
 			logger.Warn("Failed to update DC router Tailscale routes", "error", err)
-		} else {
+		} else if changed {
 			logger.Info("DC router Tailscale routes updated")
+			recordEvent(r.Recorder, node, corev1.EventTypeNormal, api.EventReasonTailscaleRoutesUpdated, "DC router now advertises pod CIDR %s", podCIDR)
 		}
 	}
 
 	return nil
 }
 
-// ensureAzureRoute creates/updates Azure route table entry
-func (r *RouteSyncReconciler) ensureAzureRoute(ctx context.Context, nodeName, podCIDR string) error {
-	// Route name: safe version of node name
-	routeName := fmt.Sprintf("stargate-%s", sanitizeRouteName(nodeName))
-
+// ensureAzureRoute creates/updates Azure route table entry. It returns true if the route was
+// applied rather than planned.
+func (r *RouteSyncReconciler) ensureAzureRoute(ctx context.Context, nodeName, podCIDR string) (bool, error) {
 	// Determine next hop - use AKS router if available, otherwise direct to node
 	nextHop := r.aksRouterIP
 	if nextHop == "" {
-		return fmt.Errorf("AKS router IP not configured or discovered")
+		return false, fmt.Errorf("AKS router IP not configured or discovered")
 	}
 
-	// Create or update the route
-	poller, err := r.routesClient.BeginCreateOrUpdate(ctx, r.AKSResourceGroup, r.RouteTableName, routeName,
+	route := azureRoute{name: workerRouteName(nodeName), prefix: podCIDR, nextHop: nextHop}
+	return r.mutate(ctx, azureRouteMutation(api.RouteActionUpsert, r.RouteTableName, route, nodeName))
+}
+
+// putAzureRoute creates or updates a route in a route table, routing its prefix to a
+// virtual appliance
+func (r *RouteSyncReconciler) putAzureRoute(ctx context.Context, routeTableName string, route azureRoute) error {
+	poller, err := r.routesClient.BeginCreateOrUpdate(ctx, r.AKSResourceGroup, routeTableName, route.name,
 		armnetwork.Route{
 			Properties: &armnetwork.RoutePropertiesFormat{
-				AddressPrefix:    to.Ptr(podCIDR),
+				AddressPrefix:    to.Ptr(route.prefix),
 				NextHopType:      to.Ptr(armnetwork.RouteNextHopTypeVirtualAppliance),
-				NextHopIPAddress: to.Ptr(nextHop),
+				NextHopIPAddress: to.Ptr(route.nextHop),
 			},
 		}, nil)
 	if err != nil {
@@ -374,10 +502,7 @@ func (r *RouteSyncReconciler) ensureRouterRouteTable(ctx context.Context) error
 		return fmt.Errorf("router subnet or VNet name not configured")
 	}
 
-	routeTableName := r.RouterRouteTableName
-	if routeTableName == "" {
-		routeTableName = "stargate-router-rt"
-	}
+	routeTableName := r.routerRouteTableName()
 
 	logger := r.Logger
 	if logger == nil {
@@ -432,15 +557,20 @@ func (r *RouteSyncReconciler) ensureRouterRouteTable(ctx context.Context) error
 	return nil
 }
 
+// routerRouteTableName returns the name of the router route table (default: stargate-router-rt)
+func (r *RouteSyncReconciler) routerRouteTableName() string {
+	if r.RouterRouteTableName != "" {
+		return r.RouterRouteTableName
+	}
+	return "stargate-router-rt"
+}
+
 // ensureRouterRouteForAKSNode adds a route in the router route table for an AKS node's pod CIDR.
 // This enables return traffic from DC workers back to AKS pods.
 func (r *RouteSyncReconciler) ensureRouterRouteForAKSNode(ctx context.Context, nodeName, podCIDR, nodeIP string) error {
-	routeTableName := r.RouterRouteTableName
-	if routeTableName == "" {
-		routeTableName = "stargate-router-rt"
-	}
+	routeTableName := r.routerRouteTableName()
 
-	routeName := fmt.Sprintf("aks-node-%s", sanitizeRouteName(nodeName))
+	routeName := aksNodeRouteName(nodeName)
 
 	// First check if a route for this podCIDR already exists (might have different name)
 	routeTable, err := r.routeTableClient.Get(ctx, r.AKSResourceGroup, routeTableName, nil)
@@ -456,154 +586,26 @@ func (r *RouteSyncReconciler) ensureRouterRouteForAKSNode(ctx context.Context, n
 				}
 				// Route exists but with wrong next hop or different name - delete it first
 				if existingRoute.Name != nil && *existingRoute.Name != routeName {
-					delPoller, err := r.routesClient.BeginDelete(ctx, r.AKSResourceGroup, routeTableName, *existingRoute.Name, nil)
-					if err == nil {
-						_, _ = delPoller.PollUntilDone(ctx, nil)
-					}
+					_, _ = r.mutate(ctx, azureRouteMutation(api.RouteActionDelete, routeTableName, azureRoute{name: *existingRoute.Name}, nodeName))
 				}
 				break
 			}
 		}
 	}
 
-	poller, err := r.routesClient.BeginCreateOrUpdate(ctx, r.AKSResourceGroup, routeTableName, routeName,
-		armnetwork.Route{
-			Properties: &armnetwork.RoutePropertiesFormat{
-				AddressPrefix:    to.Ptr(podCIDR),
-				NextHopType:      to.Ptr(armnetwork.RouteNextHopTypeVirtualAppliance),
-				NextHopIPAddress: to.Ptr(nodeIP),
-			},
-		}, nil)
+	route := azureRoute{name: routeName, prefix: podCIDR, nextHop: nodeIP}
+	applied, err := r.mutate(ctx, azureRouteMutation(api.RouteActionUpsert, routeTableName, route, nodeName))
 	if err != nil {
-		return fmt.Errorf("begin create router route: %w", err)
-	}
-
-	if _, err = poller.PollUntilDone(ctx, nil); err != nil {
 		return fmt.Errorf("create router route: %w", err)
 	}
 
-	if r.Logger != nil {
+	if applied && r.Logger != nil {
 		r.Logger.Info("Router route created for AKS node", "node", nodeName, "podCIDR", podCIDR, "nextHop", nodeIP)
 	}
 
 	return nil
 }
 
-// SyncAllAKSNodeRoutes synchronizes routes for all AKS nodes in the VMSS.
-// This is called periodically to ensure routes are up-to-date.
-func (r *RouteSyncReconciler) SyncAllAKSNodeRoutes(ctx context.Context) error {
-	logger := r.Logger
-	if logger == nil {
-		logger = slog.Default()
-	}
-
-	if err := r.ensureInitialized(ctx); err != nil {
-		return fmt.Errorf("initialize: %w", err)
-	}
-
-	// Find all VMSS in the AKS resource group
-	pager := r.vmssClient.NewListPager(r.AKSResourceGroup, nil)
-	for pager.More() {
-		page, err := pager.NextPage(ctx)
-		if err != nil {
-			return fmt.Errorf("list vmss: %w", err)
-		}
-
-		for _, vmss := range page.Value {
-			if vmss.Name == nil {
-				continue
-			}
-
-			// Skip non-AKS VMSS (AKS node pools start with "aks-")
-			if !strings.HasPrefix(*vmss.Name, "aks-") {
-				continue
-			}
-
-			logger.Info("Syncing routes for VMSS", "vmss", *vmss.Name)
-
-			// Get all VMs in this VMSS
-			vmPager := r.vmssVMsClient.NewListPager(r.AKSResourceGroup, *vmss.Name, nil)
-			for vmPager.More() {
-				vmPage, err := vmPager.NextPage(ctx)
-				if err != nil {
-					return fmt.Errorf("list vmss vms: %w", err)
-				}
-
-				for _, vm := range vmPage.Value {
-					if vm.Name == nil || vm.InstanceID == nil {
-						continue
-					}
-
-					// Get the VM's IP address from its NIC
-					ip, err := r.getVMSSVMPrivateIP(ctx, r.AKSResourceGroup, *vmss.Name, *vm.InstanceID)
-					if err != nil {
-						logger.Warn("Failed to get VM IP", "vm", *vm.Name, "error", err)
-						continue
-					}
-
-					// Derive pod CIDR from the VMSS instance index
-					// AKS Azure CNI Overlay assigns sequential /24 CIDRs
-					instanceID := *vm.InstanceID
-					podCIDR := fmt.Sprintf("10.244.%s.0/24", instanceID)
-
-					routeName := fmt.Sprintf("aks-node-%s-%s", *vmss.Name, *vm.InstanceID)
-
-					logger.Info("Ensuring route", "route", routeName, "podCIDR", podCIDR, "nextHop", ip)
-
-					poller, err := r.routesClient.BeginCreateOrUpdate(ctx, r.AKSResourceGroup, r.RouteTableName, routeName,
-						armnetwork.Route{
-							Properties: &armnetwork.RoutePropertiesFormat{
-								AddressPrefix:    to.Ptr(podCIDR),
-								NextHopType:      to.Ptr(armnetwork.RouteNextHopTypeVirtualAppliance),
-								NextHopIPAddress: to.Ptr(ip),
-							},
-						}, nil)
-					if err != nil {
-						logger.Warn("Failed to begin create route", "route", routeName, "error", err)
-						continue
-					}
-
-					if _, err := poller.PollUntilDone(ctx, nil); err != nil {
-						logger.Warn("Failed to create route", "route", routeName, "error", err)
-						continue
-					}
-				}
-			}
-		}
-	}
-
-	r.lastSync = time.Now()
-	return nil
-}
-
-// getVMSSVMPrivateIP gets the private IP of a VMSS VM instance
-func (r *RouteSyncReconciler) getVMSSVMPrivateIP(ctx context.Context, resourceGroup, vmssName, instanceID string) (string, error) {
-	// Get the network interface for this VM instance
-	nicName := fmt.Sprintf("%s_%s", vmssName, instanceID)
-
-	pager := r.nicClient.NewListVirtualMachineScaleSetVMNetworkInterfacesPager(resourceGroup, vmssName, instanceID, nil)
-	for pager.More() {
-		page, err := pager.NextPage(ctx)
-		if err != nil {
-			return "", fmt.Errorf("list nics for %s: %w", nicName, err)
-		}
-
-		for _, nic := range page.Value {
-			if nic.Properties == nil || nic.Properties.IPConfigurations == nil {
-				continue
-			}
-			for _, ipConfig := range nic.Properties.IPConfigurations {
-				if ipConfig.Properties == nil || ipConfig.Properties.PrivateIPAddress == nil {
-					continue
-				}
-				return *ipConfig.Properties.PrivateIPAddress, nil
-			}
-		}
-	}
-
-	return "", fmt.Errorf("no private IP found for VMSS VM %s/%s", vmssName, instanceID)
-}
-
 // EnableTailscaleRoutes enables routes for a Tailscale router device
 func (r *RouteSyncReconciler) EnableTailscaleRoutes(ctx context.Context, hostname string) error {
 	if r.tsClient == nil {
@@ -625,31 +627,8 @@ func (r *RouteSyncReconciler) EnableTailscaleRoutes(ctx context.Context, hostnam
 	return nil
 }
 
-// isStargateWorker checks if a node is a stargate DC worker
-func isStargateWorker(node *corev1.Node) bool {
-	// Check for stargate-specific labels
-	if _, ok := node.Labels["stargate.io/role"]; ok {
-		return true
-	}
-	// Check for DC worker naming convention
-	if strings.Contains(node.Name, "dc-") || strings.Contains(node.Name, "worker") {
-		return true
-	}
-	return false
-}
-
-// isAKSNode checks if a node is an AKS node (from VMSS)
-func isAKSNode(node *corev1.Node) bool {
-	// Check for AKS node naming convention (aks-nodepool...)
-	if strings.HasPrefix(node.Name, "aks-") {
-		return true
-	}
-	// Check for VMSS naming pattern
-	if strings.Contains(node.Name, "vmss") {
-		return true
-	}
-	return false
-}
+// tailscaleDevice is the Tailscale interface on the routers
+const tailscaleDevice = "tailscale0"
 
 // getNodeInternalIP returns the internal IP of a node
 func getNodeInternalIP(node *corev1.Node) string {
@@ -661,6 +640,23 @@ func getNodeInternalIP(node *corev1.Node) string {
 	return ""
 }
 
+// Prefixes of the names of the routes route sync programs. Only routes carrying them are
+// removed as stale by the route table resync.
+const (
+	workerRouteNamePrefix  = "stargate-" // DC worker routes in RouteTableName
+	aksNodeRouteNamePrefix = "aks-node-" // AKS node routes in the router route table
+)
+
+// workerRouteName returns the name of a DC worker's route in RouteTableName
+func workerRouteName(nodeName string) string {
+	return workerRouteNamePrefix + sanitizeRouteName(nodeName)
+}
+
+// aksNodeRouteName returns the name of an AKS node's route in the router route table
+func aksNodeRouteName(nodeName string) string {
+	return aksNodeRouteNamePrefix + sanitizeRouteName(nodeName)
+}
+
 // sanitizeRouteName converts a node name to a valid Azure route name
 func sanitizeRouteName(name string) string {
 	// Azure route names: alphanumeric, hyphens, underscores, periods
@@ -690,16 +686,20 @@ func (r *RouteSyncReconciler) initSSHClientConfig() error {
 		return fmt.Errorf("parse SSH key: %w", err)
 	}
 
-	r.sshClientConfig = &ssh.ClientConfig{
-		User: "ubuntu",
+	r.sshClientConfig = newRouterSSHConfig("ubuntu", signer)
+	return nil
+}
+
+// newRouterSSHConfig returns the SSH client config used to run commands on routers
+func newRouterSSHConfig(user string, signer ssh.Signer) *ssh.ClientConfig {
+	return &ssh.ClientConfig{
+		User: user,
 		Auth: []ssh.AuthMethod{
 			ssh.PublicKeys(signer),
 		},
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
 		Timeout:         10 * time.Second,
 	}
-
-	return nil
 }
 
 // discoverAKSRouterIP discovers the AKS router IP from the router subnet
@@ -765,13 +765,13 @@ func (r *RouteSyncReconciler) discoverVNetName(ctx context.Context) (string, err
 	return "", fmt.Errorf("no VNet found in resource group %s", r.AKSResourceGroup)
 }
 
-// runSSHCommand executes a command on a remote host via SSH
-func (r *RouteSyncReconciler) runSSHCommand(host string, command string) (string, error) {
-	if r.sshClientConfig == nil {
+// runSSHCommandWith executes a command on a remote host via SSH with the given client config
+func runSSHCommandWith(config *ssh.ClientConfig, host string, command string) (string, error) {
+	if config == nil {
 		return "", fmt.Errorf("SSH client not configured")
 	}
 
-	client, err := ssh.Dial("tcp", host+":22", r.sshClientConfig)
+	client, err := ssh.Dial("tcp", host+":22", config)
 	if err != nil {
 		return "", fmt.Errorf("SSH dial: %w", err)
 	}
@@ -798,147 +798,44 @@ func (r *RouteSyncReconciler) runSSHCommand(host string, command string) (string
 	return stdout.String(), nil
 }
 
-// ensureAKSRouterKernelRoute adds a kernel route on the AKS router for a pod CIDR via tailscale0
-func (r *RouteSyncReconciler) ensureAKSRouterKernelRoute(ctx context.Context, podCIDR string) error {
-	cmd := fmt.Sprintf("sudo ip route replace %s dev tailscale0", podCIDR)
-	_, err := r.runSSHCommand(r.AKSRouterTSIP, cmd)
-	return err
+// ensureAKSRouterKernelRoute adds a kernel route on the AKS router for a pod CIDR via tailscale0.
+// It returns true if the route was applied rather than planned.
+func (r *RouteSyncReconciler) ensureAKSRouterKernelRoute(ctx context.Context, nodeName, podCIDR string) (bool, error) {
+	return r.mutate(ctx, kernelRouteMutation(api.RouteActionUpsert, aksRouterKey, podCIDR, "", tailscaleDevice, nodeName))
 }
 
-// ensureDCRouterKernelRoute adds a kernel route on the DC router for a pod CIDR to a worker IP
-func (r *RouteSyncReconciler) ensureDCRouterKernelRoute(ctx context.Context, podCIDR, workerIP string) error {
-	cmd := fmt.Sprintf("sudo ip route replace %s via %s", podCIDR, workerIP)
-	_, err := r.runSSHCommand(r.DCRouterTSIP, cmd)
-	return err
+// ensureDCRouterKernelRoute adds a kernel route on a DC router for a pod CIDR to a worker IP.
+// It returns true if the route was applied rather than planned.
+func (r *RouteSyncReconciler) ensureDCRouterKernelRoute(ctx context.Context, router *dcRouter, nodeName, podCIDR, workerIP string) (bool, error) {
+	return r.mutate(ctx, kernelRouteMutation(api.RouteActionUpsert, router.key(), podCIDR, workerIP, "", nodeName))
 }
 
-// updateDCRouterTailscaleRoutes updates Tailscale route advertisements on the DC router
-func (r *RouteSyncReconciler) updateDCRouterTailscaleRoutes(ctx context.Context, newPodCIDR string) error {
-	if r.tsClient == nil {
-		return fmt.Errorf("Tailscale client not configured")
-	}
-
-	// Get DC router device
+// findDCRouterDevice returns a DC router's Tailscale device
+func (r *RouteSyncReconciler) findDCRouterDevice(ctx context.Context, router *dcRouter) (*tailscale.Device, error) {
 	devices, err := r.tsClient.ListDevices(ctx)
 	if err != nil {
-		return fmt.Errorf("list devices: %w", err)
+		return nil, fmt.Errorf("list devices: %w", err)
 	}
-
-	var dcRouterDevice *tailscale.Device
 	for _, d := range devices {
-		if d.TailscaleIP == r.DCRouterTSIP || strings.Contains(d.Hostname, "dc-router") {
-			dcRouterDevice = d
-			break
+		if d.TailscaleIP == router.tailscaleIP || router.matchesHostname(d.Hostname) {
+			return d, nil
 		}
 	}
-
-	if dcRouterDevice == nil {
-		return fmt.Errorf("DC router not found in Tailscale devices")
-	}
-
-	// Fetch current routes from the dedicated routes endpoint (more accurate than device list)
-	currentRoutes, err := r.tsClient.GetDeviceRoutes(ctx, dcRouterDevice.ID)
-	if err != nil {
-		return fmt.Errorf("get device routes: %w", err)
-	}
-
-	// Build route list including the DC subnet and all known pod CIDRs
-	routes := []string{r.DCSubnetCIDR}
-
-	// Add existing enabled routes (using the accurate routes endpoint data)
-	for _, route := range currentRoutes.EnabledRoutes {
-		if route != newPodCIDR && route != r.DCSubnetCIDR {
-			routes = append(routes, route)
-		}
-	}
-
-	// Add the new pod CIDR
-	routes = append(routes, newPodCIDR)
-
-	// SSH to DC router to advertise routes
-	routeList := strings.Join(routes, ",")
-	cmd := fmt.Sprintf("sudo tailscale set --advertise-routes=%s", routeList)
-	if _, err := r.runSSHCommand(r.DCRouterTSIP, cmd); err != nil {
-		return fmt.Errorf("advertise routes: %w", err)
-	}
-
-	// Enable routes via Tailscale API
-	if err := r.tsClient.EnableRoutes(ctx, dcRouterDevice.ID, routes); err != nil {
-		return fmt.Errorf("enable routes: %w", err)
-	}
-
-	return nil
+	return nil, fmt.Errorf("DC router %s not found in Tailscale devices", router.tailscaleIP)
 }
 
-// updateAKSRouterTailscaleRoutes updates Tailscale route advertisements on the AKS router.
-// The AKS router must advertise:
-// - AKS node subnet (e.g., 10.224.0.0/16) so DC workers can reach AKS nodes
-// - AKS pod CIDRs (e.g., 10.244.0.0/24, 10.244.1.0/24) for return traffic
-func (r *RouteSyncReconciler) updateAKSRouterTailscaleRoutes(ctx context.Context, newPodCIDR string) error {
-	if r.tsClient == nil {
-		return fmt.Errorf("Tailscale client not configured")
-	}
-
-	// Get AKS router device
+// findAKSRouterDevice returns the AKS router's Tailscale device
+func (r *RouteSyncReconciler) findAKSRouterDevice(ctx context.Context) (*tailscale.Device, error) {
 	devices, err := r.tsClient.ListDevices(ctx)
 	if err != nil {
-		return fmt.Errorf("list devices: %w", err)
+		return nil, fmt.Errorf("list devices: %w", err)
 	}
-
-	var aksRouterDevice *tailscale.Device
 	for _, d := range devices {
 		if d.TailscaleIP == r.AKSRouterTSIP || strings.Contains(d.Hostname, "router") && !strings.Contains(d.Hostname, "dc") {
-			aksRouterDevice = d
-			break
-		}
-	}
-
-	if aksRouterDevice == nil {
-		return fmt.Errorf("AKS router not found in Tailscale devices")
-	}
-
-	// Fetch current routes from the dedicated routes endpoint (more accurate than device list)
-	currentRoutes, err := r.tsClient.GetDeviceRoutes(ctx, aksRouterDevice.ID)
-	if err != nil {
-		return fmt.Errorf("get device routes: %w", err)
-	}
-
-	// Build route list: AKS node subnet + all known AKS pod CIDRs
-	// The AKS node subnet (10.224.0.0/16) allows DC router to reach AKS node IPs
-	routes := []string{"10.224.0.0/16"} // TODO: Make configurable
-
-	// Add existing enabled routes (using the accurate routes endpoint data)
-	// IMPORTANT: Filter out broad pod CIDRs like 10.244.0.0/16 - these cause routing
-	// conflicts on the DC router where it routes DC worker pods back through Tailscale
-	// instead of locally. Only specific /24 pod CIDRs should be advertised.
-	logger := log.FromContext(ctx)
-	for _, route := range currentRoutes.EnabledRoutes {
-		if route == newPodCIDR || route == "10.224.0.0/16" {
-			continue // Will be added separately
+			return d, nil
 		}
-		if isBroadPodCIDR(route) {
-			logger.Info("Filtering out broad pod CIDR from AKS router routes", "cidr", route)
-			continue
-		}
-		routes = append(routes, route)
 	}
-
-	// Add the new pod CIDR
-	routes = append(routes, newPodCIDR)
-
-	// SSH to AKS router to advertise routes
-	routeList := strings.Join(routes, ",")
-	cmd := fmt.Sprintf("sudo tailscale set --advertise-routes=%s", routeList)
-	if _, err := r.runSSHCommand(r.AKSRouterTSIP, cmd); err != nil {
-		return fmt.Errorf("advertise routes: %w", err)
-	}
-
-	// Enable routes via Tailscale API
-	if err := r.tsClient.EnableRoutes(ctx, aksRouterDevice.ID, routes); err != nil {
-		return fmt.Errorf("enable routes: %w", err)
-	}
-
-	return nil
+	return nil, fmt.Errorf("AKS router not found in Tailscale devices")
 }
 
 // getCiliumNodePodCIDR fetches the podCIDR from a CiliumNode resource.
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// dcRouter is the Tailscale subnet router a DC worker's routes are programmed on
+type dcRouter struct {
+	// datacenter is the "namespace/name" of the Datacenter, or "" for the router set by flags
+	datacenter   string
+	tailscaleIP  string
+	hostname     string
+	workerSubnet string
+	sshConfig    *ssh.ClientConfig
+}
+
+// matchesHostname returns true if a Tailscale device with hostname is the router. Without a
+// configured hostname, the router set by flags matches any "dc-router" host.
+func (d *dcRouter) matchesHostname(hostname string) bool {
+	if d.hostname != "" {
+		return hostname == d.hostname
+	}
+	return d.datacenter == "" && strings.Contains(hostname, "dc-router")
+}
+
+// key identifies the router in the record of applied advertisements
+func (d *dcRouter) key() string {
+	if d.datacenter == "" {
+		return "dc"
+	}
+	return "dc/" + d.datacenter
+}
+
+// nodeDatacenter returns the "namespace/name" of the Datacenter of the Server a Node was
+// bootstrapped from, or "" if the Server has none
+func (r *RouteSyncReconciler) nodeDatacenter(ctx context.Context, node *corev1.Node) (string, error) {
+	var servers api.ServerList
+	if err := r.List(ctx, &servers); err != nil {
+		return "", fmt.Errorf("list servers: %w", err)
+	}
+	return serverDatacenter(servers.Items, node.Name), nil
+}
+
+// serverDatacenter returns the "namespace/name" of the Datacenter of the Server named
+// nodeName, or "" if there is no such Server or it has no Datacenter
+func serverDatacenter(servers []api.Server, nodeName string) string {
+	for i := range servers {
+		server := &servers[i]
+		if server.Name == nodeName && server.Spec.DatacenterRef != nil && server.Spec.DatacenterRef.Name != "" {
+			return server.Namespace + "/" + server.Spec.DatacenterRef.Name
+		}
+	}
+	return ""
+}
+
+// dcRouterFor returns the router of a Datacenter ("namespace/name"), or the router set by the
+// controller's flags if datacenter is ""
+func (r *RouteSyncReconciler) dcRouterFor(ctx context.Context, datacenter string) (*dcRouter, error) {
+	if datacenter == "" {
+		return &dcRouter{
+			tailscaleIP:  r.DCRouterTSIP,
+			workerSubnet: r.DCSubnetCIDR,
+			sshConfig:    r.sshClientConfig,
+		}, nil
+	}
+
+	namespace, name, _ := strings.Cut(datacenter, "/")
+	var dc api.Datacenter
+	if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &dc); err != nil {
+		return nil, fmt.Errorf("get datacenter %s: %w", datacenter, err)
+	}
+
+	router := &dcRouter{
+		datacenter:   datacenter,
+		tailscaleIP:  dc.Spec.Router.TailscaleIP,
+		hostname:     dc.Spec.Router.Hostname,
+		workerSubnet: dc.Spec.WorkerSubnet,
+		sshConfig:    r.sshClientConfig,
+	}
+	if ref := dc.Spec.SSHCredentialsSecretRef; ref != "" {
+		config, err := r.sshConfigFromSecret(ctx, namespace, ref)
+		if err != nil {
+			return nil, fmt.Errorf("datacenter %s: %w", datacenter, err)
+		}
+		router.sshConfig = config
+	}
+	return router, nil
+}
+
+// sshConfigFromSecret builds a router SSH client config from a Secret with keys "privateKey"
+// and optionally "username" (default: ubuntu)
+func (r *RouteSyncReconciler) sshConfigFromSecret(ctx context.Context, namespace, name string) (*ssh.ClientConfig, error) {
+	var secret corev1.Secret
+	if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &secret); err != nil {
+		return nil, fmt.Errorf("get SSH credentials secret %s: %w", name, err)
+	}
+	key, ok := secret.Data["privateKey"]
+	if !ok {
+		return nil, fmt.Errorf("SSH credentials secret %s has no privateKey", name)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("parse SSH key from secret %s: %w", name, err)
+	}
+	user := "ubuntu"
+	if username, ok := secret.Data["username"]; ok && len(username) > 0 {
+		user = string(username)
+	}
+	return newRouterSSHConfig(user, signer), nil
+}
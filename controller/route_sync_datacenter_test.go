@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+func TestServerDatacenter(t *testing.T) {
+	server := func(namespace, name, datacenter string) api.Server {
+		s := api.Server{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+		if datacenter != "" {
+			s.Spec.DatacenterRef = &api.LocalObjectReference{Name: datacenter}
+		}
+		return s
+	}
+	servers := []api.Server{
+		server("azure-dc", "dc-worker-1", "dc1"),
+		server("azure-dc", "dc-worker-2", ""),
+		server("site-3", "dc-worker-3", "dc3"),
+	}
+
+	tests := []struct {
+		node string
+		want string
+	}{
+		{"dc-worker-1", "azure-dc/dc1"},
+		{"dc-worker-2", ""},
+		{"dc-worker-3", "site-3/dc3"},
+		{"dc-worker-4", ""},
+	}
+	for _, tt := range tests {
+		if got := serverDatacenter(servers, tt.node); got != tt.want {
+			t.Errorf("serverDatacenter(%s) = %q, want %q", tt.node, got, tt.want)
+		}
+	}
+}
+
+func TestDCRouterMatchesHostname(t *testing.T) {
+	tests := []struct {
+		name     string
+		router   dcRouter
+		hostname string
+		want     bool
+	}{
+		{"flags router matches any dc-router", dcRouter{}, "stargate-dc-router-1", true},
+		{"flags router ignores other hosts", dcRouter{}, "aks-router", false},
+		{"datacenter router without hostname", dcRouter{datacenter: "azure-dc/dc1"}, "stargate-dc-router-1", false},
+		{"datacenter router hostname", dcRouter{datacenter: "azure-dc/dc3", hostname: "dc3-router"}, "dc3-router", true},
+		{"hostname is matched exactly", dcRouter{datacenter: "azure-dc/dc3", hostname: "dc3-router"}, "dc3-router-old", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.router.matchesHostname(tt.hostname); got != tt.want {
+				t.Errorf("matchesHostname(%q) = %v, want %v", tt.hostname, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,593 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+	"github.com/vpatelsj/stargate/pkg/tailscale"
+)
+
+// Route sync modes
+const (
+	// RouteSyncModeAuto applies every route mutation as soon as it is computed
+	RouteSyncModeAuto = "auto"
+	// RouteSyncModePlan only publishes the route mutations, to the RouteSyncPlan, its ConfigMap
+	// and the logs
+	RouteSyncModePlan = "plan"
+	// RouteSyncModeApply publishes the route mutations like plan mode, and applies them once the
+	// RouteSyncPlan is approved
+	RouteSyncModeApply = "apply"
+)
+
+// RouteSyncPlanName is the name of the RouteSyncPlan and of the ConfigMap the plan is published to
+const RouteSyncPlanName = "route-sync"
+
+// DefaultRouteSyncPlanNamespace is the namespace the plan is published to by default
+const DefaultRouteSyncPlanNamespace = "default"
+
+// ValidRouteSyncMode returns true if mode is a route sync mode
+func ValidRouteSyncMode(mode string) bool {
+	switch mode {
+	case RouteSyncModeAuto, RouteSyncModePlan, RouteSyncModeApply:
+		return true
+	}
+	return false
+}
+
+// plannedMutation is a mutation waiting in the plan
+type plannedMutation struct {
+	seq      uint64 // orders the plan by when the mutation was last planned
+	mutation api.RouteMutation
+}
+
+// routePlan holds the route mutations planned outside auto mode. Mutations are keyed by what
+// they change, so a later mutation of the same route replaces an earlier one. A mutation
+// identical to the last one applied to its route is dropped, since it would change nothing.
+type routePlan struct {
+	mu      sync.Mutex
+	seq     uint64
+	pending map[string]plannedMutation
+	applied map[string]api.RouteMutation
+
+	// publishMu serializes publishing the plan and applying it
+	publishMu    sync.Mutex
+	published    string // hash of the last plan published
+	hasPublished bool
+}
+
+// mutationKey identifies what a mutation changes: an Azure route, a kernel route on a router, or
+// a router's Tailscale advertisement
+func mutationKey(m api.RouteMutation) string {
+	switch m.Layer {
+	case api.RouteLayerAzureRouteTable:
+		return strings.Join([]string{string(m.Layer), m.RouteTable, m.Name}, "|")
+	case api.RouteLayerKernelRoute:
+		return strings.Join([]string{string(m.Layer), m.Router, m.Route, m.NextHop, m.Device}, "|")
+	default:
+		return strings.Join([]string{string(m.Layer), m.Router}, "|")
+	}
+}
+
+// add plans a mutation. It returns true if the plan changed.
+func (p *routePlan) add(m api.RouteMutation) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := mutationKey(m)
+	current, pending := p.pending[key]
+	if applied, ok := p.applied[key]; ok && sameMutation(applied, m) {
+		delete(p.pending, key)
+		return pending
+	}
+	if pending && sameMutation(current.mutation, m) {
+		return false
+	}
+	if p.pending == nil {
+		p.pending = map[string]plannedMutation{}
+	}
+	p.seq++
+	p.pending[key] = plannedMutation{seq: p.seq, mutation: m}
+	return true
+}
+
+// markApplied records that a mutation was applied, and takes it out of the plan unless its route
+// has been planned differently since
+func (p *routePlan) markApplied(m api.RouteMutation) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := mutationKey(m)
+	if current, ok := p.pending[key]; ok && sameMutation(current.mutation, m) {
+		delete(p.pending, key)
+	}
+	if p.applied == nil {
+		p.applied = map[string]api.RouteMutation{}
+	}
+	p.applied[key] = m
+}
+
+// settle records that a mutation's route already is in the state the mutation leads to, which
+// takes any mutation of the route out of the plan
+func (p *routePlan) settle(m api.RouteMutation) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := mutationKey(m)
+	delete(p.pending, key)
+	if p.applied == nil {
+		p.applied = map[string]api.RouteMutation{}
+	}
+	p.applied[key] = m
+}
+
+// pendingFor returns true if a mutation of a Node's routes is waiting in the plan
+func (p *routePlan) pendingFor(nodeName string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, pm := range p.pending {
+		if pm.mutation.Node == nodeName {
+			return true
+		}
+	}
+	return false
+}
+
+// mutations returns the planned mutations in the order they were planned
+func (p *routePlan) mutations() []api.RouteMutation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	planned := make([]plannedMutation, 0, len(p.pending))
+	for _, pm := range p.pending {
+		planned = append(planned, pm)
+	}
+	sort.Slice(planned, func(i, j int) bool { return planned[i].seq < planned[j].seq })
+
+	mutations := make([]api.RouteMutation, 0, len(planned))
+	for _, pm := range planned {
+		mutations = append(mutations, pm.mutation)
+	}
+	return mutations
+}
+
+// sameMutation returns true if two mutations make the same change. The Node is left out: the
+// route table resync plans the routes of Nodes without naming them.
+func sameMutation(a, b api.RouteMutation) bool {
+	a.Node, b.Node = "", ""
+	return reflect.DeepEqual(a, b)
+}
+
+// planHash identifies a list of mutations, or returns "" if it is empty
+func planHash(mutations []api.RouteMutation) string {
+	if len(mutations) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(mutations)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// describeMutation returns a one-line description of a mutation
+func describeMutation(m api.RouteMutation) string {
+	var desc string
+	switch {
+	case m.Layer == api.RouteLayerAzureRouteTable && m.Action == api.RouteActionUpsert:
+		desc = fmt.Sprintf("upsert Azure route %s in %s: %s via %s", m.Name, m.RouteTable, m.Route, m.NextHop)
+	case m.Layer == api.RouteLayerAzureRouteTable && m.Action == api.RouteActionDelete:
+		desc = fmt.Sprintf("delete Azure route %s from %s", m.Name, m.RouteTable)
+	case m.Layer == api.RouteLayerKernelRoute && m.Action == api.RouteActionUpsert:
+		desc = fmt.Sprintf("add kernel route %s on router %s", kernelRouteSpec(m), m.Router)
+	case m.Layer == api.RouteLayerKernelRoute && m.Action == api.RouteActionDelete:
+		desc = fmt.Sprintf("delete kernel route %s on router %s", kernelRouteSpec(m), m.Router)
+	case m.Layer == api.RouteLayerTailscale:
+		desc = fmt.Sprintf("advertise %s on router %s", strings.Join(m.Routes, ","), m.Router)
+	default:
+		desc = fmt.Sprintf("%s %s on router %s", m.Action, m.Layer, m.Router)
+	}
+	if m.Node != "" {
+		desc += fmt.Sprintf(" (node %s)", m.Node)
+	}
+	return desc
+}
+
+// renderPlan returns the human-readable plan published to the ConfigMap
+func renderPlan(mutations []api.RouteMutation) string {
+	if len(mutations) == 0 {
+		return "No route mutations planned.\n"
+	}
+	var b strings.Builder
+	for i, m := range mutations {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, describeMutation(m))
+	}
+	return b.String()
+}
+
+// azureRouteMutation returns the mutation upserting or deleting a route in an Azure route table
+func azureRouteMutation(action api.RouteAction, routeTableName string, route azureRoute, nodeName string) api.RouteMutation {
+	return api.RouteMutation{
+		Layer:      api.RouteLayerAzureRouteTable,
+		Action:     action,
+		Router:     aksRouterKey,
+		RouteTable: routeTableName,
+		Name:       route.name,
+		Route:      route.prefix,
+		NextHop:    route.nextHop,
+		Node:       nodeName,
+	}
+}
+
+// kernelRouteMutation returns the mutation adding or deleting a kernel route on a router, to
+// prefix via nextHop or, without a next hop, via device
+func kernelRouteMutation(action api.RouteAction, router, prefix, nextHop, device, nodeName string) api.RouteMutation {
+	return api.RouteMutation{Layer: api.RouteLayerKernelRoute, Action: action, Router: router, Route: prefix, NextHop: nextHop, Device: device, Node: nodeName}
+}
+
+// kernelRouteSpec returns the kernel route of a mutation as ip route takes it. It is only for
+// display; commands are built by parseKernelRoute.
+func kernelRouteSpec(m api.RouteMutation) string {
+	if m.NextHop != "" {
+		return fmt.Sprintf("%s via %s", m.Route, m.NextHop)
+	}
+	return fmt.Sprintf("%s dev %s", m.Route, m.Device)
+}
+
+// kernelRouteDevices are the devices kernel routes without a next hop may use
+var kernelRouteDevices = map[string]bool{tailscaleDevice: true}
+
+// parseKernelRoute validates a kernel route mutation and returns its route as ip route takes
+// it, rebuilt from the parsed prefix, next hop and device. Mutations come from a status anyone
+// with access can write, and the route ends up in a command run as root on a router.
+func parseKernelRoute(m api.RouteMutation) (string, error) {
+	prefix, err := netip.ParsePrefix(m.Route)
+	if err != nil {
+		return "", fmt.Errorf("invalid kernel route prefix %q: %w", m.Route, err)
+	}
+	switch {
+	case m.NextHop != "" && m.Device != "":
+		return "", fmt.Errorf("kernel route %s has both a next hop and a device", prefix)
+	case m.NextHop != "":
+		nextHop, err := netip.ParseAddr(m.NextHop)
+		if err != nil {
+			return "", fmt.Errorf("invalid kernel route next hop %q: %w", m.NextHop, err)
+		}
+		return fmt.Sprintf("%s via %s", prefix, nextHop), nil
+	case kernelRouteDevices[m.Device]:
+		return fmt.Sprintf("%s dev %s", prefix, m.Device), nil
+	default:
+		return "", fmt.Errorf("kernel route %s has no next hop and device %q is not allowed", prefix, m.Device)
+	}
+}
+
+// parseAdvertisedRoutes validates the routes of a Tailscale mutation and returns them rebuilt
+// from the parsed prefixes
+func parseAdvertisedRoutes(routes []string) ([]string, error) {
+	parsed := make([]string, 0, len(routes))
+	for _, route := range routes {
+		prefix, err := netip.ParsePrefix(route)
+		if err != nil {
+			return nil, fmt.Errorf("invalid advertised route %q: %w", route, err)
+		}
+		parsed = append(parsed, prefix.String())
+	}
+	return parsed, nil
+}
+
+// validateAzureRoute validates an Azure route mutation. Upserts need a prefix and a next hop IP.
+func validateAzureRoute(m api.RouteMutation) error {
+	if m.RouteTable == "" || m.Name == "" {
+		return fmt.Errorf("Azure route mutation needs a route table and a name")
+	}
+	if m.Action == api.RouteActionDelete {
+		return nil
+	}
+	if _, err := netip.ParsePrefix(m.Route); err != nil {
+		return fmt.Errorf("invalid Azure route prefix %q: %w", m.Route, err)
+	}
+	if _, err := netip.ParseAddr(m.NextHop); err != nil {
+		return fmt.Errorf("invalid Azure route next hop %q: %w", m.NextHop, err)
+	}
+	return nil
+}
+
+// planning returns true if route mutations are planned instead of applied right away
+func (r *RouteSyncReconciler) planning() bool {
+	return r.Mode == RouteSyncModePlan || r.Mode == RouteSyncModeApply
+}
+
+// planNamespace returns the namespace the plan is published to
+func (r *RouteSyncReconciler) planNamespace() string {
+	if r.PlanNamespace != "" {
+		return r.PlanNamespace
+	}
+	return DefaultRouteSyncPlanNamespace
+}
+
+// mutate applies a route mutation in auto mode, and only plans it otherwise. It returns true if
+// the mutation was applied.
+func (r *RouteSyncReconciler) mutate(ctx context.Context, m api.RouteMutation) (bool, error) {
+	if !r.planning() {
+		if err := r.executeMutation(ctx, m); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if r.plan.add(m) {
+		logger := r.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Info("Planned route mutation", "mode", r.Mode, "mutation", describeMutation(m))
+	}
+	return false, nil
+}
+
+// observeSync records a route sync attempt in the metrics. In plan and apply mode a pass only
+// plans mutations, so it records failures but not successes: nothing was applied.
+// Mutations applied from an approved plan are recorded by reconcilePlan.
+func (r *RouteSyncReconciler) observeSync(layer, router string, err error) {
+	if err == nil && r.planning() {
+		return
+	}
+	observeRouteSync(layer, router, err)
+}
+
+// mutationRouter is the router a mutation is applied on
+type mutationRouter struct {
+	tailscaleIP string
+	sshConfig   *ssh.ClientConfig
+	findDevice  func(context.Context) (*tailscale.Device, error)
+}
+
+// mutationRouterFor resolves a mutation's router: "aks", or a DC router key
+func (r *RouteSyncReconciler) mutationRouterFor(ctx context.Context, key string) (*mutationRouter, error) {
+	if key == aksRouterKey {
+		return &mutationRouter{tailscaleIP: r.AKSRouterTSIP, sshConfig: r.sshClientConfig, findDevice: r.findAKSRouterDevice}, nil
+	}
+	if key != "dc" && !strings.HasPrefix(key, "dc/") {
+		return nil, fmt.Errorf("unknown router %q", key)
+	}
+	router, err := r.dcRouterFor(ctx, strings.TrimPrefix(strings.TrimPrefix(key, "dc"), "/"))
+	if err != nil {
+		return nil, err
+	}
+	findDevice := func(ctx context.Context) (*tailscale.Device, error) { return r.findDCRouterDevice(ctx, router) }
+	return &mutationRouter{tailscaleIP: router.tailscaleIP, sshConfig: router.sshConfig, findDevice: findDevice}, nil
+}
+
+// executeMutation validates a route mutation and applies it
+func (r *RouteSyncReconciler) executeMutation(ctx context.Context, m api.RouteMutation) error {
+	switch {
+	case m.Layer == api.RouteLayerAzureRouteTable && (m.Action == api.RouteActionUpsert || m.Action == api.RouteActionDelete),
+		m.Layer == api.RouteLayerKernelRoute && (m.Action == api.RouteActionUpsert || m.Action == api.RouteActionDelete),
+		m.Layer == api.RouteLayerTailscale && m.Action == api.RouteActionAdvertise:
+	default:
+		return fmt.Errorf("unsupported %s action %q", m.Layer, m.Action)
+	}
+
+	if m.Layer == api.RouteLayerAzureRouteTable {
+		if err := validateAzureRoute(m); err != nil {
+			return err
+		}
+		if m.Action == api.RouteActionDelete {
+			return r.deleteAzureRoute(ctx, m.RouteTable, m.Name)
+		}
+		return r.putAzureRoute(ctx, m.RouteTable, azureRoute{name: m.Name, prefix: m.Route, nextHop: m.NextHop})
+	}
+
+	if m.Layer == api.RouteLayerKernelRoute {
+		route, err := parseKernelRoute(m)
+		if err != nil {
+			return err
+		}
+		router, err := r.mutationRouterFor(ctx, m.Router)
+		if err != nil {
+			return err
+		}
+		if m.Action == api.RouteActionDelete {
+			return deleteKernelRoute(router.sshConfig, router.tailscaleIP, route)
+		}
+		_, err = runSSHCommandWith(router.sshConfig, router.tailscaleIP, "sudo ip route replace "+route)
+		return err
+	}
+
+	routes, err := parseAdvertisedRoutes(m.Routes)
+	if err != nil {
+		return err
+	}
+	if r.tsClient == nil {
+		return fmt.Errorf("Tailscale client not configured")
+	}
+	router, err := r.mutationRouterFor(ctx, m.Router)
+	if err != nil {
+		return err
+	}
+	device, err := router.findDevice(ctx)
+	if err != nil {
+		return err
+	}
+	cmd := fmt.Sprintf("sudo tailscale set --advertise-routes=%s", strings.Join(routes, ","))
+	if _, err := runSSHCommandWith(router.sshConfig, router.tailscaleIP, cmd); err != nil {
+		return fmt.Errorf("advertise routes: %w", err)
+	}
+	if err := r.tsClient.EnableRoutes(ctx, device.ID, routes); err != nil {
+		return fmt.Errorf("enable routes: %w", err)
+	}
+	r.recordAdvertisement(ctx, m.Router, newRouterAdvertisement(routes, nil))
+	return nil
+}
+
+// publishPlan publishes the planned mutations to the RouteSyncPlan's status, the ConfigMap of
+// the same name and the logs, if they changed since they were last published
+func (r *RouteSyncReconciler) publishPlan(ctx context.Context) error {
+	r.plan.publishMu.Lock()
+	defer r.plan.publishMu.Unlock()
+
+	mutations := r.plan.mutations()
+	hash := planHash(mutations)
+	if r.plan.hasPublished && hash == r.plan.published {
+		return nil
+	}
+
+	var plan api.RouteSyncPlan
+	key := client.ObjectKey{Namespace: r.planNamespace(), Name: RouteSyncPlanName}
+	if err := r.Get(ctx, key, &plan); apierrors.IsNotFound(err) {
+		plan = api.RouteSyncPlan{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+		if err := r.Create(ctx, &plan); err != nil {
+			return fmt.Errorf("create route sync plan: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("get route sync plan: %w", err)
+	}
+
+	now := metav1.Now()
+	plan.Status.Mode = r.Mode
+	plan.Status.Mutations = mutations
+	plan.Status.Hash = hash
+	plan.Status.LastUpdated = &now
+	if err := r.Status().Update(ctx, &plan); err != nil {
+		return fmt.Errorf("update route sync plan status: %w", err)
+	}
+
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		configMap.Data = map[string]string{"plan": renderPlan(mutations), "hash": hash}
+		return controllerutil.SetControllerReference(&plan, configMap, r.Scheme)
+	}); err != nil {
+		return fmt.Errorf("publish route sync plan configmap: %w", err)
+	}
+
+	r.plan.published, r.plan.hasPublished = hash, true
+
+	logger := r.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.Info("Published route sync plan", "mode", r.Mode, "hash", hash, "mutations", len(mutations),
+		"plan", fmt.Sprintf("%s/%s", key.Namespace, key.Name))
+	return nil
+}
+
+// publishPlanOrLog publishes the plan after a pass that may have planned mutations, logging
+// failures: the next pass retries
+func (r *RouteSyncReconciler) publishPlanOrLog(ctx context.Context) {
+	if err := r.publishPlan(ctx); err != nil {
+		logger := r.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Warn("Failed to publish route sync plan", "error", err)
+	}
+}
+
+// reconcilePlan applies the planned mutations once spec.approvedHash approves them. Only the
+// controller's own plan is applied, and only if it still hashes to the approved hash: the
+// mutations in the status are what was approved, but anyone who can write the status could have
+// swapped them. Every mutation is attempted in order; the ones that fail stay in the next plan.
+func (r *RouteSyncReconciler) reconcilePlan(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if req.Namespace != r.planNamespace() || req.Name != RouteSyncPlanName {
+		return ctrl.Result{}, nil
+	}
+	logger := r.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	var plan api.RouteSyncPlan
+	if err := r.Get(ctx, req.NamespacedName, &plan); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	hash := plan.Status.Hash
+	if hash == "" || plan.Spec.ApprovedHash != hash || plan.Status.AppliedHash == hash {
+		return ctrl.Result{}, nil
+	}
+	if err := r.ensureInitialized(ctx); err != nil {
+		return ctrl.Result{}, fmt.Errorf("initialize: %w", err)
+	}
+
+	r.plan.publishMu.Lock()
+	mutations := r.plan.mutations()
+	if planHash(mutations) != hash || planHash(plan.Status.Mutations) != hash {
+		logger.Warn("Approved route sync plan doesn't match the planned mutations, not applying it", "hash", hash,
+			"plannedHash", planHash(mutations), "statusHash", planHash(plan.Status.Mutations))
+		plan.Status.Message = fmt.Sprintf("Not applied: plan %s doesn't match the planned mutations", hash)
+		err := r.Status().Update(ctx, &plan)
+		// Republish, so the status shows the planned mutations again
+		r.plan.hasPublished = false
+		r.plan.publishMu.Unlock()
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("record refused plan: %w", err)
+		}
+		return ctrl.Result{}, r.publishPlan(ctx)
+	}
+
+	var errs []error
+	applied := 0
+	for _, m := range mutations {
+		err := r.executeMutation(ctx, m)
+		observeRouteSync(routeLayerFor(m.Layer), m.Router, err)
+		if err != nil {
+			logger.Warn("Failed to apply route mutation", "mutation", describeMutation(m), "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", describeMutation(m), err))
+			continue
+		}
+		r.plan.markApplied(m)
+		applied++
+		logger.Info("Applied route mutation", "hash", hash, "mutation", describeMutation(m))
+	}
+
+	now := metav1.Now()
+	plan.Status.AppliedHash = hash
+	plan.Status.AppliedTime = &now
+	plan.Status.Message = fmt.Sprintf("Applied %d of %d mutations", applied, len(mutations))
+	if err := errors.Join(errs...); err != nil {
+		plan.Status.Message += ": " + err.Error()
+	}
+	err := r.Status().Update(ctx, &plan)
+	r.plan.publishMu.Unlock()
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("record applied plan: %w", err)
+	}
+
+	// Mutations that failed stay in the plan, under a new hash
+	return ctrl.Result{}, r.publishPlan(ctx)
+}
+
+// routeLayerFor returns the metrics label of a route layer
+func routeLayerFor(layer api.RouteLayer) string {
+	switch layer {
+	case api.RouteLayerAzureRouteTable:
+		return routeLayerAzureRouteTable
+	case api.RouteLayerKernelRoute:
+		return routeLayerKernelRoute
+	default:
+		return routeLayerTailscale
+	}
+}
+
+// addPlanApplier runs the controller applying approved plans, in apply mode
+func (r *RouteSyncReconciler) addPlanApplier(mgr ctrl.Manager) error {
+	if r.Mode != RouteSyncModeApply {
+		return nil
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("routesyncplan").
+		For(&api.RouteSyncPlan{}).
+		Complete(reconcile.Func(r.reconcilePlan))
+}
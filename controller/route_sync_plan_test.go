@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+func TestRoutePlan(t *testing.T) {
+	upsert := azureRouteMutation(api.RouteActionUpsert, "stargate-workers-rt", azureRoute{"stargate-dc-worker-1", "10.244.60.0/24", "10.237.0.4"}, "dc-worker-1")
+	remove := azureRouteMutation(api.RouteActionDelete, "stargate-workers-rt", azureRoute{name: "stargate-dc-worker-1"}, "dc-worker-1")
+	kernel := kernelRouteMutation(api.RouteActionUpsert, "dc/azure-dc/dc1", "10.244.60.0/24", "10.50.1.10", "", "dc-worker-1")
+
+	var plan routePlan
+	if !plan.add(upsert) || !plan.add(kernel) {
+		t.Fatal("add() = false for new mutations")
+	}
+	// The resync plans the same route without naming the Node
+	resynced := upsert
+	resynced.Node = ""
+	if plan.add(upsert) || plan.add(resynced) {
+		t.Error("add() = true for a mutation already planned")
+	}
+	if got, want := plan.mutations(), []api.RouteMutation{upsert, kernel}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mutations() = %v, want %v", got, want)
+	}
+	if !plan.pendingFor("dc-worker-1") || plan.pendingFor("dc-worker-2") {
+		t.Error("pendingFor() = false for dc-worker-1 or true for dc-worker-2")
+	}
+	hash := planHash(plan.mutations())
+
+	// A later mutation of the same route replaces the earlier one and moves to the end
+	if !plan.add(remove) {
+		t.Error("add() = false for a delete of a planned route")
+	}
+	if got, want := plan.mutations(), []api.RouteMutation{kernel, remove}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mutations() = %v, want %v", got, want)
+	}
+	if planHash(plan.mutations()) == hash {
+		t.Error("planHash() unchanged after the plan changed")
+	}
+
+	// Applied mutations leave the plan and aren't planned again
+	plan.markApplied(kernel)
+	if plan.add(kernel) {
+		t.Error("add() = true for an applied mutation")
+	}
+	if got, want := plan.mutations(), []api.RouteMutation{remove}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mutations() = %v, want %v", got, want)
+	}
+
+	// Planning the applied state again takes a pending change of the route out of the plan
+	plan.markApplied(upsert)
+	if got := plan.mutations(); len(got) != 1 {
+		t.Errorf("mutations() = %v, want the delete planned since the upsert", got)
+	}
+	if !plan.add(upsert) || len(plan.mutations()) != 0 {
+		t.Errorf("mutations() = %v, want none", plan.mutations())
+	}
+	if hash := planHash(nil); hash != "" {
+		t.Errorf("planHash(nil) = %q, want empty", hash)
+	}
+}
+
+func TestRenderPlan(t *testing.T) {
+	mutations := []api.RouteMutation{
+		azureRouteMutation(api.RouteActionUpsert, "stargate-workers-rt", azureRoute{"stargate-dc-worker-1", "10.244.60.0/24", "10.237.0.4"}, "dc-worker-1"),
+		kernelRouteMutation(api.RouteActionDelete, aksRouterKey, "10.244.61.0/24", "", tailscaleDevice, ""),
+		{Layer: api.RouteLayerTailscale, Action: api.RouteActionAdvertise, Router: "dc", Routes: []string{"10.244.60.0/24", "10.50.0.0/16"}},
+	}
+	want := "1. upsert Azure route stargate-dc-worker-1 in stargate-workers-rt: 10.244.60.0/24 via 10.237.0.4 (node dc-worker-1)\n" +
+		"2. delete kernel route 10.244.61.0/24 dev tailscale0 on router aks\n" +
+		"3. advertise 10.244.60.0/24,10.50.0.0/16 on router dc\n"
+	if got := renderPlan(mutations); got != want {
+		t.Errorf("renderPlan() = %q, want %q", got, want)
+	}
+}
+
+func TestParseKernelRoute(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       api.RouteMutation
+		want    string
+		wantErr bool
+	}{
+		{"via next hop", kernelRouteMutation(api.RouteActionUpsert, "dc", "10.244.60.0/24", "10.50.1.10", "", ""), "10.244.60.0/24 via 10.50.1.10", false},
+		{"via device", kernelRouteMutation(api.RouteActionUpsert, aksRouterKey, "10.244.60.0/24", "", tailscaleDevice, ""), "10.244.60.0/24 dev tailscale0", false},
+		{"injected prefix", kernelRouteMutation(api.RouteActionUpsert, "dc", "10.244.60.0/24; reboot", "10.50.1.10", "", ""), "", true},
+		{"injected next hop", kernelRouteMutation(api.RouteActionUpsert, "dc", "10.244.60.0/24", "10.50.1.10 && reboot", "", ""), "", true},
+		{"unknown device", kernelRouteMutation(api.RouteActionUpsert, "dc", "10.244.60.0/24", "", "eth0", ""), "", true},
+		{"next hop and device", kernelRouteMutation(api.RouteActionUpsert, "dc", "10.244.60.0/24", "10.50.1.10", tailscaleDevice, ""), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseKernelRoute(tt.m)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseKernelRoute() = %q, %v, want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := parseAdvertisedRoutes([]string{"10.50.0.0/16", "10.244.60.0/24 --exit-node=x"}); err == nil {
+		t.Error("parseAdvertisedRoutes() accepted a route that is not a prefix")
+	}
+}
+
+func TestObserveSyncInPlanMode(t *testing.T) {
+	const router = "dc/test/observe-sync"
+	count := func(result string) float64 {
+		return testutil.ToFloat64(routeSyncTotal.WithLabelValues(routeLayerKernelRoute, router, result))
+	}
+
+	r := &RouteSyncReconciler{Mode: RouteSyncModePlan}
+	r.observeSync(routeLayerKernelRoute, router, nil)
+	if count("success") != 0 {
+		t.Error("a planned pass was counted as a success")
+	}
+	r.observeSync(routeLayerKernelRoute, router, errors.New("list nodes"))
+	if count("error") != 1 {
+		t.Error("a failed planned pass was not counted as an error")
+	}
+
+	r.Mode = RouteSyncModeAuto
+	r.observeSync(routeLayerKernelRoute, router, nil)
+	if count("success") != 1 {
+		t.Error("an applied pass was not counted as a success")
+	}
+}
@@ -0,0 +1,278 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// DefaultRouteResyncInterval is how often the Azure route tables are checked for drift
+const DefaultRouteResyncInterval = 10 * time.Minute
+
+// azureRoute is a route in an Azure route table to a virtual appliance
+type azureRoute struct {
+	name    string
+	prefix  string
+	nextHop string
+}
+
+// routeTableDiff is what turns a route table's routes into the desired ones
+type routeTableDiff struct {
+	remove    []string        // stale routes, and owned routes holding a prefix a desired route needs
+	upsert    []azureRoute    // missing routes, and routes with the wrong next hop
+	conflicts []routeConflict // desired routes whose prefix is held by a route route sync doesn't own
+}
+
+// routeConflict is a desired route whose prefix is held by a route added by someone else
+type routeConflict struct {
+	desired azureRoute
+	foreign azureRoute
+}
+
+// desiredRoutes returns the routes the worker and router route tables should hold, as recorded
+// on the Nodes when their routes were programmed: each DC worker's pod CIDR via the AKS router,
+// and each AKS node's pod CIDR via the node. reserved holds the route names of every live Node,
+// so the routes of Nodes that haven't been reconciled yet aren't removed as stale.
+func desiredRoutes(nodes []corev1.Node, aksRouterIP string) (workers, router []azureRoute, reserved map[string]bool) {
+	reserved = make(map[string]bool)
+	for i := range nodes {
+		node := &nodes[i]
+		if node.DeletionTimestamp != nil {
+			continue
+		}
+		reserved[workerRouteName(node.Name)] = true
+		reserved[aksNodeRouteName(node.Name)] = true
+
+		podCIDR, nodeIP := node.Annotations[podCIDRAnnotation], node.Annotations[nodeIPAnnotation]
+		if podCIDR == "" {
+			continue
+		}
+		switch nodeClass(node.Annotations[nodeClassAnnotation]) {
+		case nodeClassWorker:
+			if aksRouterIP != "" {
+				workers = append(workers, azureRoute{name: workerRouteName(node.Name), prefix: podCIDR, nextHop: aksRouterIP})
+			}
+		case nodeClassAKS:
+			if nodeIP != "" {
+				router = append(router, azureRoute{name: aksNodeRouteName(node.Name), prefix: podCIDR, nextHop: nodeIP})
+			}
+		}
+	}
+	return workers, router, reserved
+}
+
+// diffRouteTable compares a route table's actual routes with the desired ones. A desired route
+// is satisfied by any route with its prefix and next hop, whatever its name, so routes added by
+// infra-prep are kept. Azure doesn't allow two routes with the same prefix, so an owned route
+// holding a desired prefix with the wrong next hop is replaced. A route owned doesn't report is
+// left alone and the conflict is returned instead. Other routes are removed only if owned
+// reports that route sync programmed them.
+func diffRouteTable(desired, actual []azureRoute, owned func(name string) bool) routeTableDiff {
+	desired = append([]azureRoute(nil), desired...)
+	sort.Slice(desired, func(i, j int) bool { return desired[i].name < desired[j].name })
+
+	byPrefix := make(map[string]azureRoute, len(actual))
+	for _, route := range actual {
+		byPrefix[route.prefix] = route
+	}
+
+	var diff routeTableDiff
+	keep := make(map[string]bool)
+	seen := make(map[string]bool)
+	for _, want := range desired {
+		// Two Nodes briefly recording the same CIDR can't both have a route
+		if seen[want.prefix] {
+			continue
+		}
+		seen[want.prefix] = true
+
+		have, ok := byPrefix[want.prefix]
+		switch {
+		case ok && have.nextHop == want.nextHop:
+			keep[have.name] = true
+			continue
+		case ok && have.name != want.name && !owned(have.name):
+			keep[have.name] = true
+			diff.conflicts = append(diff.conflicts, routeConflict{desired: want, foreign: have})
+			continue
+		case ok && have.name != want.name:
+			diff.remove = append(diff.remove, have.name)
+		}
+		keep[want.name] = true
+		diff.upsert = append(diff.upsert, want)
+	}
+
+	removed := make(map[string]bool)
+	for _, name := range diff.remove {
+		removed[name] = true
+	}
+	for _, route := range actual {
+		if !keep[route.name] && !removed[route.name] && owned(route.name) {
+			diff.remove = append(diff.remove, route.name)
+			removed[route.name] = true
+		}
+	}
+	sort.Strings(diff.remove)
+	return diff
+}
+
+// ownedRoutes reports whether a route route sync would remove as stale: its name carries
+// prefix and belongs to no live Node
+func ownedRoutes(prefix string, reserved map[string]bool) func(name string) bool {
+	return func(name string) bool {
+		return strings.HasPrefix(name, prefix) && !reserved[name]
+	}
+}
+
+// startRouteResync periodically repairs drift in the Azure route tables and the routers'
+// Tailscale advertisements until ctx is done.
+// It runs only on the leader.
+func (r *RouteSyncReconciler) startRouteResync(ctx context.Context) error {
+	logger := r.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	ticker := time.NewTicker(r.ResyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.resyncRouteTables(ctx); err != nil {
+				logger.Warn("Route table resync failed", "error", err)
+			}
+			if err := r.resyncAdvertisements(ctx); err != nil {
+				logger.Warn("Tailscale advertisement resync failed", "error", err)
+			}
+			if r.planning() {
+				r.publishPlanOrLog(ctx)
+			}
+		}
+	}
+}
+
+// resyncRouteTables diffs the worker and router route tables against the routes recorded on
+// the Nodes, then adds missing routes, fixes wrong next hops and removes stale routes
+func (r *RouteSyncReconciler) resyncRouteTables(ctx context.Context) error {
+	if r.SubscriptionID == "" || r.AKSResourceGroup == "" || r.RouteTableName == "" {
+		return nil
+	}
+	if err := r.ensureInitialized(ctx); err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+
+	var nodes corev1.NodeList
+	if err := r.List(ctx, &nodes); err != nil {
+		return fmt.Errorf("list nodes: %w", err)
+	}
+	workers, router, reserved := desiredRoutes(nodes.Items, r.aksRouterIP)
+
+	var errs []error
+	// Without the AKS router's IP the worker routes' next hop is unknown
+	if r.aksRouterIP != "" {
+		errs = append(errs, r.resyncRouteTable(ctx, r.RouteTableName, workers, ownedRoutes(workerRouteNamePrefix, reserved)))
+	}
+	if r.RouterSubnetName != "" && r.VNetName != "" {
+		errs = append(errs, r.resyncRouteTable(ctx, r.routerRouteTableName(), router, ownedRoutes(aksNodeRouteNamePrefix, reserved)))
+	}
+	r.syncMu.Lock()
+	r.lastSync = time.Now()
+	r.syncMu.Unlock()
+	return errors.Join(errs...)
+}
+
+// resyncRouteTable brings a route table's routes to the desired ones. Every change is
+// attempted; the errors are joined.
+func (r *RouteSyncReconciler) resyncRouteTable(ctx context.Context, routeTableName string, desired []azureRoute, owned func(string) bool) error {
+	logger := r.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	actual, err := r.listAzureRoutes(ctx, routeTableName)
+	if err != nil {
+		return err
+	}
+	diff := diffRouteTable(desired, actual, owned)
+	for _, conflict := range diff.conflicts {
+		logger.Warn("Route not owned by route sync holds a desired prefix, leaving it in place", "routeTable", routeTableName,
+			"route", conflict.foreign.name, "prefix", conflict.foreign.prefix, "nextHop", conflict.foreign.nextHop,
+			"desiredRoute", conflict.desired.name, "desiredNextHop", conflict.desired.nextHop)
+	}
+
+	var errs []error
+	// Removals go first, to free prefixes for the upserts
+	for _, name := range diff.remove {
+		applied, err := r.mutate(ctx, azureRouteMutation(api.RouteActionDelete, routeTableName, azureRoute{name: name}, ""))
+		r.observeSync(routeLayerAzureRouteTable, aksRouterKey, err)
+		if applied || err != nil {
+			observeRouteDrift(routeTableName, "delete", err)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if applied {
+			logger.Info("Removed drifted route", "routeTable", routeTableName, "route", name)
+		}
+	}
+	for _, route := range diff.upsert {
+		applied, err := r.mutate(ctx, azureRouteMutation(api.RouteActionUpsert, routeTableName, route, ""))
+		r.observeSync(routeLayerAzureRouteTable, aksRouterKey, err)
+		if applied || err != nil {
+			observeRouteDrift(routeTableName, "upsert", err)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("route %s in %s: %w", route.name, routeTableName, err))
+			continue
+		}
+		if applied {
+			logger.Info("Repaired drifted route", "routeTable", routeTableName, "route", route.name, "prefix", route.prefix, "nextHop", route.nextHop)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// listAzureRoutes returns the routes of a route table
+func (r *RouteSyncReconciler) listAzureRoutes(ctx context.Context, routeTableName string) ([]azureRoute, error) {
+	table, err := r.routeTableClient.Get(ctx, r.AKSResourceGroup, routeTableName, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get route table %s: %w", routeTableName, err)
+	}
+	if table.Properties == nil {
+		return nil, nil
+	}
+
+	var routes []azureRoute
+	for _, route := range table.Properties.Routes {
+		if route == nil || route.Name == nil || route.Properties == nil || route.Properties.AddressPrefix == nil {
+			continue
+		}
+		actual := azureRoute{name: *route.Name, prefix: *route.Properties.AddressPrefix}
+		if route.Properties.NextHopIPAddress != nil {
+			actual.nextHop = *route.Properties.NextHopIPAddress
+		}
+		routes = append(routes, actual)
+	}
+	return routes, nil
+}
+
+// addRouteResync runs the route table resync on the manager, if it is enabled
+func (r *RouteSyncReconciler) addRouteResync(mgr ctrl.Manager) error {
+	if r.ResyncInterval <= 0 {
+		return nil
+	}
+	return mgr.Add(manager.RunnableFunc(r.startRouteResync))
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDesiredRoutes(t *testing.T) {
+	now := metav1.Now()
+	node := func(name string, class nodeClass, cidr, ip string) corev1.Node {
+		n := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{}}}
+		if cidr != "" {
+			n.Annotations[podCIDRAnnotation] = cidr
+			n.Annotations[nodeIPAnnotation] = ip
+			n.Annotations[nodeClassAnnotation] = string(class)
+		}
+		return n
+	}
+	deleting := node("dc-worker-3", nodeClassWorker, "10.244.62.0/24", "10.50.1.12")
+	deleting.DeletionTimestamp = &now
+	nodes := []corev1.Node{
+		node("dc-worker-1", nodeClassWorker, "10.244.60.0/24", "10.50.1.10"),
+		node("dc-worker-2", "", "", ""),
+		deleting,
+		node("aks-nodepool1-0", nodeClassAKS, "10.244.1.0/24", "10.224.0.4"),
+	}
+
+	workers, router, reserved := desiredRoutes(nodes, "10.237.0.4")
+	if want := []azureRoute{{"stargate-dc-worker-1", "10.244.60.0/24", "10.237.0.4"}}; !reflect.DeepEqual(workers, want) {
+		t.Errorf("workers = %v, want %v", workers, want)
+	}
+	if want := []azureRoute{{"aks-node-aks-nodepool1-0", "10.244.1.0/24", "10.224.0.4"}}; !reflect.DeepEqual(router, want) {
+		t.Errorf("router = %v, want %v", router, want)
+	}
+	// Untracked Nodes keep their routes; deleting Nodes don't
+	if !reserved["stargate-dc-worker-2"] || reserved["stargate-dc-worker-3"] {
+		t.Errorf("reserved = %v, want dc-worker-2 and not dc-worker-3", reserved)
+	}
+
+	// Without the AKS router's IP there is no next hop for workers
+	if workers, _, _ := desiredRoutes(nodes, ""); len(workers) != 0 {
+		t.Errorf("workers without AKS router IP = %v, want none", workers)
+	}
+}
+
+func TestDiffRouteTable(t *testing.T) {
+	owned := ownedRoutes(workerRouteNamePrefix, map[string]bool{"stargate-dc-worker-9": true})
+	desired := []azureRoute{
+		{"stargate-dc-worker-1", "10.244.60.0/24", "10.237.0.4"},
+		{"stargate-dc-worker-2", "10.244.61.0/24", "10.237.0.4"},
+		{"stargate-dc-worker-3", "10.244.62.0/24", "10.237.0.4"},
+		{"stargate-dc-worker-4", "10.244.63.0/24", "10.237.0.4"},
+	}
+
+	tests := []struct {
+		name   string
+		actual []azureRoute
+		want   routeTableDiff
+	}{
+		{
+			name: "in sync",
+			actual: []azureRoute{
+				{"stargate-dc-worker-1", "10.244.60.0/24", "10.237.0.4"},
+				{"stargate-dc-worker-2", "10.244.61.0/24", "10.237.0.4"},
+				{"stargate-dc-worker-3", "10.244.62.0/24", "10.237.0.4"},
+				// infra-prep's route for the prefix satisfies it
+				{"pod-cidr-worker-4", "10.244.63.0/24", "10.237.0.4"},
+				{"to-workers", "10.50.0.0/16", "10.237.0.4"},
+			},
+		},
+		{
+			name: "drifted",
+			actual: []azureRoute{
+				// Edited by hand
+				{"stargate-dc-worker-1", "10.244.60.0/24", "10.237.0.9"},
+				// Holds a desired prefix with the wrong next hop, but wasn't added by route sync
+				{"manual", "10.244.61.0/24", "10.237.0.9"},
+				// Route sync's, holding a desired prefix with the wrong next hop
+				{"stargate-dc-worker-7", "10.244.62.0/24", "10.237.0.9"},
+				// Left behind by a Node that is gone
+				{"stargate-dc-worker-8", "10.244.70.0/24", "10.237.0.4"},
+				// Of a Node not reconciled yet
+				{"stargate-dc-worker-9", "10.244.71.0/24", "10.237.0.4"},
+				// Not programmed by route sync
+				{"to-workers", "10.50.0.0/16", "10.237.0.4"},
+			},
+			want: routeTableDiff{
+				remove: []string{"stargate-dc-worker-7", "stargate-dc-worker-8"},
+				upsert: []azureRoute{desired[0], desired[2], desired[3]},
+				conflicts: []routeConflict{{
+					desired: desired[1],
+					foreign: azureRoute{"manual", "10.244.61.0/24", "10.237.0.9"},
+				}},
+			},
+		},
+		{
+			name: "stale route under a desired name",
+			actual: []azureRoute{
+				{"stargate-dc-worker-1", "10.244.60.0/24", "10.237.0.4"},
+				{"stargate-dc-worker-2", "10.244.61.0/24", "10.237.0.4"},
+				{"stargate-dc-worker-3", "10.244.62.0/24", "10.237.0.4"},
+				{"pod-cidr-worker-4", "10.244.63.0/24", "10.237.0.4"},
+				// dc-worker-4's route for its previous CIDR
+				{"stargate-dc-worker-4", "10.244.72.0/24", "10.237.0.4"},
+			},
+			want: routeTableDiff{remove: []string{"stargate-dc-worker-4"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffRouteTable(desired, tt.actual, owned)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffRouteTable() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+	"github.com/vpatelsj/stargate/pkg/bmc"
+)
+
+const (
+	// defaultProbeInterval is how often each Server is probed
+	defaultProbeInterval = 2 * time.Minute
+
+	// probeTimeout bounds a single probe of a Server
+	probeTimeout = 30 * time.Second
+)
+
+// healthManagedStates are the Server states the health reconciler may change. Any other state
+// (provisioning, rebooting, ...) belongs to a running Operation and is left alone.
+var healthManagedStates = map[string]bool{
+	"ready":       true,
+	"unreachable": true,
+	"off":         true,
+}
+
+// ServerHealthReconciler periodically probes Servers over SSH, through their Node and through
+// their BMC, and records the results in the Server's status
+type ServerHealthReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// Provider is the Server provider this controller probes ("azure" or "qemu")
+	Provider string
+
+	// ProbeInterval is how often each Server is probed (default: 2m)
+	ProbeInterval time.Duration
+
+	// SSH configuration used for the reachability probe, unless the Server's ProvisioningProfile
+	// or Datacenter provides credentials
+	SSHPrivateKeyPath string
+	SSHPort           int
+	AdminUsername     string
+}
+
+// probeSSHConfig holds the SSH credentials used to probe a Server and the router in front of it
+type probeSSHConfig struct {
+	username       string
+	keyPath        string
+	routerUsername string
+	routerKeyPath  string
+}
+
+// serverProbe holds the results of probing a Server
+type serverProbe struct {
+	sshErr     error
+	node       string // empty if no Node is registered
+	nodeReady  bool
+	bmcErr     error
+	powerState bmc.PowerState
+}
+
+// healthy returns true if the server answered over SSH or its Node is Ready
+func (p *serverProbe) healthy() bool {
+	return p.sshErr == nil || p.nodeReady
+}
+
+// +kubebuilder:rbac:groups=stargate.io,resources=servers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=stargate.io,resources=servers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=stargate.io,resources=provisioningprofiles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=stargate.io,resources=datacenters,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile probes a Server and updates its status, then requeues for the next probe
+func (r *ServerHealthReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var server api.Server
+	if err := r.Get(ctx, req.NamespacedName, &server); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// Provider gating: only probe servers this controller manages
+	if server.Spec.Provider != "" && server.Spec.Provider != r.Provider {
+		return ctrl.Result{}, nil
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+	probe := r.probe(probeCtx, &server)
+	cancel()
+
+	// Probes take a while, so apply the results to a fresh copy to avoid conflicts
+	if err := r.Get(ctx, req.NamespacedName, &server); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	// An Operation running on the Server records the outcome itself; writing conditions
+	// underneath it while its Node comes and goes would only cause conflicts
+	if server.Status.ActiveOperation != nil {
+		return ctrl.Result{RequeueAfter: r.probeInterval()}, nil
+	}
+	previous := server.Status.State
+	before := server.Status.DeepCopy()
+	applyServerProbe(&server, probe, metav1.Now())
+	if !needsStatusUpdate(before, &server.Status, r.probeInterval()) {
+		return ctrl.Result{RequeueAfter: r.probeInterval()}, nil
+	}
+	if server.Status.State != previous {
+		logger.Info("Server health changed", "server", server.Name, "from", previous, "to", server.Status.State)
+	}
+	if err := r.Status().Update(ctx, &server); err != nil {
+		return ctrl.Result{}, fmt.Errorf("update Server status: %w", err)
+	}
+	recordServerState(r.Recorder, &server, previous)
+
+	return ctrl.Result{RequeueAfter: r.probeInterval()}, nil
+}
+
+// probe checks the Server over SSH, through its Node and, if it has one, through its BMC
+func (r *ServerHealthReconciler) probe(ctx context.Context, server *api.Server) *serverProbe {
+	probe := &serverProbe{}
+
+	if server.Spec.IPv4 == "" {
+		probe.sshErr = fmt.Errorf("server has no IPv4 address")
+	} else {
+		probe.sshErr = r.probeSSH(ctx, server)
+	}
+
+	if node, err := getNode(ctx, r.Client, server.Name); err == nil && node != nil {
+		probe.node = node.Name
+		probe.nodeReady = isNodeReady(node)
+	}
+
+	if server.Spec.BMC != nil {
+		probe.bmcErr = withBMCSession(ctx, r.Client, server, func(bmcClient *bmc.Client) error {
+			var err error
+			probe.powerState, err = bmcClient.GetPowerState(ctx)
+			return err
+		})
+	}
+
+	return probe
+}
+
+// probeSSH runs a no-op command on the server, through its router if it has one
+func (r *ServerHealthReconciler) probeSSH(ctx context.Context, server *api.Server) error {
+	cfg, cleanup, err := r.resolveProbeSSH(ctx, server)
+	defer cleanup()
+	if err != nil {
+		return err
+	}
+
+	sshArgs := []string{
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=/dev/null",
+		"-o", "BatchMode=yes",
+		"-o", "ConnectTimeout=10",
+		"-i", cfg.keyPath,
+	}
+
+	if server.Spec.RouterIP != "" {
+		proxyCmd := fmt.Sprintf("ssh -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -o BatchMode=yes -i %s -p %d -W %%h:%%p %s@%s",
+			cfg.routerKeyPath, r.SSHPort, cfg.routerUsername, server.Spec.RouterIP)
+		sshArgs = append(sshArgs, "-o", fmt.Sprintf("ProxyCommand=%s", proxyCmd))
+	}
+
+	sshArgs = append(sshArgs,
+		"-p", strconv.Itoa(r.SSHPort),
+		fmt.Sprintf("%s@%s", cfg.username, server.Spec.IPv4),
+		"true",
+	)
+
+	cmd := exec.CommandContext(ctx, "ssh", sshArgs...)
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+	if err := cmd.Run(); err != nil {
+		if output := strings.TrimSpace(buf.String()); output != "" {
+			return fmt.Errorf("%w: %s", err, output)
+		}
+		return err
+	}
+	return nil
+}
+
+// resolveProbeSSH returns the SSH credentials bootstrap uses for the server: those of the
+// ProvisioningProfile last applied to it over the controller's, and for the router those of
+// its Datacenter, if it has any. cleanup removes key files written from Secrets.
+func (r *ServerHealthReconciler) resolveProbeSSH(ctx context.Context, server *api.Server) (*probeSSHConfig, func(), error) {
+	cfg := &probeSSHConfig{
+		username: r.AdminUsername,
+		keyPath:  r.sshPrivateKeyPath(),
+	}
+
+	var cleanups []func()
+	cleanup := func() {
+		for _, f := range cleanups {
+			f()
+		}
+	}
+
+	if name := server.Status.AppliedProvisioningProfile; name != "" {
+		var profile api.ProvisioningProfile
+		err := r.Get(ctx, client.ObjectKey{Namespace: server.Namespace, Name: name}, &profile)
+		switch {
+		case apierrors.IsNotFound(err):
+			// Fall back to the controller's credentials
+		case err != nil:
+			return nil, cleanup, fmt.Errorf("get ProvisioningProfile %s: %w", name, err)
+		default:
+			if profile.Spec.AdminUsername != "" {
+				cfg.username = profile.Spec.AdminUsername
+			}
+			if ref := profile.Spec.SSHCredentialsSecretRef; ref != "" {
+				keyPath, username, remove, err := sshCredentialsFromSecret(ctx, r.Client, server.Namespace, ref)
+				if err != nil {
+					return nil, cleanup, err
+				}
+				cleanups = append(cleanups, remove)
+				if keyPath != "" {
+					cfg.keyPath = keyPath
+				}
+				if username != "" {
+					cfg.username = username
+				}
+			}
+		}
+	}
+	if cfg.username == "" {
+		cfg.username = "ubuntu"
+	}
+
+	// The router is reached with the same credentials as the server unless its Datacenter
+	// has its own
+	cfg.routerUsername = cfg.username
+	cfg.routerKeyPath = cfg.keyPath
+
+	datacenter, err := getServerDatacenter(ctx, r.Client, server)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	if datacenter != nil && datacenter.Spec.SSHCredentialsSecretRef != "" {
+		keyPath, username, remove, err := sshCredentialsFromSecret(ctx, r.Client, server.Namespace, datacenter.Spec.SSHCredentialsSecretRef)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		cleanups = append(cleanups, remove)
+		if keyPath != "" {
+			cfg.routerKeyPath = keyPath
+		}
+		cfg.routerUsername = "ubuntu"
+		if username != "" {
+			cfg.routerUsername = username
+		}
+	}
+
+	return cfg, cleanup, nil
+}
+
+func (r *ServerHealthReconciler) sshPrivateKeyPath() string {
+	if r.SSHPrivateKeyPath != "" {
+		return r.SSHPrivateKeyPath
+	}
+	return filepath.Join(os.Getenv("HOME"), ".ssh", "id_rsa")
+}
+
+func (r *ServerHealthReconciler) probeInterval() time.Duration {
+	if r.ProbeInterval > 0 {
+		return r.ProbeInterval
+	}
+	return defaultProbeInterval
+}
+
+// applyServerProbe records probe results in the Server's status: a condition per probe,
+// the last time it was seen and, unless an Operation owns the state, whether it is
+// ready, unreachable or powered off
+func applyServerProbe(server *api.Server, probe *serverProbe, now metav1.Time) {
+	gen := server.Generation
+
+	if probe.sshErr == nil {
+		setCondition(&server.Status.Conditions, gen, api.ConditionSSHReachable, metav1.ConditionTrue, api.ReasonReachable, "SSH probe succeeded")
+	} else {
+		setCondition(&server.Status.Conditions, gen, api.ConditionSSHReachable, metav1.ConditionFalse, api.ReasonUnreachable, probe.sshErr.Error())
+	}
+
+	switch {
+	case probe.node == "":
+		setCondition(&server.Status.Conditions, gen, api.ConditionNodeRegistered, metav1.ConditionFalse, api.ReasonUnreachable, "No Node registered")
+	case probe.nodeReady:
+		setCondition(&server.Status.Conditions, gen, api.ConditionNodeRegistered, metav1.ConditionTrue, api.ReasonReachable, "Node is Ready")
+	default:
+		setCondition(&server.Status.Conditions, gen, api.ConditionNodeRegistered, metav1.ConditionFalse, api.ReasonUnreachable, "Node is not Ready")
+	}
+
+	if server.Spec.BMC != nil {
+		if probe.bmcErr == nil {
+			server.Status.PowerState = string(probe.powerState)
+			setCondition(&server.Status.Conditions, gen, api.ConditionBMCReachable, metav1.ConditionTrue, api.ReasonReachable, fmt.Sprintf("Power state %s", probe.powerState))
+		} else {
+			setCondition(&server.Status.Conditions, gen, api.ConditionBMCReachable, metav1.ConditionFalse, api.ReasonUnreachable, probe.bmcErr.Error())
+		}
+	}
+
+	if probe.healthy() {
+		server.Status.LastSeen = &now
+	}
+
+	if !healthManagedStates[server.Status.State] {
+		return
+	}
+	state, message := "ready", "Server is reachable"
+	switch {
+	case probe.bmcErr == nil && probe.powerState == bmc.PowerStateOff:
+		state, message = "off", "BMC reports the server is powered off"
+	case !probe.healthy():
+		state, message = "unreachable", "Server is not reachable over SSH and its Node is not Ready"
+	}
+	if state != server.Status.State {
+		server.Status.State = state
+		server.Status.Message = message
+		server.Status.LastUpdated = now
+	}
+	setServerReadyCondition(server)
+}
+
+// needsStatusUpdate returns true if a probe changed the Server's status in a way worth saving.
+// lastSeen moving forward by less than the probe interval alone isn't.
+func needsStatusUpdate(before, after *api.ServerStatus, interval time.Duration) bool {
+	if before.LastSeen != nil && after.LastSeen != nil && after.LastSeen.Sub(before.LastSeen.Time) < interval {
+		before = before.DeepCopy()
+		before.LastSeen = after.LastSeen
+	}
+	return !equality.Semantic.DeepEqual(before, after)
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *ServerHealthReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("serverhealth").
+		// Status updates from probes must not trigger another probe; the requeue drives them
+		For(&api.Server{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 4}).
+		Complete(r)
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+	"github.com/vpatelsj/stargate/pkg/bmc"
+)
+
+func TestApplyServerProbe(t *testing.T) {
+	sshDown := errors.New("exit status 255: connection timed out")
+
+	tests := []struct {
+		name      string
+		state     string
+		bmc       bool
+		probe     serverProbe
+		expected  string
+		lastSeen  bool
+		sshStatus metav1.ConditionStatus
+	}{
+		{"reachable", "ready", false, serverProbe{node: "w1", nodeReady: true}, "ready", true, metav1.ConditionTrue},
+		{"node ready without ssh", "ready", false, serverProbe{sshErr: sshDown, node: "w1", nodeReady: true}, "ready", true, metav1.ConditionFalse},
+		{"unreachable", "ready", false, serverProbe{sshErr: sshDown}, "unreachable", false, metav1.ConditionFalse},
+		{"recovered", "unreachable", false, serverProbe{}, "ready", true, metav1.ConditionTrue},
+		{"powered off", "ready", true, serverProbe{sshErr: sshDown, powerState: bmc.PowerStateOff}, "off", false, metav1.ConditionFalse},
+		{"operation owns state", "provisioning", false, serverProbe{sshErr: sshDown}, "provisioning", false, metav1.ConditionFalse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &api.Server{Status: api.ServerStatus{State: tt.state}}
+			if tt.bmc {
+				server.Spec.BMC = &api.BMCConfig{Address: "10.0.0.10"}
+			}
+			applyServerProbe(server, &tt.probe, metav1.Now())
+
+			if server.Status.State != tt.expected {
+				t.Errorf("expected state %s, got %s", tt.expected, server.Status.State)
+			}
+			if (server.Status.LastSeen != nil) != tt.lastSeen {
+				t.Errorf("expected lastSeen set=%v, got %v", tt.lastSeen, server.Status.LastSeen)
+			}
+			if !meta.IsStatusConditionPresentAndEqual(server.Status.Conditions, api.ConditionSSHReachable, tt.sshStatus) {
+				t.Errorf("expected SSHReachable=%s, got %+v", tt.sshStatus, server.Status.Conditions)
+			}
+		})
+	}
+}
+
+func TestNeedsStatusUpdate(t *testing.T) {
+	seen := metav1.NewTime(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	at := func(d time.Duration) *metav1.Time {
+		t := metav1.NewTime(seen.Add(d))
+		return &t
+	}
+
+	tests := []struct {
+		name   string
+		before api.ServerStatus
+		after  api.ServerStatus
+		want   bool
+	}{
+		{"unchanged", api.ServerStatus{State: "ready", LastSeen: &seen}, api.ServerStatus{State: "ready", LastSeen: &seen}, false},
+		{"lastSeen moved within the interval", api.ServerStatus{State: "ready", LastSeen: &seen}, api.ServerStatus{State: "ready", LastSeen: at(time.Minute)}, false},
+		{"lastSeen moved a whole interval", api.ServerStatus{State: "ready", LastSeen: &seen}, api.ServerStatus{State: "ready", LastSeen: at(2 * time.Minute)}, true},
+		{"first seen", api.ServerStatus{State: "ready"}, api.ServerStatus{State: "ready", LastSeen: &seen}, true},
+		{"state changed", api.ServerStatus{State: "unreachable", LastSeen: &seen}, api.ServerStatus{State: "ready", LastSeen: at(time.Minute)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsStatusUpdate(&tt.before, &tt.after, 2*time.Minute); got != tt.want {
+				t.Errorf("needsStatusUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+const (
+	// operationServerRefField is the field index used to list the Operations targeting a Server
+	operationServerRefField = "spec.serverRef.name"
+
+	// serverLockWaitInterval is how often a queued Operation re-checks its Server's lock. Lock
+	// releases also wake queued Operations through the Server watch; this is the fallback.
+	serverLockWaitInterval = 30 * time.Second
+)
+
+// indexOperationServerRef is the indexer function for operationServerRefField
+func indexOperationServerRef(obj client.Object) []string {
+	operation, ok := obj.(*api.Operation)
+	if !ok || operation.Spec.ServerRef.Name == "" {
+		return nil
+	}
+	return []string{operation.Spec.ServerRef.Name}
+}
+
+// isOperationQueued returns true if the operation is waiting to start
+func isOperationQueued(operation *api.Operation) bool {
+	return (operation.Status.Phase == "" || operation.Status.Phase == api.OperationPhasePending) &&
+		operation.DeletionTimestamp == nil && !operation.Spec.Cancel
+}
+
+// sortOperationQueue orders queued operations by priority (highest first), then creation time,
+// then name
+func sortOperationQueue(queue []*api.Operation) {
+	sort.SliceStable(queue, func(i, j int) bool {
+		a, b := queue[i], queue[j]
+		if a.Spec.Priority != b.Spec.Priority {
+			return a.Spec.Priority > b.Spec.Priority
+		}
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(&b.CreationTimestamp)
+		}
+		return a.Name < b.Name
+	})
+}
+
+// serverLockBlocker returns why the operation cannot take its Server's lock yet, or "" if it
+// may. others are the other Operations targeting the same Server. The lock is taken by at most
+// one Operation at a time: another Running Operation, or a live holder recorded in the Server's
+// activeOperation, blocks it; otherwise only the first Operation in queue order may proceed.
+func serverLockBlocker(operation *api.Operation, server *api.Server, others []api.Operation) string {
+	queue := []*api.Operation{operation}
+	for i := range others {
+		other := &others[i]
+		if other.UID == operation.UID {
+			continue
+		}
+		if other.Status.Phase == api.OperationPhaseRunning {
+			return fmt.Sprintf("Waiting for operation %s to finish on server %s", other.Name, server.Name)
+		}
+		if holder := server.Status.ActiveOperation; holder != nil && holder.UID == other.UID && !isOperationFinished(other) {
+			return fmt.Sprintf("Waiting for operation %s to finish on server %s", other.Name, server.Name)
+		}
+		if isOperationQueued(other) {
+			queue = append(queue, other)
+		}
+	}
+
+	sortOperationQueue(queue)
+	if queue[0].UID != operation.UID {
+		for i := range queue {
+			if queue[i].UID == operation.UID {
+				return fmt.Sprintf("Queued behind operation %s on server %s (position %d of %d)", queue[0].Name, server.Name, i+1, len(queue))
+			}
+		}
+	}
+	return ""
+}
+
+// acquireServerLock records the operation as the Server's activeOperation, unless another
+// Operation holds the lock or is ahead of it in the queue. It returns a message explaining
+// what the operation is waiting for if the lock was not acquired. The Server is updated in place.
+func acquireServerLock(ctx context.Context, c client.Client, operation *api.Operation, server *api.Server) (bool, string, error) {
+	if holder := server.Status.ActiveOperation; holder != nil && holder.UID == operation.UID {
+		return true, "", nil
+	}
+
+	var operations api.OperationList
+	if err := c.List(ctx, &operations, client.InNamespace(operation.Namespace), client.MatchingFields{operationServerRefField: server.Name}); err != nil {
+		return false, "", fmt.Errorf("list operations for server %s: %w", server.Name, err)
+	}
+	// Queued Operations still waiting for their ProvisioningProfile can't start, so they
+	// don't hold up the rest of the queue
+	others := make([]api.Operation, 0, len(operations.Items))
+	for i := range operations.Items {
+		other := &operations.Items[i]
+		if other.UID != operation.UID && isOperationQueued(other) {
+			waiting, err := waitingForProfile(ctx, c, other)
+			if err != nil {
+				return false, "", err
+			}
+			if waiting {
+				continue
+			}
+		}
+		others = append(others, *other)
+	}
+	if message := serverLockBlocker(operation, server, others); message != "" {
+		return false, message, nil
+	}
+
+	// A holder that was not found above is stale (deleted or finished), so the lock is free.
+	// The update fails on a conflict if another Operation took the lock first.
+	server.Status.ActiveOperation = &api.OperationReference{Name: operation.Name, UID: operation.UID}
+	if err := c.Status().Update(ctx, server); err != nil {
+		return false, "", fmt.Errorf("lock server %s: %w", server.Name, err)
+	}
+	log.FromContext(ctx).Info("Acquired server lock", "server", server.Name)
+	return true, "", nil
+}
+
+// waitingForProfile returns true if the operation references a ProvisioningProfile that
+// doesn't exist or is not ready, so it can't take its Server's lock yet
+func waitingForProfile(ctx context.Context, c client.Client, operation *api.Operation) (bool, error) {
+	if operation.Spec.ProvisioningProfileRef.Name == "" {
+		return false, nil
+	}
+	var profile api.ProvisioningProfile
+	key := client.ObjectKey{Namespace: operation.Namespace, Name: operation.Spec.ProvisioningProfileRef.Name}
+	if err := c.Get(ctx, key, &profile); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("get ProvisioningProfile %s: %w", key.Name, err)
+	}
+	ready, _ := profileReadiness(&profile)
+	return !ready, nil
+}
+
+// releaseServerLock clears the Server's activeOperation if the operation holds it
+func releaseServerLock(ctx context.Context, c client.Client, operation *api.Operation) error {
+	var server api.Server
+	key := client.ObjectKey{Namespace: operation.Namespace, Name: operation.Spec.ServerRef.Name}
+	if err := c.Get(ctx, key, &server); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if holder := server.Status.ActiveOperation; holder == nil || holder.UID != operation.UID {
+		return nil
+	}
+
+	server.Status.ActiveOperation = nil
+	if err := c.Status().Update(ctx, &server); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("unlock server %s: %w", server.Name, err)
+	}
+	log.FromContext(ctx).Info("Released server lock", "server", server.Name)
+	return nil
+}
+
+// waitForServerLock records why an Operation is queued and checks again later
+func waitForServerLock(ctx context.Context, c client.Client, recorder record.EventRecorder, operation *api.Operation, message string) (ctrl.Result, error) {
+	if operation.Status.Phase != api.OperationPhasePending || operation.Status.Message != message {
+		log.FromContext(ctx).Info("Operation queued", "server", operation.Spec.ServerRef.Name, "reason", message)
+		recordEvent(recorder, operation, corev1.EventTypeNormal, api.EventReasonQueued, message)
+		operation.Status.Phase = api.OperationPhasePending
+		operation.Status.Message = message
+		setOperationPhaseCondition(operation)
+		if err := c.Status().Update(ctx, operation); err != nil {
+			return ctrl.Result{}, fmt.Errorf("update Operation status: %w", err)
+		}
+	}
+	return ctrl.Result{RequeueAfter: serverLockWaitInterval}, nil
+}
+
+// queuedOperationsForServer maps a Server to the Operations queued on it, so they are
+// reconciled as soon as its lock is released
+func queuedOperationsForServer(c client.Client) func(ctx context.Context, obj client.Object) []reconcile.Request {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		server, ok := obj.(*api.Server)
+		if !ok || server.Status.ActiveOperation != nil {
+			return nil
+		}
+
+		var operations api.OperationList
+		if err := c.List(ctx, &operations, client.InNamespace(server.Namespace), client.MatchingFields{operationServerRefField: server.Name}); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to list Operations for server", "server", server.Name)
+			return nil
+		}
+		var requests []reconcile.Request
+		for i := range operations.Items {
+			if isOperationQueued(&operations.Items[i]) {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&operations.Items[i])})
+			}
+		}
+		return requests
+	}
+}
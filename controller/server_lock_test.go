@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+func TestServerLockBlocker(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	operation := func(name string, priority int32, age time.Duration, phase api.OperationPhase) api.Operation {
+		return api.Operation{
+			ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name), CreationTimestamp: metav1.NewTime(created.Add(-age))},
+			Spec:       api.OperationSpec{Priority: priority},
+			Status:     api.OperationStatus{Phase: phase},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		self    api.Operation
+		others  []api.Operation
+		holder  *api.OperationReference
+		blocked string
+	}{
+		{
+			name: "free server",
+			self: operation("a", 0, time.Minute, ""),
+		},
+		{
+			name:    "another operation running",
+			self:    operation("a", 0, time.Minute, ""),
+			others:  []api.Operation{operation("b", 0, 0, api.OperationPhaseRunning)},
+			blocked: "Waiting for operation b",
+		},
+		{
+			name:    "live lock holder",
+			self:    operation("a", 0, time.Minute, ""),
+			others:  []api.Operation{operation("b", 0, 0, api.OperationPhasePending)},
+			holder:  &api.OperationReference{Name: "b", UID: "b"},
+			blocked: "Waiting for operation b",
+		},
+		{
+			name:   "stale lock holder",
+			self:   operation("a", 0, time.Minute, ""),
+			others: []api.Operation{operation("b", 0, 0, api.OperationPhaseSucceeded)},
+			holder: &api.OperationReference{Name: "b", UID: "b"},
+		},
+		{
+			name:    "older operation first",
+			self:    operation("a", 0, time.Minute, ""),
+			others:  []api.Operation{operation("b", 0, time.Hour, api.OperationPhasePending)},
+			blocked: "Queued behind operation b on server s1 (position 2 of 2)",
+		},
+		{
+			name:   "higher priority first",
+			self:   operation("a", 10, time.Minute, ""),
+			others: []api.Operation{operation("b", 0, time.Hour, api.OperationPhasePending)},
+		},
+		{
+			name: "cancelled operations do not queue",
+			self: operation("a", 0, time.Minute, ""),
+			others: []api.Operation{func() api.Operation {
+				op := operation("b", 0, time.Hour, api.OperationPhasePending)
+				op.Spec.Cancel = true
+				return op
+			}()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &api.Server{ObjectMeta: metav1.ObjectMeta{Name: "s1"}, Status: api.ServerStatus{ActiveOperation: tt.holder}}
+			others := append([]api.Operation{tt.self}, tt.others...)
+			got := serverLockBlocker(&tt.self, server, others)
+			switch {
+			case tt.blocked == "" && got != "":
+				t.Errorf("expected to acquire the lock, got %q", got)
+			case tt.blocked != "" && !strings.Contains(got, tt.blocked):
+				t.Errorf("expected blocker containing %q, got %q", tt.blocked, got)
+			}
+		})
+	}
+}
+
+func TestWaitingForProfile(t *testing.T) {
+	ready := &api.ProvisioningProfile{ObjectMeta: metav1.ObjectMeta{Name: "ready", Namespace: "dc", Generation: 1}}
+	setCondition(&ready.Status.Conditions, 1, api.ConditionProfileValid, metav1.ConditionTrue, api.ReasonValid, "Profile is valid")
+	invalid := &api.ProvisioningProfile{ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "dc", Generation: 1}}
+	setCondition(&invalid.Status.Conditions, 1, api.ConditionProfileValid, metav1.ConditionFalse, api.ReasonInvalid, "secret ssh not found")
+	c := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(ready, invalid).Build()
+
+	tests := []struct {
+		profile string
+		want    bool
+	}{
+		{"", false},
+		{"ready", false},
+		{"invalid", true},
+		{"missing", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			operation := &api.Operation{ObjectMeta: metav1.ObjectMeta{Name: "op", Namespace: "dc"}}
+			operation.Spec.ProvisioningProfileRef.Name = tt.profile
+			got, err := waitingForProfile(context.Background(), c, operation)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("waitingForProfile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
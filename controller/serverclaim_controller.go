@@ -0,0 +1,396 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// serverClaimFinalizer keeps a deleted ServerClaim around until its Servers are released
+const serverClaimFinalizer = "stargate.io/serverclaim-release"
+
+// ServerClaimReconciler binds free Servers to ServerClaims, repaves them with the claim's
+// ProvisioningProfile, and wipes and releases them when the claim is deleted
+type ServerClaimReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// claimedBy returns true if the Server is bound to the claim
+func claimedBy(server *api.Server, claim *api.ServerClaim) bool {
+	return server.Status.ClaimRef != nil && server.Status.ClaimRef.UID == claim.UID
+}
+
+// isServerFree returns true if the Server is not bound to any claim and is available, i.e. has
+// been decommissioned or wiped. Ready Servers are workers in the cluster running workloads and
+// are never taken by a claim.
+func isServerFree(server *api.Server) bool {
+	return server.Status.ClaimRef == nil && server.Status.State == "available"
+}
+
+// claimOperationName returns the name of the repave Operation a claim creates for a Server
+func claimOperationName(claim *api.ServerClaim, serverName string) string {
+	return fmt.Sprintf("%s-%s", claim.Name, serverName)
+}
+
+// wipeOperationName returns the name of the wipe Operation that releases a Server from a claim.
+// Wipe Operations outlive the claim, so the claim's UID keeps a re-created claim of the same
+// name from mistaking an old wipe for its own.
+func wipeOperationName(claim *api.ServerClaim, serverName string) string {
+	return fmt.Sprintf("%s-%s-wipe-%s", claim.Name, serverName, string(claim.UID)[:min(8, len(claim.UID))])
+}
+
+// planServerClaim returns the Servers already bound to the claim and the free Servers to bind
+// next to reach spec.count. Candidates are matched by selector and inventory and taken in the
+// order of selectServers, so claims fill racks in a predictable order.
+func planServerClaim(claim *api.ServerClaim, servers []api.Server, selector labels.Selector) (bound, bind []string) {
+	var free []api.Server
+	for i := range servers {
+		server := &servers[i]
+		switch {
+		case claimedBy(server, claim):
+			bound = append(bound, server.Name)
+		case isServerFree(server) && selector.Matches(labels.Set(server.Labels)):
+			free = append(free, *server)
+		}
+	}
+	sort.Strings(bound)
+
+	for _, server := range selectServers(claim.Spec.Inventory, free) {
+		if int32(len(bound)+len(bind)) >= claim.Spec.Count {
+			break
+		}
+		bind = append(bind, server.Name)
+	}
+	return bound, bind
+}
+
+// +kubebuilder:rbac:groups=stargate.io,resources=serverclaims,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=stargate.io,resources=serverclaims/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=stargate.io,resources=serverclaims/finalizers,verbs=update
+// +kubebuilder:rbac:groups=stargate.io,resources=servers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=stargate.io,resources=servers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=stargate.io,resources=operations,verbs=get;list;watch;create;update;patch
+
+// Reconcile binds Servers to a ServerClaim and tracks their provisioning, or releases them
+// once the claim is deleted
+func (r *ServerClaimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var claim api.ServerClaim
+	if err := r.Get(ctx, req.NamespacedName, &claim); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if claim.DeletionTimestamp != nil {
+		return r.release(ctx, &claim)
+	}
+
+	if controllerutil.AddFinalizer(&claim, serverClaimFinalizer) {
+		if err := r.Update(ctx, &claim); err != nil {
+			return ctrl.Result{}, fmt.Errorf("add finalizer: %w", err)
+		}
+	}
+
+	selector := labels.Everything()
+	if claim.Spec.Selector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(claim.Spec.Selector); err != nil {
+			return r.updateStatus(ctx, &claim, nil, api.ServerClaimPhasePending, fmt.Sprintf("Invalid selector: %v", err))
+		}
+	}
+
+	var serverList api.ServerList
+	if err := r.List(ctx, &serverList, client.InNamespace(claim.Namespace)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("list servers: %w", err)
+	}
+
+	// Bind through the status subresource. Two claims racing for the same Server conflict on
+	// its resourceVersion, and the loser picks another Server on the next reconcile.
+	bound, bind := planServerClaim(&claim, serverList.Items, selector)
+	for _, name := range bind {
+		server := findServer(serverList.Items, name)
+		server.Status.ClaimRef = &api.ClaimReference{Name: claim.Name, UID: claim.UID}
+		if err := r.Status().Update(ctx, server); err != nil {
+			if apierrors.IsConflict(err) {
+				logger.Info("Server changed while binding, retrying", "server", name)
+				return ctrl.Result{Requeue: true}, nil
+			}
+			return ctrl.Result{}, fmt.Errorf("bind server %s: %w", name, err)
+		}
+		logger.Info("Bound server", "server", name)
+		bound = append(bound, name)
+	}
+	sort.Strings(bound)
+
+	if int32(len(bound)) < claim.Spec.Count {
+		return r.updateStatus(ctx, &claim, bound, api.ServerClaimPhasePending,
+			fmt.Sprintf("Bound %d of %d servers, waiting for free servers", len(bound), claim.Spec.Count))
+	}
+	if claim.Spec.ProvisioningProfileRef == nil {
+		return r.updateStatus(ctx, &claim, bound, api.ServerClaimPhaseBound, fmt.Sprintf("Bound %d servers", len(bound)))
+	}
+
+	// Repave every bound Server with the claim's profile
+	operations, err := r.claimOperations(ctx, &claim)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	var provisioned int
+	var failed []string
+	for _, name := range bound {
+		op, ok := operations[claimOperationName(&claim, name)]
+		switch {
+		case !ok:
+			if err := r.createRepave(ctx, &claim, name); err != nil {
+				return ctrl.Result{}, err
+			}
+			logger.Info("Started repave", "server", name, "provisioningProfile", claim.Spec.ProvisioningProfileRef.Name)
+		case op.Status.Phase == api.OperationPhaseSucceeded:
+			provisioned++
+		case isOperationFinished(op):
+			failed = append(failed, name)
+		}
+	}
+
+	switch {
+	case provisioned == len(bound):
+		return r.updateStatus(ctx, &claim, bound, api.ServerClaimPhaseBound, fmt.Sprintf("Bound and provisioned %d servers", len(bound)))
+	case len(failed) > 0:
+		return r.updateStatus(ctx, &claim, bound, api.ServerClaimPhaseProvisioning,
+			fmt.Sprintf("Provisioned %d of %d servers, repave failed on %v", provisioned, len(bound), failed))
+	default:
+		return r.updateStatus(ctx, &claim, bound, api.ServerClaimPhaseProvisioning,
+			fmt.Sprintf("Provisioned %d of %d servers", provisioned, len(bound)))
+	}
+}
+
+// release returns the claim's Servers to the pool, wiping them first unless the release policy
+// is Retain, then removes the finalizer so the claim can be deleted
+func (r *ServerClaimReconciler) release(ctx context.Context, claim *api.ServerClaim) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(claim, serverClaimFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	var serverList api.ServerList
+	if err := r.List(ctx, &serverList, client.InNamespace(claim.Namespace)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("list servers: %w", err)
+	}
+	operations, err := r.claimOperations(ctx, claim)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var remaining []string
+	for i := range serverList.Items {
+		server := &serverList.Items[i]
+		if !claimedBy(server, claim) {
+			continue
+		}
+
+		// Stop an in-flight repave before anything else touches the Server
+		if repave, ok := operations[claimOperationName(claim, server.Name)]; ok && !isOperationFinished(repave) {
+			if !repave.Spec.Cancel {
+				repave.Spec.Cancel = true
+				if err := r.Update(ctx, repave); err != nil {
+					return ctrl.Result{}, fmt.Errorf("cancel operation %s: %w", repave.Name, err)
+				}
+				logger.Info("Cancelled repave", "server", server.Name, "operation", repave.Name)
+			}
+			remaining = append(remaining, server.Name)
+			continue
+		}
+
+		if claim.Spec.ReleasePolicy != api.ReleasePolicyRetain {
+			wipe, ok := operations[wipeOperationName(claim, server.Name)]
+			if !ok {
+				if err := r.createWipe(ctx, claim, server.Name); err != nil {
+					return ctrl.Result{}, err
+				}
+				logger.Info("Started wipe", "server", server.Name)
+				remaining = append(remaining, server.Name)
+				continue
+			}
+			if !isOperationFinished(wipe) {
+				remaining = append(remaining, server.Name)
+				continue
+			}
+			// A Server that could not be wiped may still hold the tenant's data, so it goes back
+			// to the pool in state error and is not bound again until someone repaves it
+			if wipe.Status.Phase != api.OperationPhaseSucceeded {
+				server.Status.State = "error"
+				server.Status.Message = fmt.Sprintf("Wipe operation %s did not succeed; server needs attention", wipe.Name)
+				server.Status.LastUpdated = metav1.Now()
+				setServerReadyCondition(server)
+			}
+		}
+
+		server.Status.ClaimRef = nil
+		if err := r.Status().Update(ctx, server); err != nil {
+			return ctrl.Result{}, fmt.Errorf("release server %s: %w", server.Name, err)
+		}
+		logger.Info("Released server", "server", server.Name)
+	}
+
+	if len(remaining) > 0 {
+		return r.updateStatus(ctx, claim, remaining, api.ServerClaimPhaseReleasing, fmt.Sprintf("Releasing %d servers", len(remaining)))
+	}
+
+	controllerutil.RemoveFinalizer(claim, serverClaimFinalizer)
+	if err := r.Update(ctx, claim); err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, fmt.Errorf("remove finalizer: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// claimOperations returns the claim's Operations by name
+func (r *ServerClaimReconciler) claimOperations(ctx context.Context, claim *api.ServerClaim) (map[string]*api.Operation, error) {
+	var operationList api.OperationList
+	if err := r.List(ctx, &operationList, client.InNamespace(claim.Namespace), client.MatchingLabels{api.ServerClaimLabel: claim.Name}); err != nil {
+		return nil, fmt.Errorf("list operations: %w", err)
+	}
+	operations := make(map[string]*api.Operation, len(operationList.Items))
+	for i := range operationList.Items {
+		operations[operationList.Items[i].Name] = &operationList.Items[i]
+	}
+	return operations, nil
+}
+
+// createRepave creates the repave Operation for a bound Server, owned by the claim
+func (r *ServerClaimReconciler) createRepave(ctx context.Context, claim *api.ServerClaim, serverName string) error {
+	op := &api.Operation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      claimOperationName(claim, serverName),
+			Namespace: claim.Namespace,
+			Labels:    map[string]string{api.ServerClaimLabel: claim.Name},
+		},
+		Spec: api.OperationSpec{
+			ServerRef:              api.LocalObjectReference{Name: serverName},
+			ProvisioningProfileRef: *claim.Spec.ProvisioningProfileRef,
+			Operation:              api.OperationTypeRepave,
+		},
+	}
+	if err := controllerutil.SetControllerReference(claim, op, r.Scheme); err != nil {
+		return fmt.Errorf("set owner on operation %s: %w", op.Name, err)
+	}
+	if err := r.Create(ctx, op); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create operation %s: %w", op.Name, err)
+	}
+	return nil
+}
+
+// createWipe creates the wipe Operation that releases a Server. It is not owned by the claim
+// so it is kept as a record of the wipe after the claim is gone.
+func (r *ServerClaimReconciler) createWipe(ctx context.Context, claim *api.ServerClaim, serverName string) error {
+	op := &api.Operation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      wipeOperationName(claim, serverName),
+			Namespace: claim.Namespace,
+			Labels:    map[string]string{api.ServerClaimLabel: claim.Name},
+		},
+		Spec: api.OperationSpec{
+			ServerRef: api.LocalObjectReference{Name: serverName},
+			Operation: api.OperationTypeWipe,
+		},
+	}
+	if err := r.Create(ctx, op); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create operation %s: %w", op.Name, err)
+	}
+	return nil
+}
+
+// updateStatus records the claim's bound Servers and phase
+func (r *ServerClaimReconciler) updateStatus(ctx context.Context, claim *api.ServerClaim, bound []string, phase api.ServerClaimPhase, message string) (ctrl.Result, error) {
+	claim.Status.Phase = phase
+	claim.Status.Message = message
+	claim.Status.Servers = bound
+	claim.Status.Bound = int32(len(bound))
+	claim.Status.ObservedGeneration = claim.Generation
+
+	switch phase {
+	case api.ServerClaimPhaseBound:
+		setCondition(&claim.Status.Conditions, claim.Generation, api.ConditionReady, metav1.ConditionTrue, api.ReasonBound, message)
+	case api.ServerClaimPhasePending:
+		setCondition(&claim.Status.Conditions, claim.Generation, api.ConditionReady, metav1.ConditionFalse, api.ReasonInsufficientServers, message)
+	case api.ServerClaimPhaseReleasing:
+		setCondition(&claim.Status.Conditions, claim.Generation, api.ConditionReady, metav1.ConditionFalse, api.ReasonReleasing, message)
+	default:
+		setCondition(&claim.Status.Conditions, claim.Generation, api.ConditionReady, metav1.ConditionFalse, api.ReasonInProgress, message)
+	}
+
+	if err := r.Status().Update(ctx, claim); err != nil {
+		return ctrl.Result{}, fmt.Errorf("update ServerClaim status: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// findServer returns the Server with the given name
+func findServer(servers []api.Server, name string) *api.Server {
+	for i := range servers {
+		if servers[i].Name == name {
+			return &servers[i]
+		}
+	}
+	return nil
+}
+
+// claimsForOperation maps an Operation to the claim that created it
+func (r *ServerClaimReconciler) claimsForOperation(ctx context.Context, obj client.Object) []reconcile.Request {
+	name, ok := obj.GetLabels()[api.ServerClaimLabel]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: obj.GetNamespace(), Name: name}}}
+}
+
+// claimsForServer maps a Server to the claim it is bound to, or to the claims still waiting
+// for Servers if it is free
+func (r *ServerClaimReconciler) claimsForServer(ctx context.Context, obj client.Object) []reconcile.Request {
+	server, ok := obj.(*api.Server)
+	if !ok {
+		return nil
+	}
+	if server.Status.ClaimRef != nil {
+		return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: server.Namespace, Name: server.Status.ClaimRef.Name}}}
+	}
+	if !isServerFree(server) {
+		return nil
+	}
+
+	var claims api.ServerClaimList
+	if err := r.List(ctx, &claims, client.InNamespace(server.Namespace)); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list ServerClaims for server", "server", server.Name)
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, claim := range claims.Items {
+		if claim.Status.Phase == "" || claim.Status.Phase == api.ServerClaimPhasePending {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&claim)})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *ServerClaimReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&api.ServerClaim{}).
+		Watches(&api.Operation{}, handler.EnqueueRequestsFromMapFunc(r.claimsForOperation)).
+		Watches(&api.Server{}, handler.EnqueueRequestsFromMapFunc(r.claimsForServer)).
+		Complete(r)
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+func TestPlanServerClaim(t *testing.T) {
+	claim := &api.ServerClaim{ObjectMeta: metav1.ObjectMeta{Name: "training", UID: "claim-uid"}}
+	other := &api.ClaimReference{Name: "other", UID: "other-uid"}
+
+	server := func(name, location, state string, claimRef *api.ClaimReference) api.Server {
+		return api.Server{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"pool": "gpu"}},
+			Spec:       api.ServerSpec{Inventory: api.ServerInventory{SKU: "GPU-8xH100", Location: location}},
+			Status:     api.ServerStatus{State: state, ClaimRef: claimRef},
+		}
+	}
+	servers := []api.Server{
+		server("s5", "rack-1-slot-4", "ready", nil),
+		server("s4", "rack-2-slot-1", "available", nil),
+		server("s3", "rack-1-slot-3", "available", other),
+		server("s2", "rack-1-slot-2", "error", nil),
+		server("s1", "rack-1-slot-1", "available", nil),
+		{ObjectMeta: metav1.ObjectMeta{Name: "s0"}, Status: api.ServerStatus{State: "available"}},
+	}
+
+	tests := []struct {
+		name     string
+		count    int32
+		inv      *api.InventorySelector
+		selector labels.Selector
+		bound    []string
+		bind     []string
+	}{
+		{"binds available servers in rack order", 2, nil, labels.SelectorFromSet(labels.Set{"pool": "gpu"}), nil, []string{"s1", "s4"}},
+		{"not enough free servers", 3, nil, labels.SelectorFromSet(labels.Set{"pool": "gpu"}), nil, []string{"s1", "s4"}},
+		{"inventory filter", 2, &api.InventorySelector{LocationPrefix: "rack-2"}, labels.Everything(), nil, []string{"s4"}},
+		{"everything selector", 3, nil, labels.Everything(), nil, []string{"s0", "s1", "s4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claim.Spec = api.ServerClaimSpec{Count: tt.count, Inventory: tt.inv}
+			bound, bind := planServerClaim(claim, servers, tt.selector)
+			if !reflect.DeepEqual(bound, tt.bound) {
+				t.Errorf("expected bound %v, got %v", tt.bound, bound)
+			}
+			if !reflect.DeepEqual(bind, tt.bind) {
+				t.Errorf("expected to bind %v, got %v", tt.bind, bind)
+			}
+		})
+	}
+
+	t.Run("already bound servers count toward the claim", func(t *testing.T) {
+		claimed := append([]api.Server(nil), servers...)
+		claimed[1].Status.ClaimRef = &api.ClaimReference{Name: claim.Name, UID: claim.UID}
+		claim.Spec = api.ServerClaimSpec{Count: 2}
+		bound, bind := planServerClaim(claim, claimed, labels.Everything())
+		if !reflect.DeepEqual(bound, []string{"s4"}) || !reflect.DeepEqual(bind, []string{"s0"}) {
+			t.Errorf("expected bound [s4] and bind [s0], got %v and %v", bound, bind)
+		}
+	})
+}
@@ -0,0 +1,380 @@
+// Package bmc provides a Redfish client for out-of-band server management.
+package bmc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResetType is a Redfish ComputerSystem.Reset action type.
+type ResetType string
+
+const (
+	ResetOn              ResetType = "On"
+	ResetForceOff        ResetType = "ForceOff"
+	ResetGracefulRestart ResetType = "GracefulRestart"
+	ResetForceRestart    ResetType = "ForceRestart"
+	ResetPowerCycle      ResetType = "PowerCycle"
+)
+
+// PowerState is the power state reported by a Redfish ComputerSystem.
+type PowerState string
+
+const (
+	PowerStateOn          PowerState = "On"
+	PowerStateOff         PowerState = "Off"
+	PowerStatePoweringOn  PowerState = "PoweringOn"
+	PowerStatePoweringOff PowerState = "PoweringOff"
+)
+
+// BootSource is a Redfish boot source override target.
+type BootSource string
+
+const (
+	BootSourcePxe       BootSource = "Pxe"
+	BootSourceHdd       BootSource = "Hdd"
+	BootSourceCd        BootSource = "Cd"
+	BootSourceBiosSetup BootSource = "BiosSetup"
+)
+
+// Client is a Redfish BMC client.
+// It authenticates with HTTP basic auth until Login is called, after which
+// requests carry the session token instead.
+type Client struct {
+	httpClient *http.Client
+	endpoint   string
+	username   string
+	password   string
+
+	// Session state
+	sessionToken    string
+	sessionLocation string
+	systemPath      string
+	mu              sync.Mutex
+}
+
+// NewClient creates a new Redfish client for the BMC at address.
+// address may be a bare host (https is assumed) or a full URL.
+// BMCs almost universally use self-signed certificates, so TLS verification is disabled.
+func NewClient(address, username, password string) *Client {
+	endpoint := strings.TrimSuffix(address, "/")
+	if !strings.HasPrefix(endpoint, "https://") && !strings.HasPrefix(endpoint, "http://") {
+		endpoint = "https://" + endpoint
+	}
+
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
+		endpoint: endpoint,
+		username: username,
+		password: password,
+	}
+}
+
+// System is the subset of a Redfish ComputerSystem used by stargate.
+type System struct {
+	ID           string     `json:"Id"`
+	Name         string     `json:"Name"`
+	Manufacturer string     `json:"Manufacturer"`
+	Model        string     `json:"Model"`
+	SerialNumber string     `json:"SerialNumber"`
+	PowerState   PowerState `json:"PowerState"`
+	Boot         struct {
+		BootSourceOverrideEnabled string `json:"BootSourceOverrideEnabled"`
+		BootSourceOverrideTarget  string `json:"BootSourceOverrideTarget"`
+	} `json:"Boot"`
+	Actions struct {
+		Reset struct {
+			Target          string      `json:"target"`
+			AllowableValues []ResetType `json:"ResetType@Redfish.AllowableValues"`
+		} `json:"#ComputerSystem.Reset"`
+	} `json:"Actions"`
+}
+
+// odataID is a Redfish reference to another resource.
+type odataID struct {
+	ID string `json:"@odata.id"`
+}
+
+// doRequest performs an authenticated Redfish request and returns the response.
+func (c *Client) doRequest(ctx context.Context, method, path string, body interface{}) (*http.Response, []byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("marshal request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, reqBody)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create request: %w", err)
+	}
+
+	c.mu.Lock()
+	token := c.sessionToken
+	c.mu.Unlock()
+	if token != "" {
+		req.Header.Set("X-Auth-Token", token)
+	} else {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, nil, fmt.Errorf("redfish error %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return resp, respBody, nil
+}
+
+// Login creates a Redfish session. Subsequent requests use the session token.
+func (c *Client) Login(ctx context.Context) error {
+	body := map[string]string{"UserName": c.username, "Password": c.password}
+	resp, _, err := c.doRequest(ctx, http.MethodPost, "/redfish/v1/SessionService/Sessions", body)
+	if err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+
+	token := resp.Header.Get("X-Auth-Token")
+	if token == "" {
+		return fmt.Errorf("create session: BMC returned no X-Auth-Token")
+	}
+
+	// Location may be absolute or relative to the service root
+	location := resp.Header.Get("Location")
+	location = strings.TrimPrefix(location, c.endpoint)
+
+	c.mu.Lock()
+	c.sessionToken = token
+	c.sessionLocation = location
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Logout deletes the Redfish session created by Login, if any.
+func (c *Client) Logout(ctx context.Context) error {
+	c.mu.Lock()
+	location := c.sessionLocation
+	c.mu.Unlock()
+
+	if location == "" {
+		return nil
+	}
+
+	_, _, err := c.doRequest(ctx, http.MethodDelete, location, nil)
+
+	c.mu.Lock()
+	c.sessionToken = ""
+	c.sessionLocation = ""
+	c.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	return nil
+}
+
+// Systems lists the paths of the ComputerSystems exposed by the BMC.
+func (c *Client) Systems(ctx context.Context) ([]string, error) {
+	_, data, err := c.doRequest(ctx, http.MethodGet, "/redfish/v1/Systems", nil)
+	if err != nil {
+		return nil, fmt.Errorf("list systems: %w", err)
+	}
+
+	var collection struct {
+		Members []odataID `json:"Members"`
+	}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, fmt.Errorf("unmarshal systems: %w", err)
+	}
+
+	paths := make([]string, 0, len(collection.Members))
+	for _, m := range collection.Members {
+		paths = append(paths, m.ID)
+	}
+	return paths, nil
+}
+
+// discoverSystem returns the path of the managed ComputerSystem.
+// A BMC manages a single host, so the first member of the collection is used.
+func (c *Client) discoverSystem(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	path := c.systemPath
+	c.mu.Unlock()
+	if path != "" {
+		return path, nil
+	}
+
+	paths, err := c.Systems(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("BMC exposes no systems")
+	}
+
+	c.mu.Lock()
+	c.systemPath = paths[0]
+	c.mu.Unlock()
+
+	return paths[0], nil
+}
+
+// GetSystem fetches the managed ComputerSystem.
+func (c *Client) GetSystem(ctx context.Context) (*System, error) {
+	path, err := c.discoverSystem(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, data, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get system: %w", err)
+	}
+
+	var system System
+	if err := json.Unmarshal(data, &system); err != nil {
+		return nil, fmt.Errorf("unmarshal system: %w", err)
+	}
+
+	return &system, nil
+}
+
+// GetPowerState returns the current power state of the managed system.
+func (c *Client) GetPowerState(ctx context.Context) (PowerState, error) {
+	system, err := c.GetSystem(ctx)
+	if err != nil {
+		return "", err
+	}
+	return system.PowerState, nil
+}
+
+// Reset issues a ComputerSystem.Reset action of the given type.
+func (c *Client) Reset(ctx context.Context, resetType ResetType) error {
+	system, err := c.GetSystem(ctx)
+	if err != nil {
+		return err
+	}
+	return c.reset(ctx, system, resetType)
+}
+
+// reset posts the Reset action for an already-fetched system
+func (c *Client) reset(ctx context.Context, system *System, resetType ResetType) error {
+	target := system.Actions.Reset.Target
+	if target == "" {
+		path, err := c.discoverSystem(ctx)
+		if err != nil {
+			return err
+		}
+		target = path + "/Actions/ComputerSystem.Reset"
+	}
+	target = strings.TrimPrefix(target, c.endpoint)
+
+	body := map[string]string{"ResetType": string(resetType)}
+	if _, _, err := c.doRequest(ctx, http.MethodPost, target, body); err != nil {
+		return fmt.Errorf("reset %s: %w", resetType, err)
+	}
+
+	return nil
+}
+
+// PowerOn powers on the managed system. It is a no-op if the system is already on.
+func (c *Client) PowerOn(ctx context.Context) error {
+	system, err := c.GetSystem(ctx)
+	if err != nil {
+		return err
+	}
+	if system.PowerState == PowerStateOn || system.PowerState == PowerStatePoweringOn {
+		return nil
+	}
+	return c.reset(ctx, system, ResetOn)
+}
+
+// PowerOff immediately powers off the managed system. It is a no-op if the system is already off.
+func (c *Client) PowerOff(ctx context.Context) error {
+	system, err := c.GetSystem(ctx)
+	if err != nil {
+		return err
+	}
+	if system.PowerState == PowerStateOff || system.PowerState == PowerStatePoweringOff {
+		return nil
+	}
+	return c.reset(ctx, system, ResetForceOff)
+}
+
+// PowerCycle power cycles the managed system. BMCs that don't implement the
+// PowerCycle reset type get a ForceRestart instead; a system that is off is powered on.
+func (c *Client) PowerCycle(ctx context.Context) error {
+	system, err := c.GetSystem(ctx)
+	if err != nil {
+		return err
+	}
+	if system.PowerState == PowerStateOff {
+		return c.reset(ctx, system, ResetOn)
+	}
+
+	resetType := ResetPowerCycle
+	if allowed := system.Actions.Reset.AllowableValues; len(allowed) > 0 && !containsResetType(allowed, ResetPowerCycle) {
+		resetType = ResetForceRestart
+	}
+	return c.reset(ctx, system, resetType)
+}
+
+// SetOneTimeBoot overrides the boot source for the next boot only.
+func (c *Client) SetOneTimeBoot(ctx context.Context, source BootSource) error {
+	path, err := c.discoverSystem(ctx)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]interface{}{
+		"Boot": map[string]string{
+			"BootSourceOverrideEnabled": "Once",
+			"BootSourceOverrideTarget":  string(source),
+		},
+	}
+	if _, _, err := c.doRequest(ctx, http.MethodPatch, path, body); err != nil {
+		return fmt.Errorf("set boot override %s: %w", source, err)
+	}
+
+	return nil
+}
+
+// containsResetType checks if a reset type is in the list.
+func containsResetType(types []ResetType, t ResetType) bool {
+	for _, v := range types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
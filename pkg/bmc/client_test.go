@@ -0,0 +1,213 @@
+package bmc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// mockRedfish is a minimal in-process Redfish service exposing a single system
+type mockRedfish struct {
+	mu         sync.Mutex
+	powerState PowerState
+	allowed    []ResetType
+	resets     []ResetType
+	boot       map[string]string
+	sessions   map[string]bool
+}
+
+func newMockRedfish(t *testing.T, state PowerState) (*mockRedfish, *httptest.Server) {
+	m := &mockRedfish{
+		powerState: state,
+		allowed:    []ResetType{ResetOn, ResetForceOff, ResetGracefulRestart, ResetForceRestart, ResetPowerCycle},
+		sessions:   map[string]bool{},
+	}
+	server := httptest.NewServer(http.HandlerFunc(m.serveHTTP))
+	t.Cleanup(server.Close)
+	return m, server
+}
+
+func (m *mockRedfish) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r.Method == http.MethodPost && r.URL.Path == "/redfish/v1/SessionService/Sessions" {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["UserName"] != "admin" || body["Password"] != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		m.sessions["token-1"] = true
+		w.Header().Set("X-Auth-Token", "token-1")
+		w.Header().Set("Location", "/redfish/v1/SessionService/Sessions/1")
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	// Everything else requires either a session token or basic auth
+	if token := r.Header.Get("X-Auth-Token"); token != "" {
+		if !m.sessions[token] {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	} else if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodDelete && r.URL.Path == "/redfish/v1/SessionService/Sessions/1":
+		delete(m.sessions, "token-1")
+	case r.Method == http.MethodGet && r.URL.Path == "/redfish/v1/Systems":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"Members": []odataID{{ID: "/redfish/v1/Systems/1"}},
+		})
+	case r.Method == http.MethodGet && r.URL.Path == "/redfish/v1/Systems/1":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"Id":         "1",
+			"PowerState": m.powerState,
+			"Actions": map[string]interface{}{
+				"#ComputerSystem.Reset": map[string]interface{}{
+					"target":                            "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset",
+					"ResetType@Redfish.AllowableValues": m.allowed,
+				},
+			},
+		})
+	case r.Method == http.MethodPost && r.URL.Path == "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset":
+		var body map[string]ResetType
+		json.NewDecoder(r.Body).Decode(&body)
+		m.resets = append(m.resets, body["ResetType"])
+		switch body["ResetType"] {
+		case ResetOn:
+			m.powerState = PowerStateOn
+		case ResetForceOff:
+			m.powerState = PowerStateOff
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPatch && r.URL.Path == "/redfish/v1/Systems/1":
+		var body map[string]map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		m.boot = body["Boot"]
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestNewClientEndpoint(t *testing.T) {
+	tests := []struct {
+		address  string
+		expected string
+	}{
+		{"10.0.0.5", "https://10.0.0.5"},
+		{"https://bmc.example.com/", "https://bmc.example.com"},
+		{"http://127.0.0.1:8000", "http://127.0.0.1:8000"},
+	}
+
+	for _, tt := range tests {
+		client := NewClient(tt.address, "", "")
+		if client.endpoint != tt.expected {
+			t.Errorf("NewClient(%q) endpoint = %q, want %q", tt.address, client.endpoint, tt.expected)
+		}
+	}
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	mock, server := newMockRedfish(t, PowerStateOn)
+	client := NewClient(server.URL, "admin", "secret")
+	ctx := context.Background()
+
+	if err := client.Login(ctx); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if client.sessionToken != "token-1" {
+		t.Errorf("expected session token 'token-1', got '%s'", client.sessionToken)
+	}
+
+	state, err := client.GetPowerState(ctx)
+	if err != nil {
+		t.Fatalf("GetPowerState: %v", err)
+	}
+	if state != PowerStateOn {
+		t.Errorf("expected power state On, got %s", state)
+	}
+
+	if err := client.Logout(ctx); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if len(mock.sessions) != 0 {
+		t.Errorf("expected session to be deleted, %d remain", len(mock.sessions))
+	}
+}
+
+func TestLoginBadCredentials(t *testing.T) {
+	_, server := newMockRedfish(t, PowerStateOn)
+	client := NewClient(server.URL, "admin", "wrong")
+
+	if err := client.Login(context.Background()); err == nil {
+		t.Fatal("expected error for bad credentials")
+	}
+}
+
+func TestPowerOperations(t *testing.T) {
+	tests := []struct {
+		name     string
+		initial  PowerState
+		allowed  []ResetType
+		action   func(*Client, context.Context) error
+		resets   []ResetType
+		expected PowerState
+	}{
+		{"power on from off", PowerStateOff, nil, (*Client).PowerOn, []ResetType{ResetOn}, PowerStateOn},
+		{"power on when on is a no-op", PowerStateOn, nil, (*Client).PowerOn, nil, PowerStateOn},
+		{"power off from on", PowerStateOn, nil, (*Client).PowerOff, []ResetType{ResetForceOff}, PowerStateOff},
+		{"power off when off is a no-op", PowerStateOff, nil, (*Client).PowerOff, nil, PowerStateOff},
+		{"power cycle", PowerStateOn, nil, (*Client).PowerCycle, []ResetType{ResetPowerCycle}, PowerStateOn},
+		{"power cycle falls back to force restart", PowerStateOn, []ResetType{ResetOn, ResetForceOff, ResetForceRestart}, (*Client).PowerCycle, []ResetType{ResetForceRestart}, PowerStateOn},
+		{"power cycle when off powers on", PowerStateOff, nil, (*Client).PowerCycle, []ResetType{ResetOn}, PowerStateOn},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, server := newMockRedfish(t, tt.initial)
+			if tt.allowed != nil {
+				mock.allowed = tt.allowed
+			}
+			client := NewClient(server.URL, "admin", "secret")
+
+			if err := tt.action(client, context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(mock.resets) != len(tt.resets) {
+				t.Fatalf("expected resets %v, got %v", tt.resets, mock.resets)
+			}
+			for i := range tt.resets {
+				if mock.resets[i] != tt.resets[i] {
+					t.Errorf("expected resets %v, got %v", tt.resets, mock.resets)
+				}
+			}
+			if mock.powerState != tt.expected {
+				t.Errorf("expected power state %s, got %s", tt.expected, mock.powerState)
+			}
+		})
+	}
+}
+
+func TestSetOneTimeBoot(t *testing.T) {
+	mock, server := newMockRedfish(t, PowerStateOn)
+	client := NewClient(server.URL, "admin", "secret")
+
+	if err := client.SetOneTimeBoot(context.Background(), BootSourcePxe); err != nil {
+		t.Fatalf("SetOneTimeBoot: %v", err)
+	}
+	if mock.boot["BootSourceOverrideEnabled"] != "Once" {
+		t.Errorf("expected override enabled 'Once', got '%s'", mock.boot["BootSourceOverrideEnabled"])
+	}
+	if mock.boot["BootSourceOverrideTarget"] != "Pxe" {
+		t.Errorf("expected override target 'Pxe', got '%s'", mock.boot["BootSourceOverrideTarget"])
+	}
+}
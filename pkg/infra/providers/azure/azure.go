@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"net"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -63,12 +62,6 @@ type Provider struct {
 	tsClient         *tailscale.Client
 }
 
-// route holds a CIDR and the next hop IP for route table construction.
-type route struct {
-	Prefix  string
-	NextHop string
-}
-
 // NewProvider initializes Azure clients.
 func NewProvider(ctx context.Context, cfg Config) (*Provider, error) {
 	cred, err := azidentity.NewAzureCLICredential(nil)
@@ -295,9 +288,6 @@ func (p *Provider) CreateNodes(ctx context.Context, specs []providers.NodeSpec)
 		aksRouteCIDRs = append(aksRouteCIDRs, podCIDRBroad)
 	}
 
-	// Build per-worker pod CIDR routes once we have private IPs
-	workerRoutes := make([]route, 0, len(workerSpecs))
-
 	// Create workers with router IP for Tailscale routing
 	for _, spec := range workerSpecs {
 		nicName := fmt.Sprintf("%s-nic", spec.Name)
@@ -321,24 +311,17 @@ func (p *Provider) CreateNodes(ctx context.Context, specs []providers.NodeSpec)
 			return nil, fmt.Errorf("get private IP %s: %w", nicName, err)
 		}
 
-		podCIDR := derivePodCIDR(privIP)
-
 		nodes = append(nodes, providers.NodeInfo{
 			Name:      spec.Name,
 			Role:      providers.RoleWorker,
 			PublicIP:  "",
 			PrivateIP: privIP,
 			RouterIP:  routerIP,
-			PodCIDR:   podCIDR,
 		})
-
-		if podCIDR != "" {
-			workerRoutes = append(workerRoutes, route{Prefix: podCIDR, NextHop: privIP})
-		}
 	}
 
 	if routerIP != "" && len(workerSpecs) > 0 {
-		if err := p.ensureRouteTable(ctx, subnetID, routerIP, aksRouteCIDRs, workerRoutes); err != nil {
+		if err := p.ensureRouteTable(ctx, subnetID, routerIP, aksRouteCIDRs); err != nil {
 			return nil, fmt.Errorf("route table: %w", err)
 		}
 	}
@@ -403,7 +386,6 @@ func (p *Provider) createAKSRouter(ctx context.Context, sshKey string) (provider
 	}
 
 	// Set up route table in the AKS VNet for DC-bound traffic
-	// Routes DC pod CIDRs (10.244.50-70.0/24) through this router
 	if err := p.ensureAKSRouteTable(ctx, cfg, privIP); err != nil {
 		return providers.NodeInfo{}, fmt.Errorf("AKS route table: %w", err)
 	}
@@ -448,19 +430,16 @@ packages:
 }
 
 // buildRouterCloudInit creates cloud-init for a DC router that:
-// 1. Advertises the DC subnet and expected DC worker pod CIDRs to Tailscale
+// 1. Advertises the DC subnet to Tailscale (route sync adds the workers' pod CIDRs)
 // 2. Sets up kernel routes for AKS node subnet and pod CIDRs via tailscale0
 func buildRouterCloudInit(vmName, adminUser, sshPublicKey, tailscaleAuthKey, subnetCIDR string) (string, error) {
 	if tailscaleAuthKey == "" {
 		return "", fmt.Errorf("missing tailscale auth key for router %s", vmName)
 	}
 
-	// Advertise: DC subnet + expected DC worker pod CIDRs (10.244.50-70.0/24 range)
-	// This enables AKS to reach DC worker pods
+	// Advertise: DC subnet. The workers' pod CIDRs are allocated from a PodCIDRPool when
+	// they are repaved, and route sync adds them to the advertisement.
 	advertiseRoutes := subnetCIDR
-	for i := 50; i <= 70; i++ {
-		advertiseRoutes += fmt.Sprintf(",10.244.%d.0/24", i)
-	}
 
 	cloudInit := baseCloudInitHeader(vmName, adminUser, sshPublicKey)
 	cloudInit += fmt.Sprintf(`
@@ -509,7 +488,7 @@ runcmd:
 // buildAKSRouterCloudInit creates cloud-init for an AKS router that:
 // 1. Advertises multiple CIDRs (VNet, Pod, Service) to Tailscale
 // 2. Sets up a proxy to the AKS API server
-// 3. Sets up kernel routes for DC worker pod CIDRs via tailscale0
+// Kernel routes for DC worker pod CIDRs via tailscale0 are programmed by route sync.
 func buildAKSRouterCloudInit(vmName, adminUser, sshPublicKey, tailscaleAuthKey string, routeCIDRs []string, apiServerFQDN string) (string, error) {
 	if tailscaleAuthKey == "" {
 		return "", fmt.Errorf("missing tailscale auth key for router %s", vmName)
@@ -536,23 +515,6 @@ write_files:
       iptables -t nat -A POSTROUTING -o tailscale0 -j MASQUERADE
       mkdir -p /etc/iptables
       iptables-save > /etc/iptables/rules.v4 || true
-      
-      # Add kernel routes for DC worker pod CIDRs via tailscale0
-      # These are in the 10.244.50-250 range based on derivePodCIDR formula
-      # We add routes for the common range used by DC workers
-      for i in $(seq 50 70); do
-        ip route add 10.244.$i.0/24 dev tailscale0 2>/dev/null || true
-      done
-      
-      # Persist routes for reboot - write script using echo to avoid YAML heredoc issues
-      mkdir -p /etc/network/if-up.d
-      echo '#!/bin/bash' > /etc/network/if-up.d/stargate-routes
-      echo 'if [ "$IFACE" = "tailscale0" ]; then' >> /etc/network/if-up.d/stargate-routes
-      echo '  for i in $(seq 50 70); do' >> /etc/network/if-up.d/stargate-routes
-      echo '    ip route add 10.244.$i.0/24 dev tailscale0 2>/dev/null || true' >> /etc/network/if-up.d/stargate-routes
-      echo '  done' >> /etc/network/if-up.d/stargate-routes
-      echo 'fi' >> /etc/network/if-up.d/stargate-routes
-      chmod +x /etc/network/if-up.d/stargate-routes
 `, tailscaleAuthKey, vmName, routes)
 
 	// Add AKS API proxy if FQDN is provided
@@ -907,9 +869,10 @@ func (p *Provider) ensureNICWithIPForwarding(ctx context.Context, name, subnetID
 	return *resp.ID, nil
 }
 
-// ensureRouteTable creates a route table with Tailscale CGNAT route, optional extra routes (e.g., AKS CIDRs),
-// and per-worker pod CIDR routes, then associates it with the subnet
-func (p *Provider) ensureRouteTable(ctx context.Context, subnetID, routerIP string, extraRouteCIDRs []string, workerRoutes []route) error {
+// ensureRouteTable creates a route table with Tailscale CGNAT route and optional extra routes (e.g., AKS CIDRs),
+// then associates it with the subnet. Worker pod CIDRs are allocated from a PodCIDRPool when the
+// workers are repaved, and their routes are programmed by route sync.
+func (p *Provider) ensureRouteTable(ctx context.Context, subnetID, routerIP string, extraRouteCIDRs []string) error {
 	routeTableName := fmt.Sprintf("%s-route-table", p.cfg.ResourceGroup)
 
 	// Build routes: Tailscale CGNAT + any extra CIDRs
@@ -934,21 +897,6 @@ func (p *Provider) ensureRouteTable(ctx context.Context, subnetID, routerIP stri
 		})
 	}
 
-	// Add per-worker pod CIDR routes to keep pod egress symmetric and avoid blackholes
-	for i, wr := range workerRoutes {
-		if wr.Prefix == "" || wr.NextHop == "" {
-			continue
-		}
-		routes = append(routes, &armnetwork.Route{
-			Name: to.Ptr(fmt.Sprintf("worker-pod-route-%d", i)),
-			Properties: &armnetwork.RoutePropertiesFormat{
-				AddressPrefix:    to.Ptr(wr.Prefix),
-				NextHopType:      to.Ptr(armnetwork.RouteNextHopTypeVirtualAppliance),
-				NextHopIPAddress: to.Ptr(wr.NextHop),
-			},
-		})
-	}
-
 	// Check if route table exists
 	existing, err := p.routeTableClient.Get(ctx, p.cfg.ResourceGroup, routeTableName, nil)
 	if err != nil && !isNotFound(err) {
@@ -1067,11 +1015,11 @@ func (p *Provider) ensureTailscaleRoutes(ctx context.Context, hostname string) e
 // ensureAKSRouteTable creates a route table in the AKS VNet that routes DC-bound traffic
 // through the AKS router. This includes:
 // - DC subnet (e.g., 10.50.0.0/16) → router
-// - DC worker pod CIDRs (10.244.50-70.0/24 range) → router
 // - Tailscale CGNAT (100.64.0.0/10) → router
 //
 // The route table is associated with both the AKS router subnet and the AKS node subnet
-// so that AKS nodes can reach DC workers through the router.
+// so that AKS nodes can reach DC workers through the router. Routes for DC worker pod CIDRs
+// are added by route sync once the workers have been allocated one from a PodCIDRPool.
 func (p *Provider) ensureAKSRouteTable(ctx context.Context, cfg *providers.AKSRouterConfig, routerIP string) error {
 	routeTableName := "stargate-workers-rt"
 
@@ -1095,19 +1043,6 @@ func (p *Provider) ensureAKSRouteTable(ctx context.Context, cfg *providers.AKSRo
 		},
 	}
 
-	// Add routes for DC worker pod CIDRs (10.244.50-70.0/24 range)
-	// These are derived from DC worker private IPs using derivePodCIDR formula
-	for i := 50; i <= 70; i++ {
-		routes = append(routes, &armnetwork.Route{
-			Name: to.Ptr(fmt.Sprintf("dc-pod-cidr-%d", i)),
-			Properties: &armnetwork.RoutePropertiesFormat{
-				AddressPrefix:    to.Ptr(fmt.Sprintf("10.244.%d.0/24", i)),
-				NextHopType:      to.Ptr(armnetwork.RouteNextHopTypeVirtualAppliance),
-				NextHopIPAddress: to.Ptr(routerIP),
-			},
-		})
-	}
-
 	// Query AKS nodes and add routes for their pod CIDRs (for DC → AKS traffic)
 	if cfg.ClusterRG != "" && cfg.ClusterName != "" {
 		aksNodeRoutes, err := p.getAKSNodeRoutes(ctx, cfg.ClusterRG, cfg.ClusterName)
@@ -1322,18 +1257,6 @@ func (p *Provider) associateRouteTableWithSubnet(ctx context.Context, resourceGr
 	return nil
 }
 
-// derivePodCIDR returns a /24 pod CIDR in 10.244.x.0/24 based on the node's private IP.
-// Uses the formula from the bootstrap script: (third_octet*10 + fourth_octet) % 200 + 50
-func derivePodCIDR(privateIP string) string {
-	ip := net.ParseIP(privateIP).To4()
-	if ip == nil {
-		return ""
-	}
-	// Match the bootstrap script's podCIDR derivation formula
-	uniqueOctet := (int(ip[2])*10+int(ip[3]))%200 + 50
-	return fmt.Sprintf("10.244.%d.0/24", uniqueOctet)
-}
-
 // ensureSubnetInVNet creates or gets a subnet in a specific VNet and resource group.
 func (p *Provider) ensureSubnetInVNet(ctx context.Context, resourceGroup, vnetName, subnetName, subnetCIDR string) (string, error) {
 	subnet, err := p.subnetClient.Get(ctx, resourceGroup, vnetName, subnetName, nil)
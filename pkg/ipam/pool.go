@@ -0,0 +1,121 @@
+// Package ipam allocates fixed-size pod CIDR blocks from an IPv4 range.
+package ipam
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+)
+
+// ErrPoolExhausted is returned when every block in a pool is allocated or reserved.
+var ErrPoolExhausted = errors.New("pool exhausted")
+
+// maxBlocks bounds the number of blocks in a pool, so a full scan stays cheap.
+const maxBlocks = 1 << 16
+
+// Pool divides an IPv4 range into blocks of equal size. It holds no allocation state;
+// callers pass the blocks already in use, so the state can live wherever they persist it.
+type Pool struct {
+	prefix    netip.Prefix
+	blockBits int
+	reserved  []netip.Prefix
+}
+
+// NewPool returns a pool that hands out /blockSize blocks of cidr, skipping any block that
+// overlaps a reserved CIDR.
+func NewPool(cidr string, blockSize int, reserved []string) (*Pool, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool CIDR %q: %w", cidr, err)
+	}
+	if !prefix.Addr().Is4() {
+		return nil, fmt.Errorf("pool CIDR %q is not IPv4", cidr)
+	}
+	if prefix.Masked() != prefix {
+		return nil, fmt.Errorf("pool CIDR %q has host bits set (did you mean %s?)", cidr, prefix.Masked())
+	}
+	if blockSize < prefix.Bits() || blockSize > 32 {
+		return nil, fmt.Errorf("block size /%d does not fit in pool %s", blockSize, cidr)
+	}
+	if 1<<(blockSize-prefix.Bits()) > maxBlocks {
+		return nil, fmt.Errorf("pool %s has more than %d /%d blocks", cidr, maxBlocks, blockSize)
+	}
+
+	p := &Pool{prefix: prefix, blockBits: blockSize}
+	for _, r := range reserved {
+		rp, err := netip.ParsePrefix(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reserved CIDR %q: %w", r, err)
+		}
+		p.reserved = append(p.reserved, rp.Masked())
+	}
+	return p, nil
+}
+
+// Capacity returns the number of blocks in the pool, including reserved ones.
+func (p *Pool) Capacity() int {
+	return 1 << (p.blockBits - p.prefix.Bits())
+}
+
+// Contains returns true if cidr is one of the pool's blocks.
+func (p *Pool) Contains(cidr string) bool {
+	block, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return false
+	}
+	return block.Bits() == p.blockBits && block.Masked() == block && p.prefix.Contains(block.Addr())
+}
+
+// Allocate returns the lowest block that is neither reserved nor in used.
+func (p *Pool) Allocate(used []string) (string, error) {
+	taken := make(map[netip.Prefix]bool, len(used))
+	for _, u := range used {
+		if block, err := netip.ParsePrefix(u); err == nil {
+			taken[block.Masked()] = true
+		}
+	}
+	for i := 0; i < p.Capacity(); i++ {
+		block := p.block(i)
+		if !taken[block] && !p.isReserved(block) {
+			return block.String(), nil
+		}
+	}
+	return "", fmt.Errorf("%w: all %d /%d blocks of %s are allocated or reserved", ErrPoolExhausted, p.Capacity(), p.blockBits, p.prefix)
+}
+
+// Available returns the number of blocks that are neither reserved nor in used.
+func (p *Pool) Available(used []string) int {
+	taken := make(map[netip.Prefix]bool, len(used))
+	for _, u := range used {
+		if p.Contains(u) {
+			taken[netip.MustParsePrefix(u)] = true
+		}
+	}
+	available := 0
+	for i := 0; i < p.Capacity(); i++ {
+		if block := p.block(i); !taken[block] && !p.isReserved(block) {
+			available++
+		}
+	}
+	return available
+}
+
+// block returns the i-th block of the pool
+func (p *Pool) block(i int) netip.Prefix {
+	base := p.prefix.Addr().As4()
+	n := binary.BigEndian.Uint32(base[:]) + uint32(i)<<(32-p.blockBits)
+	var addr [4]byte
+	binary.BigEndian.PutUint32(addr[:], n)
+	return netip.PrefixFrom(netip.AddrFrom4(addr), p.blockBits)
+}
+
+// isReserved returns true if block overlaps a reserved CIDR
+func (p *Pool) isReserved(block netip.Prefix) bool {
+	for _, r := range p.reserved {
+		if r.Overlaps(block) {
+			return true
+		}
+	}
+	return false
+}
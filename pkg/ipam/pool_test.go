@@ -0,0 +1,98 @@
+package ipam
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewPool(t *testing.T) {
+	tests := []struct {
+		name      string
+		cidr      string
+		blockSize int
+		reserved  []string
+		wantErr   bool
+	}{
+		{"valid", "10.244.64.0/18", 24, nil, false},
+		{"single block", "10.244.64.0/24", 24, nil, false},
+		{"invalid CIDR", "10.244.64.0", 24, nil, true},
+		{"IPv6", "fd00::/48", 64, nil, true},
+		{"host bits set", "10.244.64.1/18", 24, nil, true},
+		{"block larger than pool", "10.244.64.0/24", 16, nil, true},
+		{"too many blocks", "10.0.0.0/8", 30, nil, true},
+		{"invalid reserved", "10.244.64.0/18", 24, []string{"nope"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPool(tt.cidr, tt.blockSize, tt.reserved)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewPool() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	pool, err := NewPool("10.244.0.0/22", 24, []string{"10.244.0.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pool.Capacity(); got != 4 {
+		t.Errorf("Capacity() = %d, want 4", got)
+	}
+
+	var used []string
+	for _, want := range []string{"10.244.1.0/24", "10.244.2.0/24", "10.244.3.0/24"} {
+		got, err := pool.Allocate(used)
+		if err != nil {
+			t.Fatalf("Allocate(%v) error = %v", used, err)
+		}
+		if got != want {
+			t.Errorf("Allocate(%v) = %s, want %s", used, got, want)
+		}
+		used = append(used, got)
+	}
+
+	if _, err := pool.Allocate(used); !errors.Is(err, ErrPoolExhausted) {
+		t.Errorf("Allocate() on a full pool error = %v, want ErrPoolExhausted", err)
+	}
+
+	// A released block is handed out again
+	if got, err := pool.Allocate([]string{"10.244.1.0/24", "10.244.3.0/24"}); err != nil || got != "10.244.2.0/24" {
+		t.Errorf("Allocate() after release = %s, %v; want 10.244.2.0/24", got, err)
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	pool, err := NewPool("10.244.64.0/20", 24, []string{"10.244.64.0/23"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 16 blocks, 2 reserved, 1 used; blocks outside the pool don't count
+	if got := pool.Available([]string{"10.244.70.0/24", "10.244.200.0/24"}); got != 13 {
+		t.Errorf("Available() = %d, want 13", got)
+	}
+}
+
+func TestContains(t *testing.T) {
+	pool, err := NewPool("10.244.64.0/18", 24, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		cidr string
+		want bool
+	}{
+		{"10.244.64.0/24", true},
+		{"10.244.127.0/24", true},
+		{"10.244.128.0/24", false},
+		{"10.244.64.0/25", false},
+		{"10.244.64.1/24", false},
+		{"garbage", false},
+	}
+	for _, tt := range tests {
+		if got := pool.Contains(tt.cidr); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.cidr, got, tt.want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -20,6 +21,9 @@ const (
 	baseURL = "https://api.tailscale.com/api/v2"
 )
 
+// ErrDeviceNotFound is returned when no device in the tailnet matches a lookup.
+var ErrDeviceNotFound = errors.New("device not found")
+
 // Client is a Tailscale API client.
 type Client struct {
 	httpClient *http.Client
@@ -333,7 +337,14 @@ func (c *Client) FindDeviceByHostname(ctx context.Context, hostname string) (*De
 		}
 	}
 
-	return nil, fmt.Errorf("device not found: %s", hostname)
+	return nil, fmt.Errorf("%w: %s", ErrDeviceNotFound, hostname)
+}
+
+// DeleteDevice removes a device from the tailnet.
+func (c *Client) DeleteDevice(ctx context.Context, deviceID string) error {
+	path := fmt.Sprintf("/device/%s", deviceID)
+	_, err := c.doRequest(ctx, http.MethodDelete, path, nil)
+	return err
 }
 
 // GetDeviceRoutes gets the routes for a device.
@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// validOperationTypes are the operations the controllers can perform
+var validOperationTypes = []string{
+	string(api.OperationTypeRepave),
+	string(api.OperationTypeReboot),
+	string(api.OperationTypePowerOn),
+	string(api.OperationTypePowerOff),
+	string(api.OperationTypePowerCycle),
+	string(api.OperationTypeWipe),
+	string(api.OperationTypeDecommission),
+	string(api.OperationTypeUpgrade),
+}
+
+// +kubebuilder:webhook:path=/mutate-stargate-io-v1alpha1-operation,mutating=true,failurePolicy=fail,sideEffects=None,groups=stargate.io,resources=operations,verbs=create,versions=v1alpha1,name=moperation.stargate.io,admissionReviewVersions=v1
+
+// OperationDefaulter applies defaults to new Operations. It only runs on create, since the
+// spec is immutable afterwards.
+type OperationDefaulter struct{}
+
+// Default makes an Operation without an operation type a repave, as the controllers treat it
+func (d *OperationDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	operation, ok := obj.(*api.Operation)
+	if !ok {
+		return fmt.Errorf("expected an Operation but got %T", obj)
+	}
+
+	if operation.Spec.Operation == "" {
+		operation.Spec.Operation = api.OperationTypeRepave
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-stargate-io-v1alpha1-operation,mutating=false,failurePolicy=fail,sideEffects=None,groups=stargate.io,resources=operations,verbs=create;update,versions=v1alpha1,name=voperation.stargate.io,admissionReviewVersions=v1
+
+// OperationValidator validates Operations. The Server and ProvisioningProfile an Operation
+// references must exist when it is created, and its spec cannot change afterwards
+// except to cancel it.
+type OperationValidator struct {
+	Client client.Client
+}
+
+// ValidateCreate validates a new Operation and checks that its references exist
+func (v *OperationValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	operation, ok := obj.(*api.Operation)
+	if !ok {
+		return nil, fmt.Errorf("expected an Operation but got %T", obj)
+	}
+
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+
+	if !contains(validOperationTypes, string(operation.Spec.Operation)) {
+		errs = append(errs, field.NotSupported(spec.Child("operation"), operation.Spec.Operation, validOperationTypes))
+	}
+
+	if operation.Spec.ServerRef.Name == "" {
+		errs = append(errs, field.Required(spec.Child("serverRef", "name"), "server is required"))
+	} else if err := v.checkExists(ctx, operation.Namespace, operation.Spec.ServerRef.Name, &api.Server{}); err != nil {
+		errs = append(errs, field.Invalid(spec.Child("serverRef", "name"), operation.Spec.ServerRef.Name, err.Error()))
+	}
+
+	profileName := operation.Spec.ProvisioningProfileRef.Name
+	switch {
+	case profileName == "" && operation.Spec.Operation == api.OperationTypeRepave:
+		errs = append(errs, field.Required(spec.Child("provisioningProfileRef", "name"), "provisioningProfileRef is required for repave operations"))
+	case profileName == "" && operation.Spec.Operation == api.OperationTypeUpgrade:
+		errs = append(errs, field.Required(spec.Child("provisioningProfileRef", "name"), "provisioningProfileRef is required for upgrade operations"))
+	case profileName != "":
+		if err := v.checkExists(ctx, operation.Namespace, profileName, &api.ProvisioningProfile{}); err != nil {
+			errs = append(errs, field.Invalid(spec.Child("provisioningProfileRef", "name"), profileName, err.Error()))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil, nil
+	}
+	return nil, apierrors.NewInvalid(api.GroupVersion.WithKind("Operation").GroupKind(), operation.Name, errs)
+}
+
+// ValidateUpdate rejects changes to an Operation's spec other than setting cancel
+func (v *OperationValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldOperation, ok := oldObj.(*api.Operation)
+	if !ok {
+		return nil, fmt.Errorf("expected an Operation but got %T", oldObj)
+	}
+	operation, ok := newObj.(*api.Operation)
+	if !ok {
+		return nil, fmt.Errorf("expected an Operation but got %T", newObj)
+	}
+
+	oldSpec := oldOperation.Spec.DeepCopy()
+	oldSpec.Cancel = operation.Spec.Cancel
+	if equality.Semantic.DeepEqual(*oldSpec, operation.Spec) {
+		return nil, nil
+	}
+
+	errs := field.ErrorList{field.Forbidden(field.NewPath("spec"), "Operation spec is immutable except for cancel; create a new Operation instead")}
+	return nil, apierrors.NewInvalid(api.GroupVersion.WithKind("Operation").GroupKind(), operation.Name, errs)
+}
+
+// ValidateDelete allows every Operation to be deleted
+func (v *OperationValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// checkExists returns an error describing why the named object could not be found
+func (v *OperationValidator) checkExists(ctx context.Context, namespace, name string, obj client.Object) error {
+	if err := v.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("not found in namespace %s", namespace)
+		}
+		return fmt.Errorf("lookup failed: %v", err)
+	}
+	return nil
+}
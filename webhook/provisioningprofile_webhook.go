@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// kubernetesVersionPattern matches the versions the bootstrap scripts accept, e.g. "1.34" or "1.34.1"
+var kubernetesVersionPattern = regexp.MustCompile(`^[0-9]+\.[0-9]+(\.[0-9]+)?$`)
+
+// validContainerRuntimes are the container runtimes the bootstrap scripts install
+var validContainerRuntimes = []string{DefaultContainerRuntime}
+
+// +kubebuilder:webhook:path=/mutate-stargate-io-v1alpha1-provisioningprofile,mutating=true,failurePolicy=fail,sideEffects=None,groups=stargate.io,resources=provisioningprofiles,verbs=create;update,versions=v1alpha1,name=mprovisioningprofile.stargate.io,admissionReviewVersions=v1
+
+// ProvisioningProfileDefaulter applies defaults to ProvisioningProfiles
+type ProvisioningProfileDefaulter struct{}
+
+// Default fills in the container runtime and admin username if unset
+func (d *ProvisioningProfileDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	profile, ok := obj.(*api.ProvisioningProfile)
+	if !ok {
+		return fmt.Errorf("expected a ProvisioningProfile but got %T", obj)
+	}
+
+	if profile.Spec.ContainerRuntime == "" {
+		profile.Spec.ContainerRuntime = DefaultContainerRuntime
+	}
+	if profile.Spec.AdminUsername == "" {
+		profile.Spec.AdminUsername = DefaultAdminUsername
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-stargate-io-v1alpha1-provisioningprofile,mutating=false,failurePolicy=fail,sideEffects=None,groups=stargate.io,resources=provisioningprofiles,verbs=create;update,versions=v1alpha1,name=vprovisioningprofile.stargate.io,admissionReviewVersions=v1
+
+// ProvisioningProfileValidator validates ProvisioningProfiles
+type ProvisioningProfileValidator struct{}
+
+// ValidateCreate validates a new ProvisioningProfile
+func (v *ProvisioningProfileValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, v.validate(obj)
+}
+
+// ValidateUpdate validates an updated ProvisioningProfile
+func (v *ProvisioningProfileValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	return nil, v.validate(newObj)
+}
+
+// ValidateDelete allows every ProvisioningProfile to be deleted
+func (v *ProvisioningProfileValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ProvisioningProfileValidator) validate(obj runtime.Object) error {
+	profile, ok := obj.(*api.ProvisioningProfile)
+	if !ok {
+		return fmt.Errorf("expected a ProvisioningProfile but got %T", obj)
+	}
+
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+
+	if !kubernetesVersionPattern.MatchString(profile.Spec.KubernetesVersion) {
+		errs = append(errs, field.Invalid(spec.Child("kubernetesVersion"), profile.Spec.KubernetesVersion, "must be a version like 1.34 or 1.34.1"))
+	}
+	if profile.Spec.ContainerRuntime != "" && !contains(validContainerRuntimes, profile.Spec.ContainerRuntime) {
+		errs = append(errs, field.NotSupported(spec.Child("containerRuntime"), profile.Spec.ContainerRuntime, validContainerRuntimes))
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(api.GroupVersion.WithKind("ProvisioningProfile").GroupKind(), profile.Name, errs)
+}
@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// validProviders are the values of Server.Spec.Provider a controller handles.
+// An empty provider is handled by every controller.
+var validProviders = []string{"azure", "qemu"}
+
+// +kubebuilder:webhook:path=/validate-stargate-io-v1alpha1-server,mutating=false,failurePolicy=fail,sideEffects=None,groups=stargate.io,resources=servers,verbs=create;update,versions=v1alpha1,name=vserver.stargate.io,admissionReviewVersions=v1
+
+// ServerValidator validates Servers
+type ServerValidator struct{}
+
+// ValidateCreate validates a new Server
+func (v *ServerValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, v.validate(obj)
+}
+
+// ValidateUpdate validates an updated Server
+func (v *ServerValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	return nil, v.validate(newObj)
+}
+
+// ValidateDelete allows every Server to be deleted
+func (v *ServerValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ServerValidator) validate(obj runtime.Object) error {
+	server, ok := obj.(*api.Server)
+	if !ok {
+		return fmt.Errorf("expected a Server but got %T", obj)
+	}
+
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+
+	if _, err := net.ParseMAC(server.Spec.MAC); err != nil {
+		errs = append(errs, field.Invalid(spec.Child("mac"), server.Spec.MAC, "must be a valid MAC address"))
+	}
+	if server.Spec.IPv4 == "" {
+		errs = append(errs, field.Required(spec.Child("ipv4"), "IPv4 address is required"))
+	} else if ip := net.ParseIP(server.Spec.IPv4); ip == nil || ip.To4() == nil {
+		errs = append(errs, field.Invalid(spec.Child("ipv4"), server.Spec.IPv4, "must be a valid IPv4 address"))
+	}
+	if server.Spec.Provider != "" && !contains(validProviders, server.Spec.Provider) {
+		errs = append(errs, field.NotSupported(spec.Child("provider"), server.Spec.Provider, validProviders))
+	}
+	if server.Spec.BMC != nil && server.Spec.BMC.Address == "" {
+		errs = append(errs, field.Required(spec.Child("bmc", "address"), "BMC address is required when bmc is set"))
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(api.GroupVersion.WithKind("Server").GroupKind(), server.Name, errs)
+}
+
+// contains returns true if values contains s
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// +kubebuilder:webhook:path=/validate-stargate-io-v1alpha1-serverclaim,mutating=false,failurePolicy=fail,sideEffects=None,groups=stargate.io,resources=serverclaims,verbs=update,versions=v1alpha1,name=vserverclaim.stargate.io,admissionReviewVersions=v1
+
+// ServerClaimValidator validates ServerClaims
+type ServerClaimValidator struct{}
+
+// ValidateCreate allows every new ServerClaim; the CRD schema validates its fields
+func (v *ServerClaimValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateUpdate rejects changes to a ServerClaim's count. The controller never releases
+// Servers from a live claim, so a lower count would leave the extra Servers bound.
+func (v *ServerClaimValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldClaim, ok := oldObj.(*api.ServerClaim)
+	if !ok {
+		return nil, fmt.Errorf("expected a ServerClaim but got %T", oldObj)
+	}
+	claim, ok := newObj.(*api.ServerClaim)
+	if !ok {
+		return nil, fmt.Errorf("expected a ServerClaim but got %T", newObj)
+	}
+
+	if claim.Spec.Count == oldClaim.Spec.Count {
+		return nil, nil
+	}
+	errs := field.ErrorList{field.Forbidden(field.NewPath("spec", "count"), "count is immutable; create another ServerClaim for more Servers, or delete this one to release them")}
+	return nil, apierrors.NewInvalid(api.GroupVersion.WithKind("ServerClaim").GroupKind(), claim.Name, errs)
+}
+
+// ValidateDelete allows every ServerClaim to be deleted
+func (v *ServerClaimValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
@@ -0,0 +1,53 @@
+// Package webhook implements the validating and defaulting admission webhooks for the Stargate CRDs
+package webhook
+
+import (
+	"fmt"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+// Defaults applied to ProvisioningProfiles. These match the fallbacks the controllers use
+// when resolving a profile.
+const (
+	DefaultContainerRuntime = "containerd"
+	DefaultAdminUsername    = "ubuntu"
+)
+
+// SetupWithManager registers the Server, ProvisioningProfile, Operation and ServerClaim webhooks
+// with the Manager
+func SetupWithManager(mgr ctrl.Manager) error {
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(&api.Server{}).
+		WithValidator(&ServerValidator{}).
+		Complete(); err != nil {
+		return fmt.Errorf("set up Server webhook: %w", err)
+	}
+
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(&api.ProvisioningProfile{}).
+		WithDefaulter(&ProvisioningProfileDefaulter{}).
+		WithValidator(&ProvisioningProfileValidator{}).
+		Complete(); err != nil {
+		return fmt.Errorf("set up ProvisioningProfile webhook: %w", err)
+	}
+
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(&api.Operation{}).
+		WithDefaulter(&OperationDefaulter{}).
+		WithValidator(&OperationValidator{Client: mgr.GetClient()}).
+		Complete(); err != nil {
+		return fmt.Errorf("set up Operation webhook: %w", err)
+	}
+
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(&api.ServerClaim{}).
+		WithValidator(&ServerClaimValidator{}).
+		Complete(); err != nil {
+		return fmt.Errorf("set up ServerClaim webhook: %w", err)
+	}
+
+	return nil
+}
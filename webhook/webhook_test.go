@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "github.com/vpatelsj/stargate/api/v1alpha1"
+)
+
+func TestServerValidator(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    api.ServerSpec
+		wantErr bool
+	}{
+		{"valid", api.ServerSpec{MAC: "60:45:bd:5c:3f:6f", IPv4: "10.50.1.5", Provider: "azure"}, false},
+		{"no provider", api.ServerSpec{MAC: "60:45:bd:5c:3f:6f", IPv4: "10.50.1.5"}, false},
+		{"no ipv4", api.ServerSpec{MAC: "60:45:bd:5c:3f:6f", Provider: "azure"}, true},
+		{"bad mac", api.ServerSpec{MAC: "60:45:bd", IPv4: "10.50.1.5"}, true},
+		{"ipv6 address", api.ServerSpec{MAC: "60:45:bd:5c:3f:6f", IPv4: "fd00::1"}, true},
+		{"unknown provider", api.ServerSpec{MAC: "60:45:bd:5c:3f:6f", IPv4: "10.50.1.5", Provider: "aws"}, true},
+		{"bmc without address", api.ServerSpec{MAC: "60:45:bd:5c:3f:6f", IPv4: "10.50.1.5", BMC: &api.BMCConfig{}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &api.Server{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}, Spec: tt.spec}
+			_, err := (&ServerValidator{}).ValidateCreate(context.Background(), server)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestProvisioningProfileWebhook(t *testing.T) {
+	profile := &api.ProvisioningProfile{Spec: api.ProvisioningProfileSpec{KubernetesVersion: "1.34"}}
+	if err := (&ProvisioningProfileDefaulter{}).Default(context.Background(), profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile.Spec.ContainerRuntime != DefaultContainerRuntime || profile.Spec.AdminUsername != DefaultAdminUsername {
+		t.Errorf("expected defaults to be applied, got %+v", profile.Spec)
+	}
+
+	for version, valid := range map[string]bool{"1.34": true, "1.34.1": true, "v1.34": false, "latest": false, "": false} {
+		profile.Spec.KubernetesVersion = version
+		_, err := (&ProvisioningProfileValidator{}).ValidateCreate(context.Background(), profile)
+		if (err == nil) != valid {
+			t.Errorf("kubernetesVersion %q: expected valid=%v, got %v", version, valid, err)
+		}
+	}
+}
+
+func TestOperationValidator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := api.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&api.Server{ObjectMeta: metav1.ObjectMeta{Name: "worker-1", Namespace: "dc"}},
+		&api.ProvisioningProfile{ObjectMeta: metav1.ObjectMeta{Name: "k8s-worker", Namespace: "dc"}},
+	).Build()
+	v := &OperationValidator{Client: c}
+
+	operation := func(opType api.OperationType, server, profile string) *api.Operation {
+		return &api.Operation{
+			ObjectMeta: metav1.ObjectMeta{Name: "op", Namespace: "dc"},
+			Spec: api.OperationSpec{
+				ServerRef:              api.LocalObjectReference{Name: server},
+				ProvisioningProfileRef: api.LocalObjectReference{Name: profile},
+				Operation:              opType,
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		op      *api.Operation
+		wantErr bool
+	}{
+		{"valid repave", operation(api.OperationTypeRepave, "worker-1", "k8s-worker"), false},
+		{"reboot without profile", operation(api.OperationTypeReboot, "worker-1", ""), false},
+		{"repave without profile", operation(api.OperationTypeRepave, "worker-1", ""), true},
+		{"upgrade without profile", operation(api.OperationTypeUpgrade, "worker-1", ""), true},
+		{"missing server", operation(api.OperationTypeReboot, "worker-2", ""), true},
+		{"missing profile", operation(api.OperationTypeRepave, "worker-1", "other"), true},
+		{"unknown type", operation("format", "worker-1", "k8s-worker"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.ValidateCreate(context.Background(), tt.op)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("default", func(t *testing.T) {
+		op := operation("", "worker-1", "")
+		if err := (&OperationDefaulter{}).Default(context.Background(), op); err != nil {
+			t.Fatal(err)
+		}
+		if op.Spec.Operation != api.OperationTypeRepave {
+			t.Errorf("expected operation to default to repave, got %q", op.Spec.Operation)
+		}
+		if _, err := v.ValidateCreate(context.Background(), op); err == nil {
+			t.Error("expected a defaulted repave without profile to be rejected")
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		old := operation(api.OperationTypeRepave, "worker-1", "k8s-worker")
+
+		cancelled := old.DeepCopy()
+		cancelled.Spec.Cancel = true
+		if _, err := v.ValidateUpdate(context.Background(), old, cancelled); err != nil {
+			t.Errorf("expected cancel to be allowed, got %v", err)
+		}
+
+		retargeted := old.DeepCopy()
+		retargeted.Spec.ServerRef.Name = "worker-2"
+		if _, err := v.ValidateUpdate(context.Background(), old, retargeted); err == nil {
+			t.Error("expected spec change to be rejected")
+		}
+	})
+}
+
+func TestServerClaimValidator(t *testing.T) {
+	v := &ServerClaimValidator{}
+	old := &api.ServerClaim{ObjectMeta: metav1.ObjectMeta{Name: "training"}, Spec: api.ServerClaimSpec{Count: 4}}
+
+	relabelled := old.DeepCopy()
+	relabelled.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "gpu"}}
+	if _, err := v.ValidateUpdate(context.Background(), old, relabelled); err != nil {
+		t.Errorf("expected a change other than count to be allowed, got %v", err)
+	}
+
+	for _, count := range []int32{2, 6} {
+		resized := old.DeepCopy()
+		resized.Spec.Count = count
+		if _, err := v.ValidateUpdate(context.Background(), old, resized); err == nil {
+			t.Errorf("expected count change to %d to be rejected", count)
+		}
+	}
+}